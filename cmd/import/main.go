@@ -0,0 +1,102 @@
+// Command import loads books from a CSV or NDJSON file, the same way as
+// POST /api/v1/admin/import/books, and prints the report as JSON.
+//
+//	go run ./cmd/import -file books.csv -dry-run
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-rest-api-ozgur/internal/config"
+	database "go-rest-api-ozgur/internal/db"
+	"go-rest-api-ozgur/internal/handlers"
+	"go-rest-api-ozgur/internal/importer"
+)
+
+func main() {
+	file := flag.String("file", "-", "file to import, - for stdin")
+	format := flag.String("format", "", "csv, ndjson, onix, marc or marcxml, defaults to the file extension")
+	dryRun := flag.Bool("dry-run", false, "validate every row without writing")
+	chunkSize := flag.Int("chunk-size", 0, "commit every N rows instead of all or nothing")
+	upsert := flag.Bool("upsert", false, "update books with the same ISBN, on by default for ONIX and MARC")
+	flag.Parse()
+
+	// Only an explicit -upsert overrides the default of the format.
+	var upsertFlag *bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "upsert" {
+			upsertFlag = upsert
+		}
+	})
+
+	failed, err := run(*file, *format, *dryRun, *chunkSize, upsertFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		os.Exit(1)
+	}
+	if failed > 0 {
+		os.Exit(2)
+	}
+}
+
+// run imports the file and returns the number of rows that were not imported.
+func run(file, format string, dryRun bool, chunkSize int, upsert *bool) (int, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".csv":
+			format = string(importer.FormatCSV)
+		case ".ndjson", ".jsonl":
+			format = string(importer.FormatNDJSON)
+		case ".mrc", ".marc":
+			format = string(importer.FormatMARC)
+		default:
+			return 0, fmt.Errorf("cannot tell the format of %s, pass -format", file)
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		input = f
+	}
+	rows, err := importer.Parse(input, importer.Format(format))
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := database.InitDB(config.LoadConfig())
+	if err != nil {
+		return 0, fmt.Errorf("connecting to the database: %w", err)
+	}
+	opts := importer.Options{
+		DryRun:    dryRun,
+		ChunkSize: chunkSize,
+		Upsert:    format != string(importer.FormatCSV) && format != string(importer.FormatNDJSON),
+		Record:    handlers.RevisionRecorder("import"),
+	}
+	if upsert != nil {
+		opts.Upsert = *upsert
+	}
+	report, err := importer.New(db).Run(rows, opts)
+	if err != nil {
+		return 0, err
+	}
+	report.Format = format
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return 0, err
+	}
+	return report.Failed, nil
+}
@@ -0,0 +1,31 @@
+// Command ratings recomputes the rating aggregates of every book from its published
+// reviews. Run it to repair aggregates that drifted, or to rescore all books after
+// changing RATING_PRIOR_MEAN or RATING_PRIOR_WEIGHT; the server only computes them
+// once, when it adds the rating columns.
+//
+//	go run ./cmd/ratings
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"go-rest-api-ozgur/internal/config"
+	database "go-rest-api-ozgur/internal/db"
+	"go-rest-api-ozgur/internal/ratings"
+)
+
+func main() {
+	cfg := config.LoadConfig()
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ratings: connecting to the database:", err)
+		os.Exit(1)
+	}
+	changed, err := ratings.RecomputeAll(db, ratings.PriorFromConfig(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ratings:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Recomputed ratings, %d books changed\n", changed)
+}
@@ -1 +1 @@
-CREATE TYPE role AS ENUM ('admin', 'user');
\ No newline at end of file
+CREATE TYPE role AS ENUM ('admin', 'moderator', 'user');
\ No newline at end of file
@@ -0,0 +1,111 @@
+package citation
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func renderBibTeX(w io.Writer, books []models.Book) error {
+	keys := make(map[string]int)
+	for i, book := range books {
+		key := bibtexKey(book)
+		keys[key]++
+		if n := keys[key]; n > 1 {
+			key += string(rune('a' + n - 2))
+		}
+
+		var b strings.Builder
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "@book{%s,\n", key)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "  %s = {%s},\n", name, bibtexEscaper.Replace(value))
+			}
+		}
+
+		roles := map[models.ContributorRole][]string{}
+		for _, p := range people(book) {
+			roles[p.Role] = append(roles[p.Role], p.inverted())
+		}
+		field("author", strings.Join(roles[models.ContributorAuthor], " and "))
+		field("editor", strings.Join(roles[models.ContributorEditor], " and "))
+		field("translator", strings.Join(roles[models.ContributorTranslator], " and "))
+		field("illustrator", strings.Join(roles[models.ContributorIllustrator], " and "))
+		field("title", book.Title)
+		if book.PublicationYear != 0 {
+			field("year", strconv.Itoa(book.PublicationYear))
+		}
+		field("publisher", publisherName(book))
+		field("isbn", book.ISBN)
+		field("language", book.Language)
+		if book.PageCount > 0 {
+			field("pagetotal", strconv.Itoa(book.PageCount))
+		}
+		field("abstract", book.Description)
+		b.WriteString("}\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bibtexKey builds a citation key like "mccarthy2006road" from the first contributor,
+// the year and the first significant word of the title.
+func bibtexKey(book models.Book) string {
+	var key string
+	if credited := people(book); len(credited) > 0 {
+		key = asciiWord(credited[0].Family)
+	}
+	if book.PublicationYear != 0 {
+		key += strconv.Itoa(book.PublicationYear)
+	}
+	for _, word := range strings.Fields(book.Title) {
+		word = asciiWord(word)
+		switch word {
+		case "", "a", "an", "the":
+			continue
+		}
+		key += word
+		break
+	}
+	if key == "" {
+		key = fmt.Sprintf("book%d", book.ID)
+	}
+	return key
+}
+
+// asciiWord lowercases a word and drops accents and everything that is not a letter or
+// digit, so keys stay valid in every BibTeX implementation.
+func asciiWord(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(word)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
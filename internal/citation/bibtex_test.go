@@ -0,0 +1,76 @@
+package citation
+
+import (
+	"bytes"
+	"testing"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestRenderBibTeX(t *testing.T) {
+	sequel := models.Book{
+		Model:           gorm.Model{ID: 8},
+		Title:           "Road Trip",
+		Contributors:    []models.BookContributor{credit("Cormac McCarthy", models.ContributorAuthor)},
+		PublicationYear: 2006,
+	}
+	var out bytes.Buffer
+	if err := Render(&out, FormatBibTeX, []models.Book{theRoad(), sequel}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	want := `@book{mccarthy2006road,
+  author = {McCarthy, Cormac},
+  editor = {Plato},
+  translator = {Costa, Margaret Jull},
+  illustrator = {Manet, Édouard},
+  title = {The Road},
+  year = {2006},
+  publisher = {Alfred A. Knopf},
+  isbn = {9780307265432},
+  language = {eng},
+  pagetotal = {241},
+  abstract = {A father and his son walk
+alone through burned America. 100\% bleak \& \{grim\}.},
+}
+
+@book{mccarthy2006roada,
+  author = {McCarthy, Cormac},
+  title = {Road Trip},
+  year = {2006},
+}
+`
+	if out.String() != want {
+		t.Errorf("rendered\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestBibTeXKey(t *testing.T) {
+	for _, tc := range []struct {
+		book models.Book
+		want string
+	}{
+		{theRoad(), "mccarthy2006road"},
+		{models.Book{
+			Title:        "The Ñandú's Song",
+			Contributors: []models.BookContributor{credit("Gabriel García Márquez", models.ContributorAuthor)},
+		}, "marqueznandus"},
+		{models.Book{Title: "An Essay", PublicationYear: 1690, Contributors: []models.BookContributor{credit("Locke", models.ContributorEditor)}}, "locke1690essay"},
+		{models.Book{Title: "!!!", PublicationYear: 1999}, "1999"},
+		{models.Book{Model: gorm.Model{ID: 9}, Title: "A"}, "book9"},
+	} {
+		if got := bibtexKey(tc.book); got != tc.want {
+			t.Errorf("bibtexKey(%q) = %q, want %q", tc.book.Title, got, tc.want)
+		}
+	}
+}
+
+func TestBibTeXEscaper(t *testing.T) {
+	got := bibtexEscaper.Replace(`50% of C:\ {draft} #1 ~ ^_$`)
+	want := `50\% of C:\textbackslash{} \{draft\} \#1 \textasciitilde{} \textasciicircum{}\_\$`
+	if got != want {
+		t.Errorf("escaped to %s, want %s", got, want)
+	}
+}
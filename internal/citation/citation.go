@@ -0,0 +1,147 @@
+// Package citation renders books in the bibliographic formats used by reference
+// managers: BibTeX, RIS, CSL-JSON and MARCXML. Books must have their contributors,
+// contributor authors and publisher loaded.
+package citation
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-rest-api-ozgur/internal/models"
+)
+
+type Format string
+
+const (
+	FormatBibTeX  Format = "bibtex"
+	FormatRIS     Format = "ris"
+	FormatCSLJSON Format = "csl-json"
+	FormatMARCXML Format = "marcxml"
+)
+
+var mediaTypes = map[Format]string{
+	FormatBibTeX:  "application/x-bibtex",
+	FormatRIS:     "application/x-research-info-systems",
+	FormatCSLJSON: "application/vnd.citationstyles.csl+json",
+	FormatMARCXML: "application/marcxml+xml",
+}
+
+// ParseFormat accepts the names used in the format query parameter.
+func ParseFormat(name string) (Format, bool) {
+	format := Format(strings.ToLower(name))
+	if format == "csljson" {
+		format = FormatCSLJSON
+	}
+	_, ok := mediaTypes[format]
+	return format, ok
+}
+
+func (f Format) MediaType() string {
+	return mediaTypes[f]
+}
+
+// Negotiate picks the citation format preferred by an Accept header. It returns false
+// when the client prefers anything else, such as plain JSON.
+func Negotiate(accept string) (Format, bool) {
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		candidates = append(candidates, candidate{mediaType, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, candidate := range candidates {
+		if candidate.q <= 0 {
+			continue
+		}
+		if candidate.mediaType == "text/x-bibtex" {
+			return FormatBibTeX, true
+		}
+		for format, mediaType := range mediaTypes {
+			if candidate.mediaType == mediaType {
+				return format, true
+			}
+		}
+		if candidate.mediaType == "application/json" || candidate.mediaType == "*/*" ||
+			candidate.mediaType == "application/*" {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// Render writes the books in the given format. Lists become a sequence of BibTeX or
+// RIS entries, a CSL-JSON array or a MARCXML collection.
+func Render(w io.Writer, format Format, books []models.Book) error {
+	switch format {
+	case FormatBibTeX:
+		return renderBibTeX(w, books)
+	case FormatRIS:
+		return renderRIS(w, books)
+	case FormatCSLJSON:
+		return renderCSLJSON(w, books)
+	case FormatMARCXML:
+		return renderMARCXML(w, books)
+	}
+	return fmt.Errorf("unsupported citation format %q", format)
+}
+
+// person is a contributor name split into family and given names.
+type person struct {
+	Family string
+	Given  string
+	Role   models.ContributorRole
+}
+
+// people returns the credited contributors of a book in credit order. Names are split
+// at the last space, which is right for most names in the catalog; single names are
+// kept whole as the family name.
+func people(book models.Book) []person {
+	var result []person
+	for _, contributor := range book.Contributors {
+		name := strings.TrimSpace(contributor.Author.Name)
+		if name == "" {
+			continue
+		}
+		p := person{Family: name, Role: contributor.Role}
+		if i := strings.LastIndex(name, " "); i > 0 {
+			p.Given, p.Family = name[:i], name[i+1:]
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// inverted returns "Family, Given", the form BibTeX, RIS and MARC expect.
+func (p person) inverted() string {
+	if p.Given == "" {
+		return p.Family
+	}
+	return p.Family + ", " + p.Given
+}
+
+func publisherName(book models.Book) string {
+	if book.Publisher != nil {
+		return book.Publisher.Name
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package citation
+
+import (
+	"bytes"
+	"testing"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func credit(name string, role models.ContributorRole) models.BookContributor {
+	return models.BookContributor{Author: models.Author{Name: name}, Role: role}
+}
+
+// theRoad is a book with every field the renderers use, one contributor of each role
+// and text that needs escaping.
+func theRoad() models.Book {
+	return models.Book{
+		Model: gorm.Model{ID: 7},
+		Title: "The Road",
+		Contributors: []models.BookContributor{
+			credit("Cormac McCarthy", models.ContributorAuthor),
+			credit("Plato", models.ContributorEditor),
+			credit("  ", models.ContributorEditor),
+			credit("Margaret Jull Costa", models.ContributorTranslator),
+			credit("Édouard Manet", models.ContributorIllustrator),
+		},
+		ISBN:            "9780307265432",
+		PublicationYear: 2006,
+		Description:     "A father and his son walk\nalone through burned America. 100% bleak & {grim}.",
+		Format:          models.FormatHardcover,
+		Publisher:       &models.Publisher{Name: "Alfred A. Knopf"},
+		PageCount:       241,
+		Language:        "eng",
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format Format
+		ok     bool
+	}{
+		{"bibtex", FormatBibTeX, true},
+		{"BibTeX", FormatBibTeX, true},
+		{"ris", FormatRIS, true},
+		{"csl-json", FormatCSLJSON, true},
+		{"csljson", FormatCSLJSON, true},
+		{"CSL-JSON", FormatCSLJSON, true},
+		{"marcxml", FormatMARCXML, true},
+		{"json", "", false},
+		{"", "", false},
+	} {
+		format, ok := ParseFormat(tc.name)
+		if ok != tc.ok || (ok && format != tc.format) {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, %v", tc.name, format, ok, tc.format, tc.ok)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		accept string
+		format Format
+		ok     bool
+	}{
+		{"application/x-bibtex", FormatBibTeX, true},
+		{"text/x-bibtex", FormatBibTeX, true},
+		{"APPLICATION/X-BIBTEX", FormatBibTeX, true},
+		{"application/x-research-info-systems", FormatRIS, true},
+		{"application/vnd.citationstyles.csl+json", FormatCSLJSON, true},
+		{"application/marcxml+xml", FormatMARCXML, true},
+		{"", "", false},
+		{"application/json", "", false},
+		{"*/*", "", false},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "", false},
+		{"text/html, application/x-bibtex;q=0.8", FormatBibTeX, true},
+		{"application/json;q=0.5, application/x-bibtex", FormatBibTeX, true},
+		{"application/x-bibtex;q=0.5, application/json", "", false},
+		{"application/x-bibtex;q=0, */*", "", false},
+		// Equal preferences keep the order of the header.
+		{"application/marcxml+xml;q=0.9, application/x-research-info-systems;q=0.9", FormatMARCXML, true},
+	} {
+		format, ok := Negotiate(tc.accept)
+		if format != tc.format || ok != tc.ok {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", tc.accept, format, ok, tc.format, tc.ok)
+		}
+	}
+}
+
+func TestRenderUnsupportedFormat(t *testing.T) {
+	var out bytes.Buffer
+	if err := Render(&out, Format("endnote"), []models.Book{theRoad()}); err == nil {
+		t.Error("Render accepted an unknown format")
+	}
+}
@@ -0,0 +1,68 @@
+package citation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go-rest-api-ozgur/internal/models"
+)
+
+type cslName struct {
+	Family string `json:"family"`
+	Given  string `json:"given,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Title         string    `json:"title"`
+	Author        []cslName `json:"author,omitempty"`
+	Editor        []cslName `json:"editor,omitempty"`
+	Translator    []cslName `json:"translator,omitempty"`
+	Illustrator   []cslName `json:"illustrator,omitempty"`
+	Issued        *cslDate  `json:"issued,omitempty"`
+	Publisher     string    `json:"publisher,omitempty"`
+	ISBN          string    `json:"ISBN,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	NumberOfPages int       `json:"number-of-pages,omitempty"`
+	Abstract      string    `json:"abstract,omitempty"`
+}
+
+func renderCSLJSON(w io.Writer, books []models.Book) error {
+	items := make([]cslItem, 0, len(books))
+	for _, book := range books {
+		item := cslItem{
+			ID:            fmt.Sprintf("book-%d", book.ID),
+			Type:          "book",
+			Title:         book.Title,
+			Publisher:     publisherName(book),
+			ISBN:          book.ISBN,
+			Language:      book.Language,
+			NumberOfPages: book.PageCount,
+			Abstract:      book.Description,
+		}
+		if book.PublicationYear != 0 {
+			item.Issued = &cslDate{DateParts: [][]int{{book.PublicationYear}}}
+		}
+		for _, p := range people(book) {
+			name := cslName{Family: p.Family, Given: p.Given}
+			switch p.Role {
+			case models.ContributorEditor:
+				item.Editor = append(item.Editor, name)
+			case models.ContributorTranslator:
+				item.Translator = append(item.Translator, name)
+			case models.ContributorIllustrator:
+				item.Illustrator = append(item.Illustrator, name)
+			default:
+				item.Author = append(item.Author, name)
+			}
+		}
+		items = append(items, item)
+	}
+	return json.NewEncoder(w).Encode(items)
+}
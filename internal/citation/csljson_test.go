@@ -0,0 +1,35 @@
+package citation
+
+import (
+	"bytes"
+	"testing"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestRenderCSLJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		books []models.Book
+		want  string
+	}{
+		{"full record", []models.Book{theRoad()}, `[{"id":"book-7","type":"book","title":"The Road",` +
+			`"author":[{"family":"McCarthy","given":"Cormac"}],"editor":[{"family":"Plato"}],` +
+			`"translator":[{"family":"Costa","given":"Margaret Jull"}],"illustrator":[{"family":"Manet","given":"Édouard"}],` +
+			`"issued":{"date-parts":[[2006]]},"publisher":"Alfred A. Knopf","ISBN":"9780307265432","language":"eng",` +
+			`"number-of-pages":241,"abstract":"A father and his son walk\nalone through burned America. 100% bleak \u0026 {grim}."}]` + "\n"},
+		{"unknown fields left out", []models.Book{{Model: gorm.Model{ID: 8}, Title: "Untitled"}},
+			`[{"id":"book-8","type":"book","title":"Untitled"}]` + "\n"},
+		{"no books", nil, "[]\n"},
+	} {
+		var out bytes.Buffer
+		if err := Render(&out, FormatCSLJSON, tc.books); err != nil {
+			t.Fatalf("%s: Render: %v", tc.name, err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("%s: rendered\n%s\nwant\n%s", tc.name, out.String(), tc.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package citation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go-rest-api-ozgur/internal/models"
+)
+
+const marcNamespace = "http://www.loc.gov/MARC21/slim"
+
+type marcCollection struct {
+	XMLName xml.Name     `xml:"collection"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Records []marcRecord `xml:"record"`
+}
+
+type marcRecord struct {
+	Leader        string             `xml:"leader"`
+	ControlFields []marcControlField `xml:"controlfield"`
+	DataFields    []marcDataField    `xml:"datafield"`
+}
+
+type marcControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcDataField struct {
+	Tag       string         `xml:"tag,attr"`
+	Ind1      string         `xml:"ind1,attr"`
+	Ind2      string         `xml:"ind2,attr"`
+	Subfields []marcSubfield `xml:"subfield"`
+}
+
+type marcSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+func renderMARCXML(w io.Writer, books []models.Book) error {
+	collection := marcCollection{XMLNS: marcNamespace}
+	for _, book := range books {
+		collection.Records = append(collection.Records, marcRecordFor(book))
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(collection); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// marcRecordFor maps a book onto a minimal MARC21 bibliographic record: ISBN (020),
+// main and added entries (100/700), title (245), publication (264), extent (300) and
+// summary (520).
+func marcRecordFor(book models.Book) marcRecord {
+	record := marcRecord{
+		// Record length and base address are left zero as MARCXML does not need them.
+		Leader: "00000nam a2200000 i 4500",
+		ControlFields: []marcControlField{
+			{Tag: "001", Value: strconv.FormatUint(uint64(book.ID), 10)},
+			{Tag: "008", Value: marcFixedField(book)},
+		},
+	}
+	data := func(tag, ind1, ind2 string, subfields ...marcSubfield) {
+		var present []marcSubfield
+		for _, subfield := range subfields {
+			if subfield.Value != "" {
+				present = append(present, subfield)
+			}
+		}
+		if len(present) > 0 {
+			record.DataFields = append(record.DataFields, marcDataField{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: present})
+		}
+	}
+
+	data("020", " ", " ", marcSubfield{"a", book.ISBN}, marcSubfield{"q", string(book.Format)})
+
+	credited := people(book)
+	mainEntry := -1
+	for i, p := range credited {
+		if p.Role == models.ContributorAuthor {
+			mainEntry = i
+			break
+		}
+	}
+	if mainEntry >= 0 {
+		data("100", "1", " ", marcSubfield{"a", credited[mainEntry].inverted()}, marcSubfield{"e", "author"})
+	}
+
+	// The first title indicator says whether the record has a main entry.
+	titleIndicator := "0"
+	if mainEntry >= 0 {
+		titleIndicator = "1"
+	}
+	data("245", titleIndicator, "0", marcSubfield{"a", book.Title})
+
+	var year string
+	if book.PublicationYear != 0 {
+		year = strconv.Itoa(book.PublicationYear)
+	}
+	data("264", " ", "1", marcSubfield{"b", publisherName(book)}, marcSubfield{"c", year})
+	if book.PageCount > 0 {
+		data("300", " ", " ", marcSubfield{"a", fmt.Sprintf("%d pages", book.PageCount)})
+	}
+	data("520", " ", " ", marcSubfield{"a", book.Description})
+
+	for i, p := range credited {
+		if i != mainEntry {
+			data("700", "1", " ", marcSubfield{"a", p.inverted()}, marcSubfield{"e", string(p.Role)})
+		}
+	}
+	return record
+}
+
+// marcFixedField builds the 40 character 008 field: a single known date at positions
+// 6-10 and the language at 35-37, everything else unspecified.
+func marcFixedField(book models.Book) string {
+	field := []byte(strings.Repeat(" ", 40))
+	field[6] = 's'
+	if book.PublicationYear > 0 && book.PublicationYear < 10000 {
+		copy(field[7:11], fmt.Sprintf("%04d", book.PublicationYear))
+	} else {
+		copy(field[7:11], "uuuu")
+	}
+	copy(field[15:17], "xx")
+	copy(field[35:38], "und")
+	if len(book.Language) == 3 {
+		copy(field[35:38], strings.ToLower(book.Language))
+	}
+	field[39] = 'd'
+	return string(field)
+}
@@ -0,0 +1,110 @@
+package citation
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestMARCRecordFor(t *testing.T) {
+	fixed := "      s2006    xx" + strings.Repeat(" ", 18) + "eng d"
+	want := marcRecord{
+		Leader: "00000nam a2200000 i 4500",
+		ControlFields: []marcControlField{
+			{Tag: "001", Value: "7"},
+			{Tag: "008", Value: fixed},
+		},
+		DataFields: []marcDataField{
+			{Tag: "020", Ind1: " ", Ind2: " ", Subfields: []marcSubfield{{"a", "9780307265432"}, {"q", "hardcover"}}},
+			{Tag: "100", Ind1: "1", Ind2: " ", Subfields: []marcSubfield{{"a", "McCarthy, Cormac"}, {"e", "author"}}},
+			{Tag: "245", Ind1: "1", Ind2: "0", Subfields: []marcSubfield{{"a", "The Road"}}},
+			{Tag: "264", Ind1: " ", Ind2: "1", Subfields: []marcSubfield{{"b", "Alfred A. Knopf"}, {"c", "2006"}}},
+			{Tag: "300", Ind1: " ", Ind2: " ", Subfields: []marcSubfield{{"a", "241 pages"}}},
+			{Tag: "520", Ind1: " ", Ind2: " ", Subfields: []marcSubfield{{"a", "A father and his son walk\nalone through burned America. 100% bleak & {grim}."}}},
+			{Tag: "700", Ind1: "1", Ind2: " ", Subfields: []marcSubfield{{"a", "Plato"}, {"e", "editor"}}},
+			{Tag: "700", Ind1: "1", Ind2: " ", Subfields: []marcSubfield{{"a", "Costa, Margaret Jull"}, {"e", "translator"}}},
+			{Tag: "700", Ind1: "1", Ind2: " ", Subfields: []marcSubfield{{"a", "Manet, Édouard"}, {"e", "illustrator"}}},
+		},
+	}
+	if got := marcRecordFor(theRoad()); !reflect.DeepEqual(got, want) {
+		t.Errorf("marcRecordFor mapped\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Without an author there is no main entry and every contributor is an added entry.
+	edited := models.Book{
+		Model:        gorm.Model{ID: 8},
+		Title:        "Collected Essays",
+		Contributors: []models.BookContributor{credit("Jane Doe", models.ContributorEditor)},
+	}
+	want = marcRecord{
+		Leader: "00000nam a2200000 i 4500",
+		ControlFields: []marcControlField{
+			{Tag: "001", Value: "8"},
+			{Tag: "008", Value: "      suuuu    xx" + strings.Repeat(" ", 18) + "und d"},
+		},
+		DataFields: []marcDataField{
+			{Tag: "245", Ind1: "0", Ind2: "0", Subfields: []marcSubfield{{"a", "Collected Essays"}}},
+			{Tag: "700", Ind1: "1", Ind2: " ", Subfields: []marcSubfield{{"a", "Doe, Jane"}, {"e", "editor"}}},
+		},
+	}
+	if got := marcRecordFor(edited); !reflect.DeepEqual(got, want) {
+		t.Errorf("marcRecordFor mapped\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestMARCFixedField(t *testing.T) {
+	for _, tc := range []struct {
+		year           int
+		language, want string
+	}{
+		{2006, "eng", "2006eng"},
+		{2006, "GER", "2006ger"},
+		{800, "lat", "0800lat"},
+		{0, "en", "uuuuund"},
+		{12000, "", "uuuuund"},
+	} {
+		field := marcFixedField(models.Book{PublicationYear: tc.year, Language: tc.language})
+		if len(field) != 40 || field[6] != 's' || field[15:17] != "xx" || field[39] != 'd' {
+			t.Errorf("marcFixedField(%d, %q) = %q, not a 40 character 008 field", tc.year, tc.language, field)
+		}
+		if got := field[7:11] + field[35:38]; got != tc.want {
+			t.Errorf("marcFixedField(%d, %q) has date and language %q, want %q", tc.year, tc.language, got, tc.want)
+		}
+	}
+}
+
+func TestRenderMARCXML(t *testing.T) {
+	var out bytes.Buffer
+	if err := Render(&out, FormatMARCXML, []models.Book{theRoad(), {Model: gorm.Model{ID: 8}, Title: "Untitled"}}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	document := out.String()
+	for _, part := range []string{
+		xml.Header + `<collection xmlns="http://www.loc.gov/MARC21/slim">`,
+		`    <datafield tag="264" ind1=" " ind2="1">` + "\n" + `      <subfield code="b">Alfred A. Knopf</subfield>`,
+		`100% bleak &amp; {grim}.</subfield>`,
+		"</collection>\n",
+	} {
+		if !strings.Contains(document, part) {
+			t.Errorf("rendered document lacks %q:\n%s", part, document)
+		}
+	}
+
+	var parsed struct {
+		Records []struct {
+			ControlFields []marcControlField `xml:"controlfield"`
+		} `xml:"http://www.loc.gov/MARC21/slim record"`
+	}
+	if err := xml.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("the rendered document does not parse: %v", err)
+	}
+	if len(parsed.Records) != 2 || parsed.Records[1].ControlFields[0].Value != "8" {
+		t.Errorf("parsed %+v, want the two records in order", parsed.Records)
+	}
+}
@@ -0,0 +1,54 @@
+package citation
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"go-rest-api-ozgur/internal/models"
+)
+
+// risTags maps contributor roles to RIS tags. A4 is the subsidiary author, which
+// reference managers import as translator.
+var risTags = map[models.ContributorRole]string{
+	models.ContributorAuthor:      "AU",
+	models.ContributorEditor:      "ED",
+	models.ContributorTranslator:  "A4",
+	models.ContributorIllustrator: "A3",
+}
+
+func renderRIS(w io.Writer, books []models.Book) error {
+	for _, book := range books {
+		var b strings.Builder
+		line := func(tag, value string) {
+			// RIS values end at the line break, so descriptions are folded into one line.
+			value = strings.Join(strings.Fields(value), " ")
+			if value != "" {
+				b.WriteString(tag + "  - " + value + "\r\n")
+			}
+		}
+
+		line("TY", "BOOK")
+		for _, p := range people(book) {
+			line(risTags[p.Role], p.inverted())
+		}
+		line("TI", book.Title)
+		if book.PublicationYear != 0 {
+			line("PY", strconv.Itoa(book.PublicationYear))
+		}
+		line("PB", publisherName(book))
+		line("SN", book.ISBN)
+		line("LA", book.Language)
+		if book.PageCount > 0 {
+			line("SP", strconv.Itoa(book.PageCount))
+		}
+		line("AB", book.Description)
+		line("ID", strconv.FormatUint(uint64(book.ID), 10))
+		b.WriteString("ER  - \r\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package citation
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestRenderRIS(t *testing.T) {
+	var out bytes.Buffer
+	untitled := models.Book{Model: gorm.Model{ID: 8}}
+	if err := Render(&out, FormatRIS, []models.Book{theRoad(), untitled}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"TY  - BOOK",
+		"AU  - McCarthy, Cormac",
+		"ED  - Plato",
+		"A4  - Costa, Margaret Jull",
+		"A3  - Manet, Édouard",
+		"TI  - The Road",
+		"PY  - 2006",
+		"PB  - Alfred A. Knopf",
+		"SN  - 9780307265432",
+		"LA  - eng",
+		"SP  - 241",
+		"AB  - A father and his son walk alone through burned America. 100% bleak & {grim}.",
+		"ID  - 7",
+		"ER  - ",
+		"TY  - BOOK",
+		"ID  - 8",
+		"ER  - ",
+		"",
+	}, "\r\n")
+	if out.String() != want {
+		t.Errorf("rendered\n%q\nwant\n%q", out.String(), want)
+	}
+}
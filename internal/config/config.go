@@ -3,16 +3,68 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// DeletePolicy decides what happens to books and reviews when their parent is deleted.
+type DeletePolicy string
+
+const (
+	// DeleteRestrict refuses to delete a record that still has live dependents.
+	DeleteRestrict DeletePolicy = "restrict"
+	// DeleteCascade permanently removes the record together with its dependents.
+	DeleteCascade DeletePolicy = "cascade"
+	// DeleteSoftCascade soft deletes the record together with its dependents.
+	DeleteSoftCascade DeletePolicy = "soft-cascade"
+)
+
 type Config struct {
-	PGHost     string
-	PGPort     string
-	PGUser     string
-	PGPassword string
-	PGName     string
+	PGHost       string
+	PGPort       string
+	PGUser       string
+	PGPassword   string
+	PGName       string
+	DeletePolicy DeletePolicy
+
+	// Blob storage for uploaded files such as book covers.
+	StorageBackend   string
+	StorageLocalDir  string
+	StoragePublicURL string
+	S3Endpoint       string
+	S3Region         string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	CoverMaxBytes    int64
+
+	// External book database used to enrich metadata by ISBN.
+	MetadataProvider   string
+	MetadataBaseURL    string
+	MetadataCoversURL  string
+	MetadataFixtureDir string
+
+	// Days soft deleted rows stay in the trash before they are purged; 0 keeps them.
+	TrashRetentionDays int
+
+	// Reject updates and deletes of books, authors and reviews that send no If-Match.
+	RequireIfMatch bool
+
+	// Reviews by accounts younger than this many days wait for a moderator; 0 publishes
+	// them right away.
+	PremoderationDays int
+
+	// Filters run over review text before publication, in order, and the blocked words
+	// of the wordlist filter, given inline and/or one per line in a file.
+	ContentFilters         []string
+	ContentFilterWords     []string
+	ContentFilterWordsFile string
+
+	// Bayesian book scores start from this many imaginary reviews of this rating.
+	RatingPriorMean   float64
+	RatingPriorWeight float64
 }
 
 func LoadConfig() *Config {
@@ -22,10 +74,99 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
-		PGHost:     os.Getenv("PG_HOST"),
-		PGPort:     os.Getenv("PG_PORT"),
-		PGUser:     os.Getenv("PG_USER"),
-		PGPassword: os.Getenv("PG_PASSWORD"),
-		PGName:     os.Getenv("PG_NAME"),
+		PGHost:       os.Getenv("PG_HOST"),
+		PGPort:       os.Getenv("PG_PORT"),
+		PGUser:       os.Getenv("PG_USER"),
+		PGPassword:   os.Getenv("PG_PASSWORD"),
+		PGName:       os.Getenv("PG_NAME"),
+		DeletePolicy: parseDeletePolicy(os.Getenv("DELETE_POLICY")),
+
+		StorageBackend:   getEnv("STORAGE_BACKEND", "local"),
+		StorageLocalDir:  getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+		StoragePublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+		S3Endpoint:       os.Getenv("S3_ENDPOINT"),
+		S3Region:         os.Getenv("S3_REGION"),
+		S3Bucket:         os.Getenv("S3_BUCKET"),
+		S3AccessKey:      os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:      os.Getenv("S3_SECRET_KEY"),
+		CoverMaxBytes:    getEnvInt64("COVER_MAX_BYTES", 5<<20),
+
+		MetadataProvider:   getEnv("METADATA_PROVIDER", "openlibrary"),
+		MetadataBaseURL:    os.Getenv("METADATA_BASE_URL"),
+		MetadataCoversURL:  os.Getenv("METADATA_COVERS_URL"),
+		MetadataFixtureDir: os.Getenv("METADATA_FIXTURE_DIR"),
+
+		TrashRetentionDays: parseRetentionDays(os.Getenv("TRASH_RETENTION_DAYS")),
+
+		RequireIfMatch: os.Getenv("REQUIRE_IF_MATCH") == "true",
+
+		PremoderationDays: int(getEnvInt64("PREMODERATION_DAYS", 0)),
+
+		ContentFilters:         getEnvList("CONTENT_FILTERS", "wordlist,spam,repeats"),
+		ContentFilterWords:     getEnvList("CONTENT_FILTER_WORDS", ""),
+		ContentFilterWordsFile: os.Getenv("CONTENT_FILTER_WORDS_FILE"),
+
+		RatingPriorMean:   getEnvFloat("RATING_PRIOR_MEAN", 3),
+		RatingPriorWeight: getEnvFloat("RATING_PRIOR_WEIGHT", 10),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated variable, dropping blank items.
+func getEnvList(key, fallback string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvFloat falls back for unset, malformed or negative values; 0 is allowed.
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// parseRetentionDays defaults to 30 days; 0, "off" or "never" turn purging off.
+func parseRetentionDays(value string) int {
+	switch value {
+	case "":
+		return 30
+	case "off", "never":
+		return 0
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 30
+	}
+	return days
+}
+
+// parseDeletePolicy falls back to DeleteRestrict so nothing is removed by accident.
+func parseDeletePolicy(value string) DeletePolicy {
+	switch DeletePolicy(value) {
+	case DeleteCascade, DeleteSoftCascade:
+		return DeletePolicy(value)
+	default:
+		return DeleteRestrict
 	}
 }
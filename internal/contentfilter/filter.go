@@ -0,0 +1,177 @@
+// Package contentfilter screens the text of reviews before they are published, so
+// abuse and spam can be held for a moderator or refused outright.
+package contentfilter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"go-rest-api-ozgur/internal/config"
+)
+
+// Action is what should happen to a piece of content. Actions are ordered from the
+// most to the least permissive.
+type Action string
+
+const (
+	Publish Action = "publish"
+	Hold    Action = "hold"
+	Reject  Action = "reject"
+)
+
+var severity = map[Action]int{Publish: 0, Hold: 1, Reject: 2}
+
+// Content is the text being screened with what is known about its author.
+type Content struct {
+	Text string
+	// Previous holds other texts by the same author, most recent first.
+	Previous []string
+}
+
+// Verdict is the finding of one filter. Score runs from 0 (nothing found) to 1
+// (certainly unwanted); Reasons say what was found, for moderators and the author.
+type Verdict struct {
+	Filter  string   `json:"filter"`
+	Score   float64  `json:"score"`
+	Action  Action   `json:"action"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ContentFilter checks content and gives a verdict.
+type ContentFilter interface {
+	// Name identifies the filter in verdicts.
+	Name() string
+	Check(ctx context.Context, content Content) (Verdict, error)
+}
+
+// Result is the combined outcome of a pipeline: the strictest action any filter asked
+// for, with every verdict that asked for more than publishing.
+type Result struct {
+	Action   Action    `json:"action"`
+	Verdicts []Verdict `json:"verdicts,omitempty"`
+}
+
+// Reasons lists the reasons of all verdicts.
+func (r Result) Reasons() []string {
+	var reasons []string
+	for _, verdict := range r.Verdicts {
+		reasons = append(reasons, verdict.Reasons...)
+	}
+	return reasons
+}
+
+// Pipeline runs filters one after another. A nil pipeline publishes everything.
+type Pipeline struct {
+	filters []ContentFilter
+}
+
+func NewPipeline(filters ...ContentFilter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Run checks content with every filter, stopping early once one rejects it.
+func (p *Pipeline) Run(ctx context.Context, content Content) (Result, error) {
+	result := Result{Action: Publish}
+	if p == nil {
+		return result, nil
+	}
+	for _, filter := range p.filters {
+		verdict, err := filter.Check(ctx, content)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", filter.Name(), err)
+		}
+		if verdict.Action == Publish {
+			continue
+		}
+		verdict.Filter = filter.Name()
+		result.Verdicts = append(result.Verdicts, verdict)
+		if severity[verdict.Action] > severity[result.Action] {
+			result.Action = verdict.Action
+		}
+		if result.Action == Reject {
+			break
+		}
+	}
+	return result, nil
+}
+
+// New builds the pipeline of the filters named in CONTENT_FILTERS, in that order. It
+// returns nil when filtering is turned off.
+func New(cfg *config.Config) (*Pipeline, error) {
+	var filters []ContentFilter
+	for _, name := range cfg.ContentFilters {
+		switch name {
+		case "none":
+			return nil, nil
+		case "wordlist":
+			words := cfg.ContentFilterWords
+			if cfg.ContentFilterWordsFile != "" {
+				fromFile, err := readWordList(cfg.ContentFilterWordsFile)
+				if err != nil {
+					return nil, err
+				}
+				words = append(words, fromFile...)
+			}
+			filters = append(filters, NewWordList(words))
+		case "spam":
+			filters = append(filters, NewSpamHeuristics())
+		case "repeats":
+			filters = append(filters, NewRepeatedText())
+		default:
+			return nil, fmt.Errorf("unknown content filter %q", name)
+		}
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return NewPipeline(filters...), nil
+}
+
+// readWordList reads one word or phrase per line, skipping blank lines and lines
+// starting with #.
+func readWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read word list: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read word list: %w", err)
+	}
+	return words, nil
+}
+
+// verdictFor turns a score into an action using the hold and reject thresholds.
+func verdictFor(score, holdAt, rejectAt float64, reasons []string) Verdict {
+	if score > 1 {
+		score = 1
+	}
+	verdict := Verdict{Score: score, Action: Publish, Reasons: reasons}
+	switch {
+	case score >= rejectAt:
+		verdict.Action = Reject
+	case score >= holdAt:
+		verdict.Action = Hold
+	}
+	return verdict
+}
+
+// words splits text into lowercase words, dropping the punctuation around them.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
@@ -0,0 +1,120 @@
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-rest-api-ozgur/internal/config"
+)
+
+// fixedFilter gives the same verdict to everything and counts how often it was asked.
+type fixedFilter struct {
+	name    string
+	verdict Verdict
+	err     error
+	calls   int
+}
+
+func (f *fixedFilter) Name() string {
+	return f.name
+}
+
+func (f *fixedFilter) Check(ctx context.Context, content Content) (Verdict, error) {
+	f.calls++
+	return f.verdict, f.err
+}
+
+func TestPipelineRun(t *testing.T) {
+	publish := &fixedFilter{name: "publish", verdict: Verdict{Action: Publish, Reasons: []string{"not shown"}}}
+	hold := &fixedFilter{name: "hold", verdict: Verdict{Score: 0.5, Action: Hold, Reasons: []string{"held"}}}
+	reject := &fixedFilter{name: "reject", verdict: Verdict{Score: 1, Action: Reject, Reasons: []string{"rejected"}}}
+	after := &fixedFilter{name: "after", verdict: Verdict{Action: Hold}}
+
+	result, err := NewPipeline(publish, hold, reject, after).Run(context.Background(), Content{Text: "text"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := Result{Action: Reject, Verdicts: []Verdict{
+		{Filter: "hold", Score: 0.5, Action: Hold, Reasons: []string{"held"}},
+		{Filter: "reject", Score: 1, Action: Reject, Reasons: []string{"rejected"}},
+	}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Run returned %+v, want %+v", result, want)
+	}
+	if after.calls != 0 {
+		t.Error("Run kept checking after a filter rejected the content")
+	}
+	if reasons := result.Reasons(); !reflect.DeepEqual(reasons, []string{"held", "rejected"}) {
+		t.Errorf("Reasons() = %q", reasons)
+	}
+
+	// Without a reject, a hold is the strictest action.
+	result, err = NewPipeline(hold, publish).Run(context.Background(), Content{})
+	if err != nil || result.Action != Hold {
+		t.Errorf("Run returned %+v, %v; want hold", result, err)
+	}
+
+	var none *Pipeline
+	if result, err := none.Run(context.Background(), Content{Text: "text"}); err != nil || result.Action != Publish || result.Verdicts != nil {
+		t.Errorf("a nil pipeline returned %+v, %v; want publish", result, err)
+	}
+}
+
+func TestPipelineRunError(t *testing.T) {
+	broken := &fixedFilter{name: "remote", err: errors.New("timeout")}
+	_, err := NewPipeline(broken).Run(context.Background(), Content{Text: "text"})
+	if err == nil || err.Error() != "remote: timeout" {
+		t.Errorf("Run returned %v, want the error named after the filter", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(list, []byte("# blocked\n\n  fudge  \nheck no\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		cfg     config.Config
+		filters []string
+		fails   bool
+	}{
+		{"defaults", config.Config{ContentFilters: []string{"wordlist", "spam", "repeats"}}, []string{"wordlist", "spam", "repeats"}, false},
+		{"in the given order", config.Config{ContentFilters: []string{"repeats", "spam"}}, []string{"repeats", "spam"}, false},
+		{"turned off", config.Config{ContentFilters: []string{"wordlist", "none"}}, nil, false},
+		{"empty", config.Config{}, nil, false},
+		{"unknown filter", config.Config{ContentFilters: []string{"spam", "bayes"}}, nil, true},
+		{"missing word list", config.Config{ContentFilters: []string{"wordlist"}, ContentFilterWordsFile: filepath.Join(t.TempDir(), "missing")}, nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pipeline, err := New(&tc.cfg)
+			if (err != nil) != tc.fails {
+				t.Fatalf("New returned %v", err)
+			}
+			var names []string
+			if pipeline != nil {
+				for _, filter := range pipeline.filters {
+					names = append(names, filter.Name())
+				}
+			}
+			if !reflect.DeepEqual(names, tc.filters) {
+				t.Errorf("New built %q, want %q", names, tc.filters)
+			}
+		})
+	}
+
+	cfg := config.Config{ContentFilters: []string{"wordlist"}, ContentFilterWords: []string{"darn"}, ContentFilterWordsFile: list}
+	pipeline, err := New(&cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	result, err := pipeline.Run(context.Background(), Content{Text: "Darn it. Fudge! Heck, no."})
+	if err != nil || result.Action != Reject {
+		t.Errorf("the configured and listed words gave %+v, %v; want a reject for three matches", result, err)
+	}
+}
@@ -0,0 +1,106 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RepeatedText catches text pasted over and over: a review that copies another review
+// by the same author, or one that repeats the same few words to fill space.
+type RepeatedText struct {
+	// HoldAt is how alike, from 0 to 1, a review must be to an earlier one to be held.
+	HoldAt float64
+	// RejectCopies is how many earlier reviews a review must copy outright to be
+	// rejected.
+	RejectCopies int
+	// MinUniqueShare is the smallest share of distinct words a text of 20 words or more
+	// may have before it is held as filler.
+	MinUniqueShare float64
+}
+
+// NewRepeatedText holds near copies of an earlier review and rejects a text the author
+// has already posted three times.
+func NewRepeatedText() *RepeatedText {
+	return &RepeatedText{HoldAt: 0.8, RejectCopies: 3, MinUniqueShare: 0.3}
+}
+
+func (r *RepeatedText) Name() string {
+	return "repeats"
+}
+
+func (r *RepeatedText) Check(ctx context.Context, content Content) (Verdict, error) {
+	tokens := words(content.Text)
+	if len(tokens) == 0 {
+		return Verdict{Action: Publish}, nil
+	}
+	var score float64
+	var reasons []string
+
+	shingles := shingleSet(tokens)
+	copies := 0
+	for _, previous := range content.Previous {
+		similarity := jaccard(shingles, shingleSet(words(previous)))
+		if similarity > score {
+			score = similarity
+		}
+		if similarity == 1 {
+			copies++
+		}
+	}
+	if score >= r.HoldAt {
+		reasons = append(reasons, fmt.Sprintf("%.0f%% the same as another review by the same author", score*100))
+	}
+
+	if len(tokens) >= 20 {
+		unique := map[string]bool{}
+		for _, token := range tokens {
+			unique[token] = true
+		}
+		if share := float64(len(unique)) / float64(len(tokens)); share < r.MinUniqueShare {
+			reasons = append(reasons, "repeats the same few words")
+			if score < r.HoldAt {
+				score = r.HoldAt
+			}
+		}
+	}
+
+	if r.RejectCopies > 0 && copies >= r.RejectCopies {
+		reasons = append(reasons, fmt.Sprintf("already posted %d times", copies))
+		return Verdict{Score: 1, Action: Reject, Reasons: reasons}, nil
+	}
+	if score < r.HoldAt {
+		return Verdict{Action: Publish}, nil
+	}
+	return Verdict{Score: score, Action: Hold, Reasons: reasons}, nil
+}
+
+// shingleSet is the set of runs of three words in tokens, or of the whole text when it
+// is shorter.
+func shingleSet(tokens []string) map[string]bool {
+	set := map[string]bool{}
+	if len(tokens) < 3 {
+		if len(tokens) > 0 {
+			set[strings.Join(tokens, " ")] = true
+		}
+		return set
+	}
+	for i := 0; i+3 <= len(tokens); i++ {
+		set[strings.Join(tokens[i:i+3], " ")] = true
+	}
+	return set
+}
+
+// jaccard is the share of shingles two sets have in common.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for shingle := range a {
+		if b[shingle] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
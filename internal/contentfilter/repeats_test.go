@@ -0,0 +1,63 @@
+package contentfilter
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRepeatedText(t *testing.T) {
+	review := "The prose is spare and the plot moves quickly, though the ending felt rushed to me and the second half drags a little."
+	nearCopy := strings.Replace(review, "a little.", "a lot.", 1)
+	filler := strings.Repeat("good book ", 12)
+
+	repeats := NewRepeatedText()
+	for _, tc := range []struct {
+		name     string
+		text     string
+		previous []string
+		action   Action
+	}{
+		{"first review", review, nil, Publish},
+		{"different review", review, []string{"Loved every page of this one, the characters stay with you."}, Publish},
+		{"copy", review, []string{"Something else entirely.", review}, Hold},
+		{"near copy", nearCopy, []string{review}, Hold},
+		{"copy with other punctuation", strings.ToUpper(strings.ReplaceAll(review, ",", "")), []string{review}, Hold},
+		{"posted three times before", review, []string{review, review, review}, Reject},
+		{"short copy", "Loved it.", []string{"loved it"}, Hold},
+		{"filler", filler, nil, Hold},
+		{"short repetition", "good good good", nil, Publish},
+		{"empty", "", []string{""}, Publish},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, err := repeats.Check(context.Background(), Content{Text: tc.text, Previous: tc.previous})
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if verdict.Action != tc.action {
+				t.Errorf("Check returned %+v, want %s", verdict, tc.action)
+			}
+			if tc.action != Publish && len(verdict.Reasons) == 0 {
+				t.Error("Check gave no reason")
+			}
+		})
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want float64
+	}{
+		{"one two three four", "one two three four", 1},
+		// Shingles "one two three" and "two three four" against "one two three" and "two three five".
+		{"one two three four", "one two three five", 1.0 / 3},
+		{"one two", "one two", 1},
+		{"one two", "two one", 0},
+		{"one two three", "", 0},
+	} {
+		if got := jaccard(shingleSet(words(tc.a)), shingleSet(words(tc.b))); got != tc.want {
+			t.Errorf("jaccard(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"unicode"
+)
+
+var (
+	linkPattern  = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|info|biz|ru|xyz|top|io)(?:/\S*)?\b`)
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+)
+
+// SpamHeuristics scores the marks of spam: links and e-mail addresses, text written in
+// capitals and long runs of one character such as "!!!!!!!!". A review is about a book,
+// so even one link counts for a lot.
+type SpamHeuristics struct {
+	// LinkWeight is added to the score for every link or e-mail address.
+	LinkWeight float64
+	// ShoutingWeight is added when more than ShoutingShare of the letters are capitals,
+	// in texts of at least 20 letters.
+	ShoutingWeight float64
+	ShoutingShare  float64
+	// RunWeight is added when a character repeats RunLength times or more in a row.
+	RunWeight float64
+	RunLength int
+	HoldAt    float64
+	RejectAt  float64
+}
+
+// NewSpamHeuristics holds a review with a link and rejects one with three.
+func NewSpamHeuristics() *SpamHeuristics {
+	return &SpamHeuristics{
+		LinkWeight:     0.35,
+		ShoutingWeight: 0.3,
+		ShoutingShare:  0.7,
+		RunWeight:      0.2,
+		RunLength:      6,
+		HoldAt:         0.35,
+		RejectAt:       1,
+	}
+}
+
+func (s *SpamHeuristics) Name() string {
+	return "spam"
+}
+
+func (s *SpamHeuristics) Check(ctx context.Context, content Content) (Verdict, error) {
+	var score float64
+	var reasons []string
+
+	emails := emailPattern.FindAllString(content.Text, -1)
+	links := len(emails) + len(linkPattern.FindAllString(emailPattern.ReplaceAllString(content.Text, " "), -1))
+	if links > 0 {
+		score += float64(links) * s.LinkWeight
+		reasons = append(reasons, fmt.Sprintf("contains %d link(s) or e-mail address(es)", links))
+	}
+
+	letters, capitals := 0, 0
+	for _, r := range content.Text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				capitals++
+			}
+		}
+	}
+	if letters >= 20 && float64(capitals) > s.ShoutingShare*float64(letters) {
+		score += s.ShoutingWeight
+		reasons = append(reasons, "written mostly in capitals")
+	}
+
+	if longestRun(content.Text) >= s.RunLength {
+		score += s.RunWeight
+		reasons = append(reasons, "repeats a character many times in a row")
+	}
+
+	if score == 0 {
+		return Verdict{Action: Publish}, nil
+	}
+	return verdictFor(score, s.HoldAt, s.RejectAt, reasons), nil
+}
+
+// longestRun is the length of the longest run of one non-space character.
+func longestRun(text string) int {
+	longest, run := 0, 0
+	var previous rune
+	for _, r := range text {
+		if r == previous && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = r
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
@@ -0,0 +1,56 @@
+package contentfilter
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSpamHeuristics(t *testing.T) {
+	spam := NewSpamHeuristics()
+	for _, tc := range []struct {
+		name   string
+		text   string
+		score  float64
+		action Action
+	}{
+		{"plain review", "A fine book about whales, and Mr. Ahab. The end.", 0, Publish},
+		{"link", "Buy it cheaper at https://cheap.example/books?id=1", 0.35, Hold},
+		{"bare domain", "Get it at cheapbooks.com today", 0.35, Hold},
+		{"e-mail counted once", "Write to bob@mail.com for a copy", 0.35, Hold},
+		{"three links", "cheapbooks.com, bestdeals.xyz and www.spam.net", 1, Reject},
+		{"shouting alone", "THIS IS THE WORST BOOK I HAVE EVER READ", 0.3, Publish},
+		{"short capitals", "NASA and the USA", 0, Publish},
+		{"long run alone", "Sooooooo good", 0.2, Publish},
+		{"shouting with a run", "THIS IS THE WORST BOOK I HAVE EVER READ!!!!!!", 0.5, Hold},
+		{"link with a run", "Read more at www.example.org ........", 0.55, Hold},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, err := spam.Check(context.Background(), Content{Text: tc.text})
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if math.Abs(verdict.Score-tc.score) > 1e-9 || verdict.Action != tc.action {
+				t.Errorf("Check(%q) = %+v, want score %.2f and %s", tc.text, verdict, tc.score, tc.action)
+			}
+		})
+	}
+}
+
+func TestLongestRun(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"aab", 2},
+		{"a      b", 1},
+		{"Wow!!!!!!", 6},
+		{"ééé", 3},
+	} {
+		if got := longestRun(tc.text); got != tc.want {
+			t.Errorf("longestRun(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// WordList flags text containing blocked words or phrases. Matching ignores case and
+// punctuation and only matches whole words, so "class" does not match "ass".
+type WordList struct {
+	phrases [][]string
+	// HoldAt and RejectAt are how many matches hold the text for a moderator and
+	// reject it.
+	HoldAt   int
+	RejectAt int
+}
+
+// NewWordList holds text with one blocked word and rejects it from three.
+func NewWordList(blocked []string) *WordList {
+	list := &WordList{HoldAt: 1, RejectAt: 3}
+	for _, phrase := range blocked {
+		if tokens := words(phrase); len(tokens) > 0 {
+			list.phrases = append(list.phrases, tokens)
+		}
+	}
+	return list
+}
+
+func (w *WordList) Name() string {
+	return "wordlist"
+}
+
+func (w *WordList) Check(ctx context.Context, content Content) (Verdict, error) {
+	tokens := words(content.Text)
+	matches := 0
+	var found []string
+	for _, phrase := range w.phrases {
+		if n := countPhrase(tokens, phrase); n > 0 {
+			matches += n
+			found = append(found, strings.Join(phrase, " "))
+		}
+	}
+	if matches == 0 {
+		return Verdict{Action: Publish}, nil
+	}
+	reasons := []string{fmt.Sprintf("contains blocked words: %s", strings.Join(found, ", "))}
+	return verdictFor(float64(matches)/float64(w.RejectAt), float64(w.HoldAt)/float64(w.RejectAt), 1, reasons), nil
+}
+
+// countPhrase counts where phrase appears in tokens as consecutive words.
+func countPhrase(tokens, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		matched := true
+		for j, word := range phrase {
+			if tokens[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+		}
+	}
+	return count
+}
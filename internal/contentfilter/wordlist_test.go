@@ -0,0 +1,51 @@
+package contentfilter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWordList(t *testing.T) {
+	list := NewWordList([]string{"darn", "Heck no", "fudge!", "...", ""})
+	for _, tc := range []struct {
+		text    string
+		action  Action
+		reasons []string
+	}{
+		{"A lovely classic.", Publish, nil},
+		{"Darn, that ending.", Hold, []string{"contains blocked words: darn"}},
+		// Only whole words match, ignoring case and punctuation.
+		{"Darned classical fudgery.", Publish, nil},
+		{"Heck, NO!", Hold, []string{"contains blocked words: heck no"}},
+		{"Heck yes, no regrets.", Publish, nil},
+		{"Darn it, fudge.", Hold, []string{"contains blocked words: darn, fudge"}},
+		{"Darn, darn, fudge.", Reject, []string{"contains blocked words: darn, fudge"}},
+		{"", Publish, nil},
+	} {
+		verdict, err := list.Check(context.Background(), Content{Text: tc.text})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", tc.text, err)
+		}
+		if verdict.Action != tc.action || !reflect.DeepEqual(verdict.Reasons, tc.reasons) {
+			t.Errorf("Check(%q) = %+v, want %s with %q", tc.text, verdict, tc.action, tc.reasons)
+		}
+	}
+}
+
+func TestCountPhrase(t *testing.T) {
+	for _, tc := range []struct {
+		text, phrase string
+		want         int
+	}{
+		{"no no no", "no", 3},
+		{"no no no", "no no", 2},
+		{"heck no and heck no", "heck no", 2},
+		{"heck", "heck no", 0},
+		{"", "heck", 0},
+	} {
+		if got := countPhrase(words(tc.text), words(tc.phrase)); got != tc.want {
+			t.Errorf("countPhrase(%q, %q) = %d, want %d", tc.text, tc.phrase, got, tc.want)
+		}
+	}
+}
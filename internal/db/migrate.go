@@ -0,0 +1,215 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MigrateRoles adds the roles introduced after the role type was first created.
+// ADD VALUE cannot run in a transaction block, so it is run as a statement of its own.
+func MigrateRoles(database *gorm.DB) error {
+	return database.Exec(`ALTER TYPE role ADD VALUE IF NOT EXISTS 'moderator'`).Error
+}
+
+// MigrateBookContributors moves the legacy books.author_id column into the
+// book_contributors join table. It is a no-op once the column is gone.
+func MigrateBookContributors(database *gorm.DB) error {
+	if !database.Migrator().HasColumn(&models.Book{}, "author_id") {
+		return nil
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO book_contributors (book_id, author_id, role, position, created_at)
+			SELECT id, author_id, ?, 0, NOW() FROM books WHERE author_id IS NOT NULL
+			ON CONFLICT DO NOTHING`, models.ContributorAuthor).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.Book{}, "author_id")
+	})
+}
+
+// MigrateBookWorks gives every book that predates works a work of its own, so
+// each existing book becomes the single edition of that work.
+func MigrateBookWorks(database *gorm.DB) error {
+	var books []models.Book
+	if err := database.Unscoped().Where("work_id IS NULL").Find(&books).Error; err != nil {
+		return err
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		for _, book := range books {
+			work := models.Work{
+				Title:              book.Title,
+				OriginalLanguage:   book.Language,
+				FirstPublishedYear: book.PublicationYear,
+			}
+			if err := tx.Create(&work).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.Book{}).Where("id = ?", book.ID).Update("work_id", work.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigratePublishers turns the free-text books.publisher column into Publisher rows
+// referenced through books.publisher_id. It is a no-op once the column is gone.
+func MigratePublishers(database *gorm.DB) error {
+	if !database.Migrator().HasColumn(&models.Book{}, "publisher") {
+		return nil
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		var names []string
+		if err := tx.Raw(`SELECT DISTINCT TRIM(publisher) FROM books WHERE TRIM(COALESCE(publisher, '')) <> ''`).Scan(&names).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			publisher := models.Publisher{Name: name}
+			if err := tx.Create(&publisher).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`UPDATE books SET publisher_id = ? WHERE TRIM(publisher) = ?`, publisher.ID, name).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropColumn(&models.Book{}, "publisher")
+	})
+}
+
+// legacyDateLayouts are the spellings of the free-text authors.birth_date column that
+// are understood besides YYYY, YYYY-MM and YYYY-MM-DD, with the precision they carry.
+var legacyDateLayouts = []struct {
+	layout    string
+	precision models.DatePrecision
+}{
+	{"January 2, 2006", models.PrecisionDay},
+	{"2 January 2006", models.PrecisionDay},
+	{"Jan 2, 2006", models.PrecisionDay},
+	{"2 Jan 2006", models.PrecisionDay},
+	{"02.01.2006", models.PrecisionDay},
+	{"January 2006", models.PrecisionMonth},
+	{"Jan 2006", models.PrecisionMonth},
+}
+
+var legacyYear = regexp.MustCompile(`\b\d{4}\b`)
+
+// MigrateAuthorBirthDates turns the free-text authors.birth_date column into the typed
+// born_date and born_precision columns. Values that are not a recognisable date keep
+// just their year, if they contain one. It is a no-op once the column is gone.
+func MigrateAuthorBirthDates(database *gorm.DB) error {
+	if !database.Migrator().HasColumn(&models.Author{}, "birth_date") {
+		return nil
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID        uint
+			BirthDate string
+		}
+		err := tx.Raw(`SELECT id, birth_date FROM authors WHERE TRIM(COALESCE(birth_date, '')) <> ''`).Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			born := parseLegacyDate(strings.TrimSpace(row.BirthDate))
+			if born.Date == nil {
+				continue
+			}
+			err := tx.Exec(`UPDATE authors SET born_date = ?, born_precision = ? WHERE id = ?`, born.Date, born.Precision, row.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropColumn(&models.Author{}, "birth_date")
+	})
+}
+
+func parseLegacyDate(value string) models.PartialDate {
+	if date, err := models.ParsePartialDate(value); err == nil {
+		return date
+	}
+	for _, legacy := range legacyDateLayouts {
+		if date, err := time.Parse(legacy.layout, value); err == nil {
+			return models.PartialDate{Date: &date, Precision: legacy.precision}
+		}
+	}
+	if year := legacyYear.FindString(value); year != "" {
+		date, _ := models.ParsePartialDate(year)
+		return date
+	}
+	return models.PartialDate{}
+}
+
+// MigrateAuthorSearchKeys fills in the search and sort keys of authors and aliases
+// stored before those keys existed.
+func MigrateAuthorSearchKeys(database *gorm.DB) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		var authors []models.Author
+		if err := tx.Unscoped().Where("COALESCE(sort_key, '') = '' AND name <> ''").Find(&authors).Error; err != nil {
+			return err
+		}
+		for _, author := range authors {
+			err := tx.Unscoped().Model(&author).UpdateColumns(map[string]interface{}{
+				"name_key": models.SearchKey(author.Name),
+				"sort_key": models.SortKey(author.Name),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var aliases []models.AuthorAlias
+		if err := tx.Where("COALESCE(name_key, '') = ''").Find(&aliases).Error; err != nil {
+			return err
+		}
+		for _, alias := range aliases {
+			if err := tx.Model(&alias).UpdateColumn("name_key", models.SearchKey(alias.Name)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateGenreSlugs drops the unique index that also covered deleted genres, so a
+// slug can be used again once its genre is deleted. AutoMigrate creates its partial
+// replacement, idx_genre_slug.
+func MigrateGenreSlugs(database *gorm.DB) error {
+	if !database.Migrator().HasIndex(&models.Genre{}, "idx_genres_slug") {
+		return nil
+	}
+	return database.Migrator().DropIndex(&models.Genre{}, "idx_genres_slug")
+}
+
+// MigrateBookISBNIndex indexes the normalized ISBN of books, so importers matching
+// rows to existing books by ISBN do not scan the whole table for every row.
+func MigrateBookISBNIndex(database *gorm.DB) error {
+	return database.Exec("CREATE INDEX IF NOT EXISTS idx_books_isbn_key ON books ((" + models.BookISBNKey + "))").Error
+}
+
+// EnableTrigramSearch installs pg_trgm and the trigram indexes used to find similar
+// author names and book titles. Creating the extension needs sufficient privileges;
+// without it duplicate detection falls back to exact matches.
+func EnableTrigramSearch(database *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_authors_name_trgm ON authors USING gin (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_books_title_trgm ON books USING gin (title gin_trgm_ops)`,
+	}
+	for _, statement := range statements {
+		if err := database.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
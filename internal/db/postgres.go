@@ -15,7 +15,7 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 		" port=" + cfg.PGPort +
 		" sslmode=disable"
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
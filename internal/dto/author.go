@@ -1,20 +1,58 @@
 package dto
 
+import "time"
+
+// CreateAuthorRequest takes the dates of birth and death as YYYY, YYYY-MM or YYYY-MM-DD, as far
+// as they are known.
 type CreateAuthorRequest struct {
-	Name      string `json:"name" binding:"required"`
-	Biography string `json:"biography" binding:"required"`
-	BirthDate string `json:"birth_date" binding:"required"`
+	Name        string   `json:"name" binding:"required"`
+	Biography   string   `json:"biography" binding:"required"`
+	BirthDate   string   `json:"birth_date" binding:"required"`
+	DeathDate   string   `json:"death_date"`
+	Nationality string   `json:"nationality" binding:"max=100"`
+	Aliases     []string `json:"aliases" binding:"omitempty,dive,required,max=200"`
 }
 
 type UpdateAuthorRequest struct {
-	Name      string `json:"name"`
-	Biography string `json:"biography"`
-	BirthDate string `json:"birth_date"`
+	Name        string   `json:"name"`
+	Biography   string   `json:"biography"`
+	BirthDate   string   `json:"birth_date"`
+	DeathDate   string   `json:"death_date"`
+	Nationality string   `json:"nationality" binding:"max=100"`
+	Aliases     []string `json:"aliases" binding:"omitempty,dive,required,max=200"`
 }
 
 type AuthorResponse struct {
-	ID        uint   `json:"id"`
-	Name      string `json:"name"`
-	Biography string `json:"biography"`
-	BirthDate string `json:"birth_date"`
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Biography   string    `json:"biography"`
+	BirthDate   string    `json:"birth_date"`
+	DeathDate   string    `json:"death_date"`
+	Nationality string    `json:"nationality"`
+	Aliases     []string  `json:"aliases"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// MatchedAlias is the alias a search matched when the name itself did not.
+	MatchedAlias string `json:"matched_alias,omitempty"`
+}
+
+// AuthorIndexEntry counts the authors whose surname starts with Letter; "#" holds the
+// surnames that do not start with a letter from A to Z.
+type AuthorIndexEntry struct {
+	Letter string `json:"letter"`
+	Count  int64  `json:"count"`
+}
+
+// BibliographyEntry is one credit of an author, as listed in their bibliography.
+type BibliographyEntry struct {
+	BookID          uint   `json:"book_id"`
+	Title           string `json:"title"`
+	PublicationYear int    `json:"publication_year"`
+	Role            string `json:"role"`
+}
+
+// AuthorStats sums up the books an author is credited on and their reviews.
+type AuthorStats struct {
+	BookCount     int64    `json:"book_count"`
+	ReviewCount   int64    `json:"review_count"`
+	AverageRating *float64 `json:"average_rating"`
 }
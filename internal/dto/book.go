@@ -1,26 +1,83 @@
 package dto
 
+import "time"
+
+// ContributorRequest credits an author on a book. Contributors are ordered as they appear in the list.
+type ContributorRequest struct {
+	AuthorID uint   `json:"author_id" binding:"required"`
+	Role     string `json:"role" binding:"omitempty,oneof=author editor translator illustrator"`
+}
+
 type CreateBookRequest struct {
-	Title           string `json:"title" binding:"required"`
-	AuthorID        uint   `json:"author_id" binding:"required"`
-	ISBN            string `json:"isbn" binding:"required"`
-	PublicationYear int    `json:"publication_year" binding:"required"`
-	Description     string `json:"description" binding:"required"`
+	Title           string               `json:"title" binding:"required"`
+	Contributors    []ContributorRequest `json:"contributors" binding:"required,min=1,dive"`
+	ISBN            string               `json:"isbn" binding:"required"`
+	PublicationYear int                  `json:"publication_year" binding:"required"`
+	Description     string               `json:"description" binding:"required"`
+	WorkID          *uint                `json:"work_id"`
+	Format          string               `json:"format" binding:"omitempty,oneof=hardcover paperback ebook audiobook"`
+	PublisherID     *uint                `json:"publisher_id"`
+	ImprintID       *uint                `json:"imprint_id"`
+	PageCount       int                  `json:"page_count" binding:"min=0"`
+	Language        string               `json:"language"`
+	GenreIDs        []uint               `json:"genre_ids"`
+	Tags            []string             `json:"tags" binding:"omitempty,dive,max=50"`
 }
 
 type UpdateBookRequest struct {
-	Title           string `json:"title"`
-	AuthorID        uint   `json:"author_id"`
-	ISBN            string `json:"isbn"`
-	PublicationYear int    `json:"publication_year"`
-	Description     string `json:"description"`
+	Title           string               `json:"title"`
+	Contributors    []ContributorRequest `json:"contributors" binding:"omitempty,min=1,dive"`
+	ISBN            string               `json:"isbn"`
+	PublicationYear int                  `json:"publication_year"`
+	Description     string               `json:"description"`
+	WorkID          *uint                `json:"work_id"`
+	Format          string               `json:"format" binding:"omitempty,oneof=hardcover paperback ebook audiobook"`
+	PublisherID     *uint                `json:"publisher_id"`
+	ImprintID       *uint                `json:"imprint_id"`
+	PageCount       int                  `json:"page_count" binding:"min=0"`
+	Language        string               `json:"language"`
+	GenreIDs        []uint               `json:"genre_ids"`
+	Tags            []string             `json:"tags" binding:"omitempty,dive,max=50"`
+}
+
+type ContributorResponse struct {
+	AuthorID uint   `json:"author_id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Order    int    `json:"order"`
 }
 
 type BookResponse struct {
-	ID              uint   `json:"id"`
-	Title           string `json:"title"`
-	AuthorID        uint   `json:"author_id"`
-	ISBN            string `json:"isbn"`
-	PublicationYear int    `json:"publication_year"`
-	Description     string `json:"description"`
+	ID              uint                  `json:"id"`
+	Title           string                `json:"title"`
+	Contributors    []ContributorResponse `json:"contributors"`
+	ISBN            string                `json:"isbn"`
+	PublicationYear int                   `json:"publication_year"`
+	Description     string                `json:"description"`
+	WorkID          *uint                 `json:"work_id"`
+	Format          string                `json:"format"`
+	Publisher       *PublisherSummary     `json:"publisher"`
+	Imprint         *ImprintSummary       `json:"imprint"`
+	Cover           *CoverResponse        `json:"cover"`
+	PageCount       int                   `json:"page_count"`
+	Language        string                `json:"language"`
+	Genres          []GenreSummary        `json:"genres"`
+	Tags            []string              `json:"tags"`
+	Rating          RatingSummary         `json:"rating"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+// RatingSummary sums up the published reviews of a book. Average is null without
+// reviews; Score is the Bayesian average that book lists sort by.
+type RatingSummary struct {
+	Count     int            `json:"count"`
+	Average   *float64       `json:"average"`
+	Histogram map[string]int `json:"histogram"`
+	Score     float64        `json:"score"`
+}
+
+// CoverResponse links to the uploaded cover and its thumbnails, keyed by size name.
+type CoverResponse struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
 }
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// CreateCommentRequest comments on a review, or replies to the comment in ParentID.
+type CreateCommentRequest struct {
+	Body     string `json:"body" binding:"required,max=5000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+}
+
+// CommentResponse is a comment with its replies, oldest first. A deleted comment that
+// still has replies keeps its place in the thread with Deleted set and no body or
+// author.
+type CommentResponse struct {
+	ID        uint              `json:"id"`
+	ReviewID  uint              `json:"review_id"`
+	ParentID  *uint             `json:"parent_id"`
+	UserID    *uint             `json:"user_id"`
+	Author    string            `json:"author"`
+	Body      string            `json:"body"`
+	Deleted   bool              `json:"deleted"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Replies   []CommentResponse `json:"replies"`
+}
@@ -0,0 +1,50 @@
+package dto
+
+import "time"
+
+// FieldChange is one difference between a book and the metadata found for it.
+// FillsEmpty is set when the book has no value yet, which is what gets applied by default.
+type FieldChange struct {
+	Field      string      `json:"field"`
+	Current    interface{} `json:"current"`
+	Proposed   interface{} `json:"proposed"`
+	FillsEmpty bool        `json:"fills_empty"`
+}
+
+type EnrichmentProposal struct {
+	BookID   uint          `json:"book_id"`
+	ISBN     string        `json:"isbn"`
+	Provider string        `json:"provider"`
+	Changes  []FieldChange `json:"changes"`
+	Applied  []string      `json:"applied,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Book     *BookResponse `json:"book,omitempty"`
+}
+
+// ApplyEnrichmentRequest selects the fields to apply. Without fields only the changes
+// that fill empty fields are applied; listed fields are applied even if they overwrite.
+type ApplyEnrichmentRequest struct {
+	Fields []string `json:"fields" binding:"omitempty,dive,oneof=title authors description page_count publication_year publisher cover"`
+}
+
+// EnrichmentJobRequest starts a batch enrichment. Without book IDs the job picks books
+// with an ISBN that lack a description, page count, cover or authors.
+type EnrichmentJobRequest struct {
+	BookIDs []uint   `json:"book_ids"`
+	Fields  []string `json:"fields" binding:"omitempty,dive,oneof=title authors description page_count publication_year publisher cover"`
+	Apply   bool     `json:"apply"`
+	Limit   int      `json:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// EnrichmentJobResponse is the progress of a batch enrichment. Status is running, done,
+// or cancelled when the server shut down before the last book.
+type EnrichmentJobResponse struct {
+	ID         string               `json:"id"`
+	Status     string               `json:"status"`
+	Apply      bool                 `json:"apply"`
+	Total      int                  `json:"total"`
+	Processed  int                  `json:"processed"`
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt *time.Time           `json:"finished_at,omitempty"`
+	Results    []EnrichmentProposal `json:"results"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+type CreateGenreRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// UpdateGenreRequest moves a genre to the root of the tree when MoveToRoot is set.
+type UpdateGenreRequest struct {
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	ParentID   *uint  `json:"parent_id"`
+	MoveToRoot bool   `json:"move_to_root"`
+}
+
+type GenreSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type GenreResponse struct {
+	ID       uint            `json:"id"`
+	Name     string          `json:"name"`
+	Slug     string          `json:"slug"`
+	ParentID *uint           `json:"parent_id"`
+	Children []GenreResponse `json:"children,omitempty"`
+}
+
+type TagResponse struct {
+	Name  string `json:"name"`
+	Books int64  `json:"books"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+// ImportRowResult reports what happened to one input row. Row numbers are 1-based and
+// count data rows only, so the CSV header is not row 1.
+type ImportRowResult struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	BookID uint     `json:"book_id,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ImportReport summarizes an import. UnrecognizedFields counts the source fields that
+// could not be mapped onto a book, e.g. ONIX element paths or MARC subfields like "245$c".
+type ImportReport struct {
+	DryRun             bool              `json:"dry_run"`
+	Mode               string            `json:"mode"`
+	Format             string            `json:"format,omitempty"`
+	Total              int               `json:"total"`
+	Created            int               `json:"created"`
+	Updated            int               `json:"updated"`
+	Unchanged          int               `json:"unchanged"`
+	Valid              int               `json:"valid"`
+	Failed             int               `json:"failed"`
+	AuthorsCreated     []string          `json:"authors_created"`
+	PublishersCreated  []string          `json:"publishers_created"`
+	UnrecognizedFields map[string]int    `json:"unrecognized_fields,omitempty"`
+	Rows               []ImportRowResult `json:"rows"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+// DuplicateCandidate is a pair of records that probably describe the same author or
+// book. Reasons lists why: same_normalized_name, similar_name, same_isbn,
+// same_title_and_author or similar_title.
+type DuplicateCandidate struct {
+	LeftID     uint     `json:"left_id"`
+	LeftName   string   `json:"left_name"`
+	RightID    uint     `json:"right_id"`
+	RightName  string   `json:"right_name"`
+	Similarity float64  `json:"similarity"`
+	Reasons    []string `json:"reasons"`
+}
+
+// MergeRequest names the records to fold into the survivor given in the URL.
+type MergeRequest struct {
+	DuplicateIDs []uint `json:"duplicate_ids" binding:"required,min=1"`
+}
+
+type MergeResponse struct {
+	SurvivorID         uint   `json:"survivor_id"`
+	MergedIDs          []uint `json:"merged_ids"`
+	ContributionsMoved int64  `json:"contributions_moved"`
+	ReviewsMoved       int64  `json:"reviews_moved"`
+}
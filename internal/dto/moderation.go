@@ -0,0 +1,43 @@
+package dto
+
+import "time"
+
+type FlagReviewRequest struct {
+	Reason string `json:"reason" binding:"required,oneof=spam abuse spoiler off_topic other"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+type FlagResponse struct {
+	ID        uint      `json:"id"`
+	ReviewID  uint      `json:"review_id"`
+	Reason    string    `json:"reason"`
+	Note      string    `json:"note,omitempty"`
+	FlaggedBy string    `json:"flagged_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModerationQueueItem is a review waiting for a moderator: pending publication, flagged
+// by readers, or both.
+type ModerationQueueItem struct {
+	Review ReviewResponse `json:"review"`
+	Flags  []FlagResponse `json:"flags"`
+}
+
+// ModerateReviewRequest acts on a review. publish, hide and reject set its status,
+// dismiss leaves it; all of them resolve the open flags.
+type ModerateReviewRequest struct {
+	Action string `json:"action" binding:"required,oneof=publish hide reject dismiss"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+type ModerationActionResponse struct {
+	ID            uint      `json:"id"`
+	ReviewID      uint      `json:"review_id"`
+	Action        string    `json:"action"`
+	FromStatus    string    `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	Note          string    `json:"note,omitempty"`
+	FlagsResolved int       `json:"flags_resolved"`
+	Moderator     string    `json:"moderator"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package dto
+
+type CreatePublisherRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Website string `json:"website" binding:"omitempty,url"`
+	Country string `json:"country"`
+}
+
+type UpdatePublisherRequest struct {
+	Name    string `json:"name"`
+	Website string `json:"website" binding:"omitempty,url"`
+	Country string `json:"country"`
+}
+
+type ImprintRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type PublisherSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type ImprintSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type ImprintResponse struct {
+	ID          uint   `json:"id"`
+	PublisherID uint   `json:"publisher_id"`
+	Name        string `json:"name"`
+}
+
+type PublisherResponse struct {
+	ID       uint              `json:"id"`
+	Name     string            `json:"name"`
+	Website  string            `json:"website"`
+	Country  string            `json:"country"`
+	Imprints []ImprintResponse `json:"imprints"`
+}
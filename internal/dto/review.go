@@ -1,9 +1,12 @@
 package dto
 
+import "time"
+
+// CreateReviewRequest is posted to the book being reviewed; the reviewer is the user
+// the token was issued to.
 type CreateReviewRequest struct {
 	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
 	Comment string `json:"comment" binding:"required"`
-	BookID  uint   `json:"book_id" binding:"required"`
 }
 
 type UpdateReviewRequest struct {
@@ -11,10 +14,39 @@ type UpdateReviewRequest struct {
 	Comment string `json:"comment"`
 }
 
+// ReviewResponse names the reviewer by their public username. Reviews written before
+// reviews had owners have neither user_id nor reviewer. Status is pending, published,
+// hidden or rejected; readers only see published reviews.
 type ReviewResponse struct {
 	ID         uint   `json:"id"`
 	Rating     int    `json:"rating"`
 	Comment    string `json:"comment"`
 	DatePosted string `json:"date_posted"`
+	Status     string `json:"status"`
 	BookID     uint   `json:"book_id"`
+	UserID     *uint  `json:"user_id"`
+	Reviewer   string `json:"reviewer"`
+	// HelpfulVotes and UnhelpfulVotes count readers' votes; HelpfulScore is the lower
+	// bound of the Wilson score interval of the helpful share, which reviews sort by.
+	HelpfulVotes   int       `json:"helpful_votes"`
+	UnhelpfulVotes int       `json:"unhelpful_votes"`
+	HelpfulScore   float64   `json:"helpful_score"`
+	CommentCount   int       `json:"comment_count"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// VoteReviewRequest says whether the review was helpful. Helpful is a pointer so that
+// false can be told apart from a missing field.
+type VoteReviewRequest struct {
+	Helpful *bool `json:"helpful" binding:"required"`
+}
+
+// ReviewVoteResponse is the voter's current vote, null once taken back, with the new
+// totals of the review.
+type ReviewVoteResponse struct {
+	ReviewID       uint    `json:"review_id"`
+	Helpful        *bool   `json:"helpful"`
+	HelpfulVotes   int     `json:"helpful_votes"`
+	UnhelpfulVotes int     `json:"unhelpful_votes"`
+	HelpfulScore   float64 `json:"helpful_score"`
 }
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type RevisionResponse struct {
+	Entity       string          `json:"entity"`
+	EntityID     uint            `json:"entity_id"`
+	Version      int             `json:"version"`
+	Action       string          `json:"action"`
+	RevertedFrom *int            `json:"reverted_from,omitempty"`
+	ChangedBy    string          `json:"changed_by"`
+	ChangedAt    time.Time       `json:"changed_at"`
+	Changed      []string        `json:"changed,omitempty"`
+	Snapshot     json.RawMessage `json:"snapshot"`
+}
+
+// RevisionChange is a field that differs between two revisions.
+type RevisionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type RevisionDiffResponse struct {
+	Entity   string           `json:"entity"`
+	EntityID uint             `json:"entity_id"`
+	From     int              `json:"from"`
+	To       int              `json:"to"`
+	Changes  []RevisionChange `json:"changes"`
+}
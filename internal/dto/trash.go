@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+// TrashItem is a soft deleted book, author or review. MergedInto is set for records
+// that were merged into another one, PurgeAt when the retention job will remove it.
+type TrashItem struct {
+	Entity     string     `json:"entity"`
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	DeletedAt  time.Time  `json:"deleted_at"`
+	DeletedBy  string     `json:"deleted_by,omitempty"`
+	MergedInto *uint      `json:"merged_into,omitempty"`
+	PurgeAt    *time.Time `json:"purge_at,omitempty"`
+}
+
+// TrashRestoreResponse lists what came back with the restored record.
+type TrashRestoreResponse struct {
+	Entity          string `json:"entity"`
+	ID              uint   `json:"id"`
+	RestoredBooks   []uint `json:"restored_books,omitempty"`
+	RestoredReviews []uint `json:"restored_reviews,omitempty"`
+	RestoredWorks   []uint `json:"restored_works,omitempty"`
+}
+
+type TrashPurgeReport struct {
+	DeletedBefore time.Time `json:"deleted_before"`
+	Books         int       `json:"books"`
+	Authors       int       `json:"authors"`
+	Reviews       int       `json:"reviews"`
+}
@@ -0,0 +1,62 @@
+package dto
+
+type CreateWorkRequest struct {
+	Title              string `json:"title" binding:"required"`
+	OriginalLanguage   string `json:"original_language"`
+	FirstPublishedYear int    `json:"first_published_year"`
+	SeriesID           *uint  `json:"series_id"`
+	SeriesPosition     int    `json:"series_position" binding:"min=0"`
+}
+
+type UpdateWorkRequest struct {
+	Title              string `json:"title"`
+	OriginalLanguage   string `json:"original_language"`
+	FirstPublishedYear int    `json:"first_published_year"`
+	SeriesID           *uint  `json:"series_id"`
+	SeriesPosition     int    `json:"series_position" binding:"min=0"`
+	LeaveSeries        bool   `json:"leave_series"`
+}
+
+// EditionSummary is the short form of a book when listed under its work.
+type EditionSummary struct {
+	ID              uint   `json:"id"`
+	Title           string `json:"title"`
+	ISBN            string `json:"isbn"`
+	Format          string `json:"format"`
+	Publisher       string `json:"publisher"`
+	Language        string `json:"language"`
+	PublicationYear int    `json:"publication_year"`
+}
+
+type WorkResponse struct {
+	ID                 uint             `json:"id"`
+	Title              string           `json:"title"`
+	OriginalLanguage   string           `json:"original_language"`
+	FirstPublishedYear int              `json:"first_published_year"`
+	SeriesID           *uint            `json:"series_id"`
+	SeriesPosition     int              `json:"series_position"`
+	Editions           []EditionSummary `json:"editions"`
+}
+
+type CreateSeriesRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateSeriesRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SeriesWork struct {
+	ID             uint   `json:"id"`
+	Title          string `json:"title"`
+	SeriesPosition int    `json:"series_position"`
+}
+
+type SeriesResponse struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Works       []SeriesWork `json:"works"`
+}
@@ -0,0 +1,166 @@
+// Package export writes tabular rows as CSV, NDJSON or XLSX one row at a time, so
+// large exports never have to be held in memory.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+// ParseFormat accepts the format names used in query parameters.
+func ParseFormat(name string) (Format, bool) {
+	switch name {
+	case "", "csv":
+		return FormatCSV, true
+	case "ndjson", "jsonl":
+		return FormatNDJSON, true
+	case "xlsx":
+		return FormatXLSX, true
+	}
+	return "", false
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Writer writes the rows of one table. Values are the ones database/sql scans into an
+// interface{}: nil, int64, float64, bool, []byte, string or time.Time.
+type Writer interface {
+	WriteRow(values []interface{}) error
+	// Close finishes the output. It does not close the underlying io.Writer.
+	Close() error
+}
+
+// NewWriter starts a table with the given columns. The name is used as the sheet name
+// of XLSX workbooks.
+func NewWriter(format Format, w io.Writer, name string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, name, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer, record: make([]string, len(columns))}, nil
+}
+
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	for i, value := range values {
+		w.record[i] = formatValue(value)
+	}
+	return w.writer.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// ndjsonWriter writes each row as an object with its keys in column order, which a
+// map would lose, so the fields come out in the order they were asked for.
+type ndjsonWriter struct {
+	w    io.Writer
+	keys [][]byte
+	line []byte
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) (*ndjsonWriter, error) {
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return &ndjsonWriter{w: w, keys: keys}, nil
+}
+
+func (w *ndjsonWriter) WriteRow(values []interface{}) error {
+	w.line = append(w.line[:0], '{')
+	for i, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			value = string(v)
+		case time.Time:
+			value = v.UTC().Format(time.RFC3339)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			w.line = append(w.line, ',')
+		}
+		w.line = append(w.line, w.keys[i]...)
+		w.line = append(w.line, ':')
+		w.line = append(w.line, encoded...)
+	}
+	w.line = append(w.line, '}', '\n')
+	_, err := w.w.Write(w.line)
+	return err
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// formatValue renders a scanned value as text; NULL becomes the empty string.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,35 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNDJSONWriterKeepsColumnOrder(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(FormatNDJSON, &out, "books", []string{"title", "id", "isbn", "created_at", "average"})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	created := time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	rows := [][]interface{}{
+		{"Dune <1>", int64(7), []byte("9780441013593"), created, 4.25},
+		{"Emma", int64(8), nil, nil, nil},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := `{"title":"Dune \u003c1\u003e","id":7,"isbn":"9780441013593","created_at":"2024-05-01T12:30:00Z","average":4.25}
+{"title":"Emma","id":8,"isbn":null,"created_at":null,"average":null}
+`
+	if out.String() != want {
+		t.Errorf("wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -59,7 +59,7 @@ func Login(c *gin.Context) {
 	}
 
 	// Generate tokens
-	accessToken, refreshToken, err := utils.GenerateTokens(user.Username, string(user.Role))
+	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Username, string(user.Role))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
@@ -85,8 +85,15 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Generate new tokens
-	accessToken, refreshToken, err := utils.GenerateTokens(claims.Username, claims.Role)
+	// Tokens issued before they carried the user ID only have the username to go by
+	var user models.User
+	if err := db.Where("username = ?", claims.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	// Generate new tokens with the current role, so promotions and demotions apply on refresh
+	accessToken, refreshToken, err := utils.GenerateTokens(user.ID, user.Username, string(user.Role))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
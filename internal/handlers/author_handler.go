@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"go-rest-api-ozgur/internal/dto"
 	"go-rest-api-ozgur/internal/models"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreateAuthor godoc
@@ -16,7 +22,7 @@ import (
 // @Produce json
 // @Param author body dto.CreateAuthorRequest true "Create author"
 // @Success 201 {object} dto.AuthorResponse
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/authors [post]
 func CreateAuthor(c *gin.Context) {
@@ -27,81 +33,215 @@ func CreateAuthor(c *gin.Context) {
 		return
 	}
 
+	born, died, err := parseAuthorDates(req.BirthDate, req.DeathDate)
+	if err != nil {
+		respondInvalidAuthorDates(c, err)
+		return
+	}
+
 	author := models.Author{
-		Name:      req.Name,
-		Biography: req.Biography,
-		BirthDate: req.BirthDate,
+		Name:        req.Name,
+		Biography:   req.Biography,
+		Born:        born,
+		Died:        died,
+		Nationality: req.Nationality,
 	}
 
-	if err := db.Create(&author).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&author).Error; err != nil {
+			return err
+		}
+		aliases, err := replaceAliases(tx, author.ID, req.Aliases)
+		if err != nil {
+			return err
+		}
+		author.Aliases = aliases
+		return recordRevision(tx, historyAuthor, author.ID, models.RevisionCreate, actor(c))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author", "Check if its already exist": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, dto.AuthorResponse{
-		ID:        author.ID,
-		Name:      author.Name,
-		Biography: author.Biography,
-		BirthDate: author.BirthDate,
-	})
+	c.Header("ETag", entityTag(author.ID, author.UpdatedAt, ""))
+	c.JSON(http.StatusCreated, toAuthorResponse(author))
 }
 
 // GetAuthors godoc
 // @Summary Get all authors
-// @Description Get a list of all authors
+// @Description Get a page of authors in alphabetical order of surname. q searches names and aliases ignoring
+// @Description case, accents and punctuation, so a pen name finds the author who wrote under it; matched_alias
+// @Description tells which alias matched. letter limits the list to surnames starting with that letter, or "#"
+// @Description for those not starting with A to Z.
 // @Tags authors
 // @Accept json
 // @Produce json
+// @Param q query string false "Name or alias to search for"
+// @Param letter query string false "A to Z, or # for other surnames"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Records per page (default 20, max 100)"
 // @Success 200 {array} dto.AuthorResponse
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/v1/authors [get]
 func GetAuthors(c *gin.Context) {
-	var authors []models.Author
+	options, ok := parseReadOptions(c, dto.AuthorResponse{})
+	if !ok {
+		return
+	}
+
+	key := models.SearchKey(c.Query("q"))
+	query := searchAuthors(db.Model(&models.Author{}), key)
+	if letter := strings.TrimSpace(c.Query("letter")); letter != "" {
+		condition, ok := authorLetterCondition(letter)
+		if !ok {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid letter",
+				Details: "letter takes one of A to Z, or # for surnames starting otherwise",
+			})
+			return
+		}
+		query = query.Where(condition)
+	}
 
-	if err := db.Find(&authors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authors", "Check if they exist": err.Error()})
+	paged, err := paginate(c, query, &models.Author{})
+	if err != nil {
+		respondAuthorListError(c, err)
+		return
+	}
+	var authors []models.Author
+	if err := withAliases(paged).Order("authors.sort_key, authors.id").Find(&authors).Error; err != nil {
+		respondAuthorListError(c, err)
 		return
 	}
 
-	var response []dto.AuthorResponse
+	response := []interface{}{}
 	for _, author := range authors {
-		response = append(response, dto.AuthorResponse{
-			ID:        author.ID,
-			Name:      author.Name,
-			Biography: author.Biography,
-			BirthDate: author.BirthDate,
-		})
+		result := toAuthorResponse(author)
+		result.MatchedAlias = matchedAlias(author, key)
+		response = append(response, options.shape(result, nil))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GetAuthorIndex godoc
+// @Summary Count authors by letter
+// @Description Count the authors by the first letter of their surname, for browsing them from A to Z. Every
+// @Description letter is listed, with a count of zero if no surname starts with it, followed by "#" for the
+// @Description surnames that do not start with a letter from A to Z. Pass a letter to GET /authors to list them.
+// @Tags authors
+// @Produce json
+// @Success 200 {array} dto.AuthorIndexEntry
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/authors/index [get]
+func GetAuthorIndex(c *gin.Context) {
+	var counts []dto.AuthorIndexEntry
+	err := db.Model(&models.Author{}).
+		Select(`CASE WHEN sort_key ~ '^[a-z]' THEN UPPER(LEFT(sort_key, 1)) ELSE '#' END AS letter, COUNT(*) AS count`).
+		Group("letter").
+		Scan(&counts).Error
+	if err != nil {
+		respondAuthorListError(c, err)
+		return
+	}
+	byLetter := map[string]int64{}
+	for _, entry := range counts {
+		byLetter[entry.Letter] = entry.Count
+	}
+
+	index := make([]dto.AuthorIndexEntry, 0, 27)
+	for letter := 'A'; letter <= 'Z'; letter++ {
+		index = append(index, dto.AuthorIndexEntry{Letter: string(letter), Count: byLetter[string(letter)]})
+	}
+	index = append(index, dto.AuthorIndexEntry{Letter: "#", Count: byLetter["#"]})
+	c.JSON(http.StatusOK, index)
+}
+
 // GetAuthor godoc
 // @Summary Get specific author info
-// @Description Get an author by given id
+// @Description Get an author by given id. An author that was merged into another one redirects to it.
+// @Description include=bibliography lists their credits by year, include=stats counts their books and reviews
+// @Description and averages the ratings.
 // @Tags authors
 // @Accept json
 // @Produce json
 // @Param id path string true "Author ID"
+// @Param include query string false "Related resources to embed: books, bibliography, stats"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} dto.AuthorResponse
+// @Success 304 "The cached copy is still current"
+// @Failure 301 {string} string "The author was merged, Location points at the surviving author"
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/authors/{id} [get]
 func GetAuthor(c *gin.Context) {
 	id := c.Param("id")
+	options, ok := parseReadOptions(c, dto.AuthorResponse{}, "books", "bibliography", "stats")
+	if !ok {
+		return
+	}
 
 	var author models.Author
-	if err := db.First(&author, id).Error; err != nil {
+	if err := withAliases(db).First(&author, id).Error; err != nil {
+		if redirectMerged(c, redirectAuthor, id) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "There is no Author with such credentials"})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.AuthorResponse{
-		ID:        author.ID,
-		Name:      author.Name,
-		Biography: author.Biography,
-		BirthDate: author.BirthDate,
-	})
+	included := map[string]interface{}{}
+	variant := options.variant()
+	if options.includes("books") {
+		var books []models.Book
+		err := withBookDetails(db).
+			Where("books.id IN (SELECT book_id FROM book_contributors WHERE author_id = ?)", author.ID).
+			Order("books.id").
+			Find(&books).Error
+		if err != nil {
+			respondAuthorIncludeError(c, err)
+			return
+		}
+		response := make([]dto.BookResponse, 0, len(books))
+		stamps := make([]time.Time, 0, len(books))
+		for _, book := range books {
+			response = append(response, toBookResponse(book))
+			stamps = append(stamps, book.UpdatedAt)
+		}
+		included["books"] = response
+		variant += includedVersion(stamps)
+	}
+	if options.includes("bibliography") {
+		entries, stamps, err := authorBibliography(author.ID)
+		if err != nil {
+			respondAuthorIncludeError(c, err)
+			return
+		}
+		included["bibliography"] = entries
+		variant += includedVersion(stamps)
+	}
+	if options.includes("stats") {
+		stats, err := authorStats(author.ID)
+		if err != nil {
+			respondAuthorIncludeError(c, err)
+			return
+		}
+		included["stats"] = stats
+		variant += fmt.Sprintf(";%d/%d", stats.BookCount, stats.ReviewCount)
+		if stats.AverageRating != nil {
+			variant += fmt.Sprintf("/%.2f", *stats.AverageRating)
+		}
+	}
+	if notModified(c, entityTag(author.ID, author.UpdatedAt, variant)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, options.shape(toAuthorResponse(author), included))
 }
 
 // UpdateAuthor godoc
@@ -112,9 +252,12 @@ func GetAuthor(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Author ID"
 // @Param author body dto.UpdateAuthorRequest true "Update author"
+// @Param If-Match header string false "ETag of the version being changed"
 // @Success 200 {object} dto.AuthorResponse
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/authors/{id} [put]
 func UpdateAuthor(c *gin.Context) {
@@ -131,6 +274,10 @@ func UpdateAuthor(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "There is no Author with such credentials"})
 		return
 	}
+	if !checkIfMatch(c, "author", entityTag(author.ID, author.UpdatedAt, "")) {
+		return
+	}
+	readAt := author.UpdatedAt
 
 	if req.Name != "" {
 		author.Name = req.Name
@@ -138,32 +285,139 @@ func UpdateAuthor(c *gin.Context) {
 	if req.Biography != "" {
 		author.Biography = req.Biography
 	}
-	if req.BirthDate != "" {
-		author.BirthDate = req.BirthDate
+	if req.Nationality != "" {
+		author.Nationality = req.Nationality
+	}
+	born, died, err := parseAuthorDates(req.BirthDate, req.DeathDate)
+	if err != nil {
+		respondInvalidAuthorDates(c, err)
+		return
+	}
+	if born.Date != nil {
+		author.Born = born
+	}
+	if died.Date != nil {
+		author.Died = died
+	}
+	if author.Died.Before(author.Born) {
+		respondInvalidAuthorDates(c, errDiedBeforeBorn)
+		return
 	}
 
-	if err := db.Save(&author).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := guardVersion(c, tx, &models.Author{}, author.ID, readAt); err != nil {
+			return err
+		}
+		if err := tx.Save(&author).Error; err != nil {
+			return err
+		}
+		if req.Aliases != nil {
+			if _, err := replaceAliases(tx, author.ID, req.Aliases); err != nil {
+				return err
+			}
+		}
+		forgetAuthorBooks(tx, author.ID)
+		return recordRevision(tx, historyAuthor, author.ID, models.RevisionUpdate, actor(c))
+	})
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(c, "author", "")
+		return
+	}
+	if err == nil {
+		err = withAliases(db).First(&author, author.ID).Error
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author", "": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.AuthorResponse{
-		ID:        author.ID,
-		Name:      author.Name,
-		Biography: author.Biography,
-		BirthDate: author.BirthDate,
-	})
+	c.Header("ETag", entityTag(author.ID, author.UpdatedAt, ""))
+	c.JSON(http.StatusOK, toAuthorResponse(author))
+}
+
+// PatchAuthor godoc
+// @Summary Patch an author
+// @Description Change some fields of an author with a JSON Merge Patch (RFC 7396, application/merge-patch+json)
+// @Description or a JSON Patch (RFC 6902, application/json-patch+json) against the fields of
+// @Description dto.CreateAuthorRequest. Unlike PUT, empty values are applied. The result is validated like a new
+// @Description author, so fields may be emptied but not removed.
+// @Tags authors
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Author ID"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Param If-Match header string false "ETag of the version being changed"
+// @Success 200 {object} dto.AuthorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "A JSON Patch test failed"
+// @Failure 412 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/authors/{id} [patch]
+func PatchAuthor(c *gin.Context) {
+	var author models.Author
+	if err := withAliases(db).First(&author, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Author not found",
+			Details: "The author with the given ID does not exist",
+		})
+		return
+	}
+	if !checkIfMatch(c, "author", entityTag(author.ID, author.UpdatedAt, "")) {
+		return
+	}
+
+	var req dto.CreateAuthorRequest
+	if !patchDocument(c, toAuthorSnapshot(author), &req) {
+		return
+	}
+	if _, _, err := parseAuthorDates(req.BirthDate, req.DeathDate); err != nil {
+		respondUnprocessablePatch(c, err.Error())
+		return
+	}
+	snapshot := authorSnapshot{
+		Name:        req.Name,
+		Biography:   req.Biography,
+		BirthDate:   req.BirthDate,
+		DeathDate:   req.DeathDate,
+		Nationality: req.Nationality,
+		Aliases:     req.Aliases,
+	}
+	if !commitPatch(c, historyAuthor, &models.Author{}, author.ID, author.UpdatedAt, snapshot, nil) {
+		return
+	}
+
+	if err := withAliases(db).First(&author, author.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update author",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.Header("ETag", entityTag(author.ID, author.UpdatedAt, ""))
+	c.JSON(http.StatusOK, toAuthorResponse(author))
 }
 
 // DeleteAuthor godoc
 // @Summary Delete an author
-// @Description Delete an author by given id
+// @Description Delete an author by given id. Their books and reviews are handled according to the configured
+// @Description DELETE_POLICY (restrict, cascade or soft-cascade).
 // @Tags authors
 // @Accept json
 // @Produce json
 // @Param id path string true "Author ID"
+// @Param If-Match header string false "ETag of the version being changed"
 // @Success 200 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/authors/{id} [delete]
 func DeleteAuthor(c *gin.Context) {
@@ -174,11 +428,216 @@ func DeleteAuthor(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "There is no author with such credentials"})
 		return
 	}
+	if !checkIfMatch(c, "author", entityTag(author.ID, author.UpdatedAt, "")) {
+		return
+	}
 
-	if err := db.Delete(&author).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := guardVersion(c, tx, &models.Author{}, author.ID, author.UpdatedAt); err != nil {
+			return err
+		}
+		if err := recordRevision(tx, historyAuthor, author.ID, models.RevisionDelete, actor(c)); err != nil {
+			return err
+		}
+		return deleteAuthor(tx, &author, cfg.DeletePolicy, actor(c))
+	})
+	var dependents *dependentsError
+	if errors.As(err, &dependents) {
+		respondDependents(c, "author", dependents)
+		return
+	}
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(c, "author", "")
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author", "": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Author deleted, Long live oppresive press"})
 }
+
+// authorBibliography lists the credits of an author on live books, oldest first, with
+// the times the books last changed.
+func authorBibliography(authorID uint) ([]dto.BibliographyEntry, []time.Time, error) {
+	var rows []struct {
+		dto.BibliographyEntry
+		UpdatedAt time.Time
+	}
+	err := db.Table("book_contributors bc").
+		Select("b.id AS book_id, b.title, b.publication_year, bc.role, b.updated_at").
+		Joins("JOIN books b ON b.id = bc.book_id AND b.deleted_at IS NULL").
+		Where("bc.author_id = ?", authorID).
+		Order("b.publication_year, b.title, bc.role").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]dto.BibliographyEntry, 0, len(rows))
+	stamps := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, row.BibliographyEntry)
+		stamps = append(stamps, row.UpdatedAt)
+	}
+	return entries, stamps, nil
+}
+
+// authorStats counts the live books an author is credited on and the reviews of those
+// books, and averages their ratings.
+func authorStats(authorID uint) (dto.AuthorStats, error) {
+	var stats dto.AuthorStats
+	err := db.Raw(`
+		WITH credited AS (
+			SELECT b.id FROM books b
+			WHERE b.deleted_at IS NULL AND b.id IN (SELECT book_id FROM book_contributors WHERE author_id = ?))
+		SELECT (SELECT COUNT(*) FROM credited) AS book_count,
+			COUNT(r.id) AS review_count,
+			ROUND(AVG(r.rating), 2)::float8 AS average_rating
+		FROM reviews r
+		WHERE r.deleted_at IS NULL AND r.status = ? AND r.book_id IN (SELECT id FROM credited)`, authorID, models.ReviewPublished).
+		Scan(&stats).Error
+	return stats, err
+}
+
+func respondAuthorIncludeError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to fetch the books of the author",
+		Details: err.Error(),
+	})
+}
+
+var errDiedBeforeBorn = errors.New("death_date is before birth_date")
+
+// parseAuthorDates reads the dates of birth and death of an author, either of which may
+// be empty, and checks that they are in order.
+func parseAuthorDates(birth, death string) (born, died models.PartialDate, err error) {
+	if born, err = models.ParsePartialDate(birth); err != nil {
+		return born, died, fmt.Errorf("birth_date: %w", err)
+	}
+	if died, err = models.ParsePartialDate(death); err != nil {
+		return born, died, fmt.Errorf("death_date: %w", err)
+	}
+	if died.Before(born) {
+		return born, died, errDiedBeforeBorn
+	}
+	return born, died, nil
+}
+
+func respondInvalidAuthorDates(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid date",
+		Details: err.Error(),
+	})
+}
+
+// withAliases preloads the aliases of authors in alphabetical order.
+// searchAuthors limits query to the authors whose name or one of whose aliases
+// contains the search key. The name also matches surname first, so "King Stephen"
+// finds Stephen King. An empty key leaves query as it is.
+func searchAuthors(query *gorm.DB, key string) *gorm.DB {
+	if key == "" {
+		return query
+	}
+	pattern := "%" + key + "%"
+	return query.Where(
+		"authors.name_key LIKE ? OR REPLACE(authors.sort_key, ' ', '') LIKE ? OR authors.id IN (SELECT author_id FROM author_aliases WHERE name_key LIKE ?)",
+		pattern, pattern, pattern,
+	)
+}
+
+// authorLetterCondition turns the letter of the alphabetical index into a condition on
+// the sort key.
+func authorLetterCondition(letter string) (string, bool) {
+	if letter == "#" {
+		return "authors.sort_key !~ '^[a-z]'", true
+	}
+	letter = strings.ToLower(letter)
+	if len(letter) != 1 || letter < "a" || letter > "z" {
+		return "", false
+	}
+	return fmt.Sprintf("authors.sort_key LIKE '%s%%'", letter), true
+}
+
+// matchedAlias names the alias a search for key found the author by, or nothing when
+// the name matched.
+func matchedAlias(author models.Author, key string) string {
+	if key == "" || strings.Contains(author.NameKey, key) || strings.Contains(strings.ReplaceAll(author.SortKey, " ", ""), key) {
+		return ""
+	}
+	for _, alias := range author.Aliases {
+		if strings.Contains(alias.NameKey, key) {
+			return alias.Name
+		}
+	}
+	return ""
+}
+
+func respondAuthorListError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to fetch authors",
+		Details: err.Error(),
+	})
+}
+
+func withAliases(query *gorm.DB) *gorm.DB {
+	return query.Preload("Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("name") })
+}
+
+// replaceAliases sets the aliases of an author, dropping blanks and repeats, and
+// returns the stored rows.
+func replaceAliases(tx *gorm.DB, authorID uint, names []string) ([]models.AuthorAlias, error) {
+	if err := tx.Where("author_id = ?", authorID).Delete(&models.AuthorAlias{}).Error; err != nil {
+		return nil, err
+	}
+	aliases := []models.AuthorAlias{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		aliases = append(aliases, models.AuthorAlias{AuthorID: authorID, Name: name})
+	}
+	if len(aliases) == 0 {
+		return aliases, nil
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	return aliases, tx.Create(&aliases).Error
+}
+
+func aliasNames(aliases []models.AuthorAlias) []string {
+	names := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		names = append(names, alias.Name)
+	}
+	return names
+}
+
+func toAuthorResponse(author models.Author) dto.AuthorResponse {
+	return dto.AuthorResponse{
+		ID:          author.ID,
+		Name:        author.Name,
+		Biography:   author.Biography,
+		BirthDate:   author.Born.String(),
+		DeathDate:   author.Died.String(),
+		Nationality: author.Nationality,
+		Aliases:     aliasNames(author.Aliases),
+		UpdatedAt:   author.UpdatedAt,
+	}
+}
+
+func toAuthorSnapshot(author models.Author) authorSnapshot {
+	return authorSnapshot{
+		Name:        author.Name,
+		Biography:   author.Biography,
+		BirthDate:   author.Born.String(),
+		DeathDate:   author.Died.String(),
+		Nationality: author.Nationality,
+		Aliases:     aliasNames(author.Aliases),
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// withBookDetails preloads everything toBookResponse renders.
+func withBookDetails(query *gorm.DB) *gorm.DB {
+	return withContributors(query).
+		Preload("Genres", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Publisher").
+		Preload("Imprint")
+}
+
+// bookTag is the ETag of a book loaded with withBookDetails. The response names the
+// credited authors, the publisher, the imprint and the genres, which change without
+// touching the book, so their versions are part of the tag.
+func bookTag(book models.Book, variant string) string {
+	return entityTag(book.ID, book.UpdatedAt, variant+bookDetailsVersion(book))
+}
+
+// bookDetailsVersion sums up the related records rendered with a book, see includedVersion.
+func bookDetailsVersion(book models.Book) string {
+	var stamps []time.Time
+	for _, contributor := range book.Contributors {
+		if contributor.Author.ID != 0 {
+			stamps = append(stamps, contributor.Author.UpdatedAt)
+		}
+	}
+	if book.Publisher != nil {
+		stamps = append(stamps, book.Publisher.UpdatedAt)
+	}
+	if book.Imprint != nil {
+		stamps = append(stamps, book.Imprint.UpdatedAt)
+	}
+	for _, genre := range book.Genres {
+		stamps = append(stamps, genre.UpdatedAt)
+	}
+	return includedVersion(stamps)
+}
+
+// checkBookIfMatch honours If-Match on a write to the book, comparing against the same
+// tag GetBook sends.
+func checkBookIfMatch(c *gin.Context, id uint) bool {
+	var book models.Book
+	if err := withBookDetails(db).First(&book, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to read book",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return checkIfMatch(c, "book", bookTag(book, ""))
+}
+
+func toBookResponse(book models.Book) dto.BookResponse {
+	return dto.BookResponse{
+		ID:              book.ID,
+		Title:           book.Title,
+		Contributors:    toContributorResponses(book.Contributors),
+		ISBN:            book.ISBN,
+		PublicationYear: book.PublicationYear,
+		Description:     book.Description,
+		WorkID:          book.WorkID,
+		Format:          string(book.Format),
+		Publisher:       toPublisherSummary(book.Publisher),
+		Imprint:         toImprintSummary(book.Imprint),
+		Cover:           toCoverResponse(book.CoverKey),
+		PageCount:       book.PageCount,
+		Language:        book.Language,
+		Genres:          toGenreSummaries(book.Genres),
+		Tags:            toTagNames(book.Tags),
+		Rating:          toRatingSummary(book.Rating),
+		UpdatedAt:       book.UpdatedAt,
+	}
+}
@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"go-rest-api-ozgur/internal/cache"
 	"go-rest-api-ozgur/internal/dto"
 	"go-rest-api-ozgur/internal/models"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ErrorResponse struct {
@@ -26,6 +29,7 @@ type ErrorResponse struct {
 // @Param book body dto.CreateBookRequest true "Create book"
 // @Success 201 {object} dto.BookResponse
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books [post]
 
@@ -41,15 +45,109 @@ func CreateBook(c *gin.Context) {
 		return
 	}
 
+	contributors, missing, err := buildContributors(db, req.Contributors)
+	if errors.Is(err, errDuplicateContributor) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Internal server error",
+			Details: err.Error(),
+		})
+		return
+	}
+	if missing != 0 {
+		respondMissingReference(c, "author", missing)
+		return
+	}
+
+	genres, missing, err := resolveGenres(db, req.GenreIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Internal server error",
+			Details: err.Error(),
+		})
+		return
+	}
+	if missing != 0 {
+		respondMissingReference(c, "genre", missing)
+		return
+	}
+
+	if req.WorkID != nil {
+		if exists, err := referenceExists(db, &models.Work{}, *req.WorkID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Internal server error",
+				Details: err.Error(),
+			})
+			return
+		} else if !exists {
+			respondMissingReference(c, "work", *req.WorkID)
+			return
+		}
+	}
+
+	publisherID, imprintID, ok := resolvePublisher(c, req.PublisherID, req.ImprintID)
+	if !ok {
+		return
+	}
+
 	book := models.Book{
 		Title:           req.Title,
-		AuthorID:        req.AuthorID,
+		WorkID:          req.WorkID,
+		Contributors:    contributors,
 		ISBN:            req.ISBN,
 		PublicationYear: req.PublicationYear,
 		Description:     req.Description,
+		Format:          models.BookFormat(req.Format),
+		PublisherID:     publisherID,
+		ImprintID:       imprintID,
+		PageCount:       req.PageCount,
+		Language:        req.Language,
+		Genres:          genres,
 	}
 
-	if err := db.Create(&book).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// A book created without a work is the first edition of a new one.
+		if book.WorkID == nil {
+			work := models.Work{
+				Title:              book.Title,
+				OriginalLanguage:   book.Language,
+				FirstPublishedYear: book.PublicationYear,
+			}
+			if err := tx.Create(&work).Error; err != nil {
+				return err
+			}
+			book.WorkID = &work.ID
+		}
+
+		tags, err := resolveTags(tx, req.Tags)
+		if err != nil {
+			return err
+		}
+		book.Tags = tags
+		if err := tx.Omit("Contributors.Author", "Genres.*", "Tags.*").Create(&book).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, historyBook, book.ID, models.RevisionCreate, actor(c))
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Referenced author does not exist",
+				Details: err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "Internal server error",
@@ -58,14 +156,17 @@ func CreateBook(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, dto.BookResponse{
-		ID:              book.ID,
-		Title:           book.Title,
-		AuthorID:        book.AuthorID,
-		ISBN:            book.ISBN,
-		PublicationYear: book.PublicationYear,
-		Description:     book.Description,
-	})
+	if err := withBookDetails(db).First(&book, book.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Internal server error",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", bookTag(book, ""))
+	c.JSON(http.StatusCreated, toBookResponse(book))
 }
 
 // GetBooks godoc
@@ -74,14 +175,68 @@ func CreateBook(c *gin.Context) {
 // @Tags books
 // @Accept json
 // @Produce json
+// @Param genre query string false "Only books in this genre (ID or slug) or any of its descendants"
+// @Param tag query string false "Only books carrying this tag"
+// @Param sort query string false "id (default), title, rating (Bayesian score), average or ratings (most reviewed)"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Books per page (default 20, max 100)"
+// @Param format query string false "json (default), bibtex, ris, csl-json or marcxml"
+// @Param include query string false "Related resources to embed: authors"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
 // @Success 200 {array} dto.BookResponse
 // @Success 200 {object} map[string]string "There are no books in the system"
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books [get]
 func GetBooks(c *gin.Context) {
-	var books []models.Book
+	query := db.Model(&models.Book{})
+	if key := c.Query("genre"); key != "" {
+		genre, err := findGenre(key)
+		if err != nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "Genre not found",
+				Details: "The genre with the given ID or slug does not exist",
+			})
+			return
+		}
+		ids, err := genreSubtreeIDs(genre.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to retrieve books",
+				Details: err.Error(),
+			})
+			return
+		}
+		query = query.Where("books.id IN (SELECT book_id FROM book_genres WHERE genre_id IN ?)", ids)
+	}
+	if tag := normalizeTags([]string{c.Query("tag")}); len(tag) > 0 {
+		query = query.Where("books.id IN (SELECT bt.book_id FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE t.name = ?)", tag[0])
+	}
+
+	order, ok := bookOrder(c)
+	if !ok {
+		return
+	}
+	listBooks(c, query, order, "There are no books in the system")
+}
 
-	if err := db.Find(&books).Error; err != nil {
+// listBooks responds with one page of the books matched by query in the given order,
+// or with emptyMessage when the page is empty.
+func listBooks(c *gin.Context, query *gorm.DB, order, emptyMessage string) {
+	format, err := citationFormat(c)
+	if err != nil {
+		respondInvalidFormat(c, err)
+		return
+	}
+	options, ok := parseReadOptions(c, dto.BookResponse{}, "authors")
+	if !ok {
+		return
+	}
+
+	page, err := paginate(c, query, &models.Book{})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to retrieve books",
@@ -90,21 +245,35 @@ func GetBooks(c *gin.Context) {
 		return
 	}
 
+	if options.includes("authors") {
+		page = page.Preload("Contributors.Author.Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("name") })
+	}
+	var books []models.Book
+	if err := withBookDetails(page).Order(order).Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve books",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if format != "" {
+		respondCitations(c, format, books)
+		return
+	}
 	if len(books) == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "There are no books in the system"})
+		c.JSON(http.StatusOK, gin.H{"message": emptyMessage})
 		return
 	}
 
-	var response []dto.BookResponse
+	response := make([]interface{}, 0, len(books))
 	for _, book := range books {
-		response = append(response, dto.BookResponse{
-			ID:              book.ID,
-			Title:           book.Title,
-			AuthorID:        book.AuthorID,
-			ISBN:            book.ISBN,
-			PublicationYear: book.PublicationYear,
-			Description:     book.Description,
-		})
+		included := map[string]interface{}{}
+		if options.includes("authors") {
+			included["authors"] = creditedAuthors(book)
+		}
+		response = append(response, options.shape(toBookResponse(book), included))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -112,31 +281,85 @@ func GetBooks(c *gin.Context) {
 
 // GetBook godoc
 // @Summary Get a specific book
-// @Description Get a book by its ID
+// @Description Get a book by its ID. Citations in BibTeX, RIS, CSL-JSON or MARCXML can be requested with the
+// @Description format parameter or the Accept header. A book that was merged into another one redirects to it.
 // @Tags books
 // @Accept json
 // @Produce json
+// @Produce application/x-bibtex
+// @Produce application/x-research-info-systems
+// @Produce application/vnd.citationstyles.csl+json
+// @Produce application/marcxml+xml
 // @Param id path string true "Book ID"
+// @Param format query string false "json (default), bibtex, ris, csl-json or marcxml"
+// @Param include query string false "Related resources to embed: authors, reviews"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} dto.BookResponse
+// @Success 304 "The cached copy is still current"
+// @Failure 301 {string} string "The book was merged, Location points at the surviving book"
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books/{id} [get]
 func GetBook(c *gin.Context) {
 	id := c.Param("id")
 
-	// Check if the book is cached
+	format, err := citationFormat(c)
+	if err != nil {
+		respondInvalidFormat(c, err)
+		return
+	}
+	options, ok := parseReadOptions(c, dto.BookResponse{}, "authors", "reviews")
+	if !ok {
+		return
+	}
+	if format != "" {
+		var book models.Book
+		if err := withBookDetails(db).First(&book, id).Error; err != nil {
+			if redirectMerged(c, redirectBook, id) {
+				return
+			}
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "Book not found",
+				Details: "The book with the given ID does not exist",
+			})
+			return
+		}
+		if notModified(c, bookTag(book, string(format))) {
+			return
+		}
+		respondCitations(c, format, []models.Book{book})
+		return
+	}
+
+	// Check if the book is cached; included resources are not, so those need the database
 	cachedBook, err := cache.Get("book:" + id)
-	if err == nil {
-		var book dto.BookResponse
-		if err := json.Unmarshal([]byte(cachedBook), &book); err == nil {
-			c.JSON(http.StatusOK, book)
+	if err == nil && len(options.Include) == 0 {
+		var entry cachedBookEntry
+		if err := json.Unmarshal([]byte(cachedBook), &entry); err == nil && entry.Book.ID != 0 {
+			if notModified(c, entityTag(entry.Book.ID, entry.Book.UpdatedAt, options.variant()+entry.Details)) {
+				return
+			}
+			c.JSON(http.StatusOK, options.shape(entry.Book, nil))
 			return
 		}
 	}
 
 	// Fetch the book from the database
+	query := withBookDetails(db)
+	if options.includes("authors") {
+		query = query.Preload("Contributors.Author.Aliases", func(db *gorm.DB) *gorm.DB { return db.Order("name") })
+	}
+	if options.includes("reviews") {
+		query = query.Preload("Reviews", func(db *gorm.DB) *gorm.DB { return publishedReviews(db).Order("id") }).Preload("Reviews.User")
+	}
 	var book models.Book
-	if err := db.Preload("Author").Preload("Reviews").First(&book, id).Error; err != nil {
+	if err := query.First(&book, id).Error; err != nil {
+		if redirectMerged(c, redirectBook, id) {
+			return
+		}
 		c.JSON(http.StatusNotFound, ErrorResponse{
 			Code:    http.StatusNotFound,
 			Message: "Book not found",
@@ -146,21 +369,48 @@ func GetBook(c *gin.Context) {
 	}
 
 	// Prepare the response
-	response := dto.BookResponse{
-		ID:              book.ID,
-		Title:           book.Title,
-		AuthorID:        book.AuthorID,
-		ISBN:            book.ISBN,
-		PublicationYear: book.PublicationYear,
-		Description:     book.Description,
-	}
+	response := toBookResponse(book)
 
 	// Cache the book for 5 minutes
-	if jsonData, err := json.Marshal(response); err == nil {
+	if jsonData, err := json.Marshal(cachedBookEntry{Book: response, Details: bookDetailsVersion(book)}); err == nil {
 		cache.Set("book:"+id, jsonData, 5*time.Minute)
 	}
 
-	c.JSON(http.StatusOK, response)
+	included := map[string]interface{}{}
+	var stamps []time.Time
+	var counters []string
+	if options.includes("authors") {
+		authors := creditedAuthors(book)
+		for _, author := range authors {
+			stamps = append(stamps, author.UpdatedAt)
+		}
+		included["authors"] = authors
+	}
+	if options.includes("reviews") {
+		reviews := make([]dto.ReviewResponse, 0, len(book.Reviews))
+		for _, review := range book.Reviews {
+			reviews = append(reviews, toReviewResponse(review))
+			stamps = append(stamps, review.UpdatedAt)
+			counters = append(counters, reviewCounters(review))
+		}
+		included["reviews"] = reviews
+	}
+	variant := options.variant()
+	if len(options.Include) > 0 {
+		variant += includedVersion(stamps) + ";" + strings.Join(counters, ",")
+	}
+	if notModified(c, bookTag(book, variant)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, options.shape(response, included))
+}
+
+// cachedBookEntry is a book response kept in Redis together with the version of its
+// related records, so a cached copy gets the same ETag as one read from the database.
+type cachedBookEntry struct {
+	Book    dto.BookResponse `json:"book"`
+	Details string           `json:"details"`
 }
 
 // UpdateBook godoc
@@ -171,9 +421,13 @@ func GetBook(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Book ID"
 // @Param book body dto.UpdateBookRequest true "Update book"
+// @Param If-Match header string false "ETag of the version being changed"
 // @Success 200 {object} dto.BookResponse
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 422 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books/{id} [put]
 func UpdateBook(c *gin.Context) {
@@ -198,13 +452,14 @@ func UpdateBook(c *gin.Context) {
 		})
 		return
 	}
+	if !checkBookIfMatch(c, book.ID) {
+		return
+	}
+	readAt := book.UpdatedAt
 
 	if req.Title != "" {
 		book.Title = req.Title
 	}
-	if req.AuthorID != 0 {
-		book.AuthorID = req.AuthorID
-	}
 	if req.ISBN != "" {
 		book.ISBN = req.ISBN
 	}
@@ -214,8 +469,140 @@ func UpdateBook(c *gin.Context) {
 	if req.Description != "" {
 		book.Description = req.Description
 	}
+	if req.Format != "" {
+		book.Format = models.BookFormat(req.Format)
+	}
+	if req.PublisherID != nil || req.ImprintID != nil {
+		publisherID, imprintID, ok := resolvePublisher(c, req.PublisherID, req.ImprintID)
+		if !ok {
+			return
+		}
+		// Moving a book to another publisher drops an imprint that belongs to the old one.
+		if imprintID == nil && book.PublisherID != nil && *book.PublisherID != *publisherID {
+			book.ImprintID = nil
+		}
+		if imprintID != nil {
+			book.ImprintID = imprintID
+		}
+		book.PublisherID = publisherID
+	}
+	if req.PageCount != 0 {
+		book.PageCount = req.PageCount
+	}
+	if req.Language != "" {
+		book.Language = req.Language
+	}
+	if req.WorkID != nil {
+		if exists, err := referenceExists(db, &models.Work{}, *req.WorkID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update book",
+				Details: err.Error(),
+			})
+			return
+		} else if !exists {
+			respondMissingReference(c, "work", *req.WorkID)
+			return
+		}
+		book.WorkID = req.WorkID
+	}
+
+	var contributors []models.BookContributor
+	if req.Contributors != nil {
+		// Binding skips an empty list, but sending one would remove every credit.
+		if len(req.Contributors) == 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid input data",
+				Details: "A book needs at least one contributor; leave contributors out to keep the current ones",
+			})
+			return
+		}
+		var missing uint
+		var err error
+		contributors, missing, err = buildContributors(db, req.Contributors)
+		if errors.Is(err, errDuplicateContributor) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid input data",
+				Details: err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update book",
+				Details: err.Error(),
+			})
+			return
+		}
+		if missing != 0 {
+			respondMissingReference(c, "author", missing)
+			return
+		}
+	}
+
+	var genres []models.Genre
+	if req.GenreIDs != nil {
+		var missing uint
+		var err error
+		genres, missing, err = resolveGenres(db, req.GenreIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update book",
+				Details: err.Error(),
+			})
+			return
+		}
+		if missing != 0 {
+			respondMissingReference(c, "genre", missing)
+			return
+		}
+	}
 
-	if err := db.Save(&book).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := guardVersion(c, tx, &models.Book{}, book.ID, readAt); err != nil {
+			return err
+		}
+		if err := tx.Omit(ratingColumns...).Save(&book).Error; err != nil {
+			return err
+		}
+		if req.Contributors != nil {
+			if err := replaceContributors(tx, book.ID, contributors); err != nil {
+				return err
+			}
+		}
+		if req.GenreIDs != nil {
+			if err := replaceAssociation(tx, &book, "Genres", genres); err != nil {
+				return err
+			}
+		}
+		if req.Tags != nil {
+			tags, err := resolveTags(tx, req.Tags)
+			if err != nil {
+				return err
+			}
+			if err := replaceAssociation(tx, &book, "Tags", tags); err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, historyBook, book.ID, models.RevisionUpdate, actor(c))
+	})
+	if err != nil {
+		if errors.Is(err, errPreconditionFailed) {
+			respondPreconditionFailed(c, "book", "")
+			return
+		}
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Referenced author does not exist",
+				Details: err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to update book",
@@ -223,26 +610,126 @@ func UpdateBook(c *gin.Context) {
 		})
 		return
 	}
+	forgetBooks([]uint{book.ID})
 
-	c.JSON(http.StatusOK, dto.BookResponse{
-		ID:              book.ID,
-		Title:           book.Title,
-		AuthorID:        book.AuthorID,
-		ISBN:            book.ISBN,
-		PublicationYear: book.PublicationYear,
-		Description:     book.Description,
-	})
+	if err := withBookDetails(db).First(&book, book.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update book",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.Header("ETag", bookTag(book, ""))
+	c.JSON(http.StatusOK, toBookResponse(book))
+}
+
+// PatchBook godoc
+// @Summary Patch a book
+// @Description Change some fields of a book with a JSON Merge Patch (RFC 7396, application/merge-patch+json) or a
+// @Description JSON Patch (RFC 6902, application/json-patch+json) against the fields of dto.CreateBookRequest.
+// @Description Unlike PUT, zero values are applied and null clears optional fields. The result is validated like
+// @Description a new book, so required fields may be emptied but not removed.
+// @Tags books
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Param If-Match header string false "ETag of the version being changed"
+// @Success 200 {object} dto.BookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "A JSON Patch test failed"
+// @Failure 412 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/books/{id} [patch]
+func PatchBook(c *gin.Context) {
+	var book models.Book
+	if err := db.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return
+	}
+	if !checkBookIfMatch(c, book.ID) {
+		return
+	}
+
+	current, err := takeSnapshot(db, historyBook, book.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update book",
+			Details: err.Error(),
+		})
+		return
+	}
+	var req dto.CreateBookRequest
+	if !patchDocument(c, current, &req) {
+		return
+	}
+	publisherID, imprintID, ok := resolvePublisher(c, req.PublisherID, req.ImprintID)
+	if !ok {
+		return
+	}
+
+	snapshot := bookSnapshot{
+		Title:           req.Title,
+		ISBN:            req.ISBN,
+		PublicationYear: req.PublicationYear,
+		Description:     req.Description,
+		Format:          req.Format,
+		WorkID:          req.WorkID,
+		PublisherID:     publisherID,
+		ImprintID:       imprintID,
+		PageCount:       req.PageCount,
+		Language:        req.Language,
+		GenreIDs:        req.GenreIDs,
+		Tags:            req.Tags,
+	}
+	for _, contributor := range req.Contributors {
+		snapshot.Contributors = append(snapshot.Contributors, contributorSnapshot{
+			AuthorID: contributor.AuthorID,
+			Role:     contributor.Role,
+		})
+	}
+	if !commitPatch(c, historyBook, &models.Book{}, book.ID, book.UpdatedAt, snapshot, nil) {
+		return
+	}
+
+	if err := withBookDetails(db).First(&book, book.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update book",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.Header("ETag", bookTag(book, ""))
+	c.JSON(http.StatusOK, toBookResponse(book))
 }
 
 // DeleteBook godoc
 // @Summary Delete a book
-// @Description Delete a book by its ID
+// @Description Delete a book by its ID. Its reviews are handled according to the configured DELETE_POLICY
+// @Description (restrict, cascade or soft-cascade).
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param If-Match header string false "ETag of the version being changed"
 // @Success 200 {object} map[string]string "Ignorance is BLISS"
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books/{id} [delete]
 func DeleteBook(c *gin.Context) {
@@ -257,8 +744,29 @@ func DeleteBook(c *gin.Context) {
 		})
 		return
 	}
+	if !checkBookIfMatch(c, book.ID) {
+		return
+	}
 
-	if err := db.Delete(&book).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := guardVersion(c, tx, &models.Book{}, book.ID, book.UpdatedAt); err != nil {
+			return err
+		}
+		if err := recordRevision(tx, historyBook, book.ID, models.RevisionDelete, actor(c)); err != nil {
+			return err
+		}
+		return deleteBook(tx, &book, cfg.DeletePolicy, actor(c))
+	})
+	var dependents *dependentsError
+	if errors.As(err, &dependents) {
+		respondDependents(c, "book", dependents)
+		return
+	}
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(c, "book", "")
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to delete book",
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/citation"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// citationFormat returns the bibliographic format requested with ?format= or, failing
+// that, the Accept header. An empty format means the regular JSON response.
+func citationFormat(c *gin.Context) (citation.Format, error) {
+	c.Header("Vary", "Accept")
+	if name := c.Query("format"); name != "" {
+		if name == "json" {
+			return "", nil
+		}
+		format, ok := citation.ParseFormat(name)
+		if !ok {
+			return "", fmt.Errorf("unknown format %q, use json, bibtex, ris, csl-json or marcxml", name)
+		}
+		return format, nil
+	}
+	format, _ := citation.Negotiate(c.GetHeader("Accept"))
+	return format, nil
+}
+
+// respondCitations renders books in a bibliographic format. The output is buffered so
+// a rendering error can still be reported with a proper status.
+func respondCitations(c *gin.Context, format citation.Format, books []models.Book) {
+	var body bytes.Buffer
+	if err := citation.Render(&body, format, books); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to render books",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, format.MediaType()+"; charset=utf-8", body.Bytes())
+}
+
+func respondInvalidFormat(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid format",
+		Details: err.Error(),
+	})
+}
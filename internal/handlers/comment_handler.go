@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetReviewComments godoc
+// @Summary Get the comments on a review
+// @Description Get a page of the comment threads on a published review, oldest first, each comment with its
+// @Description replies nested under it. Deleted comments that still have replies are kept in place without
+// @Description their text. Paging counts top-level comments.
+// @Tags comments
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Threads per page (default 20, max 100)"
+// @Success 200 {array} dto.CommentResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reviews/{id}/comments [get]
+func GetReviewComments(c *gin.Context) {
+	var review models.Review
+	if err := publishedReviews(db).Select("reviews.id").First(&review, c.Param("id")).Error; err != nil {
+		respondReviewNotFound(c)
+		return
+	}
+
+	roots := db.Unscoped().Model(&models.Comment{}).
+		Where("review_id = ? AND parent_id IS NULL", review.ID).
+		Where("deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments r WHERE r.root_id = comments.id AND r.deleted_at IS NULL)")
+	paged, err := paginate(c, roots, &models.Comment{})
+	if err != nil {
+		respondCommentError(c, "Failed to fetch comments", err)
+		return
+	}
+	var rootIDs []uint
+	if err := paged.Order("created_at, id").Pluck("id", &rootIDs).Error; err != nil {
+		respondCommentError(c, "Failed to fetch comments", err)
+		return
+	}
+
+	var comments []models.Comment
+	err = db.Unscoped().Preload("User").
+		Where("id IN ? OR root_id IN ?", rootIDs, rootIDs).
+		Order("created_at, id").
+		Find(&comments).Error
+	if err != nil {
+		respondCommentError(c, "Failed to fetch comments", err)
+		return
+	}
+	c.JSON(http.StatusOK, commentThreads(comments, rootIDs))
+}
+
+// CreateComment godoc
+// @Summary Comment on a review
+// @Description Comment on a published review as the signed in user, or reply to one of its comments by giving
+// @Description parent_id.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param comment body dto.CreateCommentRequest true "Comment"
+// @Success 201 {object} dto.CommentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "The parent comment is not on this review or was deleted"
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reviews/{id}/comments [post]
+func CreateComment(c *gin.Context) {
+	var req dto.CreateCommentRequest
+	if !bindComment(c, &req, &req.Body) {
+		return
+	}
+	userID := currentUserID(c)
+	if userID == 0 {
+		respondTokenWithoutUser(c)
+		return
+	}
+
+	var review models.Review
+	if err := publishedReviews(db).Select("reviews.id").First(&review, c.Param("id")).Error; err != nil {
+		respondReviewNotFound(c)
+		return
+	}
+
+	comment := models.Comment{ReviewID: review.ID, UserID: &userID, Body: req.Body}
+	if req.ParentID != nil {
+		var parent models.Comment
+		err := db.Where("review_id = ?", review.ID).First(&parent, *req.ParentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Cannot reply to this comment",
+				Details: "The comment to reply to is not on this review or was deleted",
+			})
+			return
+		}
+		if err != nil {
+			respondCommentError(c, "Failed to add the comment", err)
+			return
+		}
+		comment.ParentID = &parent.ID
+		comment.RootID = parent.RootID
+		if comment.RootID == nil {
+			comment.RootID = &parent.ID
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Review", "Parent", "User").Create(&comment).Error; err != nil {
+			return err
+		}
+		return countComments(tx, review.ID)
+	})
+	if err != nil {
+		respondCommentError(c, "Failed to add the comment", err)
+		return
+	}
+
+	comment.User = &models.User{Model: gorm.Model{ID: userID}, Username: c.GetString("username")}
+	c.JSON(http.StatusCreated, toCommentResponse(comment))
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Change the text of a comment. Only its author or a moderator can edit it.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Param comment body dto.UpdateCommentRequest true "New text"
+// @Success 200 {object} dto.CommentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/comments/{id} [put]
+func UpdateComment(c *gin.Context) {
+	var req dto.UpdateCommentRequest
+	if !bindComment(c, &req, &req.Body) {
+		return
+	}
+	comment, ok := editableComment(c)
+	if !ok {
+		return
+	}
+
+	if err := db.Model(&comment).Update("body", req.Body).Error; err != nil {
+		respondCommentError(c, "Failed to edit the comment", err)
+		return
+	}
+	c.JSON(http.StatusOK, toCommentResponse(comment))
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete a comment. Its replies stay, and the comment keeps its place in the thread without its
+// @Description text. Only its author or a moderator can delete it.
+// @Tags comments
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/comments/{id} [delete]
+func DeleteComment(c *gin.Context) {
+	comment, ok := editableComment(c)
+	if !ok {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.Comment{}, comment.ID).Error; err != nil {
+			return err
+		}
+		return countComments(tx, comment.ReviewID)
+	})
+	if err != nil {
+		respondCommentError(c, "Failed to delete the comment", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// bindComment binds a comment request and checks that its body is not blank.
+func bindComment(c *gin.Context, req interface{}, body *string) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		if *body = strings.TrimSpace(*body); *body == "" {
+			err = errors.New("body must not be blank")
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// editableComment loads the comment in the path, answering 404 or 403 itself when it
+// does not exist or the user may not change it.
+func editableComment(c *gin.Context) (models.Comment, bool) {
+	var comment models.Comment
+	if err := db.Preload("User").First(&comment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Comment not found",
+			Details: "The comment with the given ID does not exist",
+		})
+		return comment, false
+	}
+	if !ownsOrModerates(c, comment.UserID) {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "Not your comment",
+			Details: "Only the author of a comment or a moderator can change it",
+		})
+		return comment, false
+	}
+	return comment, true
+}
+
+// countComments stores the number of live comments on a review. Like the vote counts
+// it leaves updated_at alone and is part of reviewCounters instead.
+func countComments(tx *gorm.DB, reviewID uint) error {
+	return tx.Model(&models.Review{}).Where("id = ?", reviewID).UpdateColumn("comment_count",
+		gorm.Expr("(SELECT COUNT(*) FROM comments WHERE review_id = ? AND deleted_at IS NULL)", reviewID)).Error
+}
+
+// commentThreads nests the comments of the given threads under their parents, in the
+// order of rootIDs. Deleted comments are dropped unless a live reply hangs below them.
+func commentThreads(comments []models.Comment, rootIDs []uint) []dto.CommentResponse {
+	byID := map[uint]models.Comment{}
+	children := map[uint][]uint{}
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+		if comment.ParentID != nil {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment.ID)
+		}
+	}
+
+	var build func(id uint) (dto.CommentResponse, bool)
+	build = func(id uint) (dto.CommentResponse, bool) {
+		response := toCommentResponse(byID[id])
+		for _, childID := range children[id] {
+			if reply, ok := build(childID); ok {
+				response.Replies = append(response.Replies, reply)
+			}
+		}
+		return response, !response.Deleted || len(response.Replies) > 0
+	}
+
+	threads := make([]dto.CommentResponse, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		if thread, ok := build(id); ok {
+			threads = append(threads, thread)
+		}
+	}
+	return threads
+}
+
+func toCommentResponse(comment models.Comment) dto.CommentResponse {
+	response := dto.CommentResponse{
+		ID:        comment.ID,
+		ReviewID:  comment.ReviewID,
+		ParentID:  comment.ParentID,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		Replies:   []dto.CommentResponse{},
+	}
+	if comment.DeletedAt.Valid {
+		response.Deleted = true
+		return response
+	}
+	response.UserID = comment.UserID
+	response.Body = comment.Body
+	if comment.User != nil {
+		response.Author = comment.User.Username
+	}
+	return response
+}
+
+func respondCommentError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+		Details: err.Error(),
+	})
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var errDuplicateContributor = errors.New("the same author is listed twice with the same role")
+
+// withContributors preloads the credited authors of a book in their credit order.
+func withContributors(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Contributors.Author")
+}
+
+// buildContributors turns the requested contributor list into join rows. When one of the
+// authors does not exist its ID is returned as missing.
+func buildContributors(tx *gorm.DB, reqs []dto.ContributorRequest) (contributors []models.BookContributor, missing uint, err error) {
+	ids := make([]uint, 0, len(reqs))
+	for _, req := range reqs {
+		ids = append(ids, req.AuthorID)
+	}
+
+	var found []uint
+	if err := tx.Model(&models.Author{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+		return nil, 0, err
+	}
+	known := make(map[uint]bool, len(found))
+	for _, id := range found {
+		known[id] = true
+	}
+
+	type credit struct {
+		authorID uint
+		role     models.ContributorRole
+	}
+	seen := make(map[credit]bool, len(reqs))
+	for i, req := range reqs {
+		if !known[req.AuthorID] {
+			return nil, req.AuthorID, nil
+		}
+
+		role := models.ContributorRole(req.Role)
+		if role == "" {
+			role = models.ContributorAuthor
+		}
+
+		key := credit{authorID: req.AuthorID, role: role}
+		if seen[key] {
+			return nil, 0, errDuplicateContributor
+		}
+		seen[key] = true
+
+		contributors = append(contributors, models.BookContributor{
+			AuthorID: req.AuthorID,
+			Role:     role,
+			Position: i,
+		})
+	}
+	return contributors, 0, nil
+}
+
+// replaceContributors swaps the contributor list of a book for a new one.
+func replaceContributors(tx *gorm.DB, bookID uint, contributors []models.BookContributor) error {
+	if err := tx.Where("book_id = ?", bookID).Delete(&models.BookContributor{}).Error; err != nil {
+		return err
+	}
+	if len(contributors) == 0 {
+		return nil
+	}
+	for i := range contributors {
+		contributors[i].BookID = bookID
+	}
+	return tx.Omit("Author").Create(&contributors).Error
+}
+
+func toContributorResponses(contributors []models.BookContributor) []dto.ContributorResponse {
+	response := make([]dto.ContributorResponse, 0, len(contributors))
+	for _, contributor := range contributors {
+		// Soft deleted authors are not preloaded and stay hidden from the credits.
+		if contributor.Author.ID == 0 {
+			continue
+		}
+		response = append(response, dto.ContributorResponse{
+			AuthorID: contributor.AuthorID,
+			Name:     contributor.Author.Name,
+			Role:     string(contributor.Role),
+			Order:    contributor.Position,
+		})
+	}
+	return response
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/imaging"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCoverPixels guards against decompression bombs: small files that decode to huge images.
+const maxCoverPixels = 40_000_000
+
+// coverSizes are the thumbnail widths generated for every uploaded cover.
+var coverSizes = []struct {
+	Name  string
+	Width int
+}{
+	{"small", 96},
+	{"medium", 240},
+	{"large", 480},
+}
+
+// coverFormats maps the sniffed content types we accept to file extensions.
+var coverFormats = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// UploadBookCover godoc
+// @Summary Upload a book cover
+// @Description Upload a JPEG, PNG or GIF cover as the multipart field "cover". The type is detected from the
+// @Description content, not the file name. Thumbnails are generated in small, medium and large sizes.
+// @Tags books
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param cover formData file true "Cover image"
+// @Success 200 {object} dto.CoverResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/books/{id}/cover [put]
+func UploadBookCover(c *gin.Context) {
+	var book models.Book
+	if err := db.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return
+	}
+
+	// Leave some room for the multipart framing around the file itself.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.CoverMaxBytes+64<<10)
+	header, err := c.FormFile("cover")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondCoverTooLarge(c)
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: "Send the image as the multipart form field \"cover\"",
+		})
+		return
+	}
+	if header.Size > cfg.CoverMaxBytes {
+		respondCoverTooLarge(c)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		respondCoverError(c, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, cfg.CoverMaxBytes+1))
+	if err != nil {
+		respondCoverError(c, err)
+		return
+	}
+	if int64(len(data)) > cfg.CoverMaxBytes {
+		respondCoverTooLarge(c)
+		return
+	}
+
+	cover, err := replaceCover(c.Request.Context(), &book, data)
+	var unsupported unsupportedCoverError
+	var invalid invalidCoverError
+	switch {
+	case errors.As(err, &unsupported):
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Unsupported image type",
+			Details: "Covers must be JPEG, PNG or GIF images, got " + string(unsupported),
+		})
+		return
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Invalid image",
+			Details: invalid.Error(),
+		})
+		return
+	case err != nil:
+		respondCoverError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cover)
+}
+
+// unsupportedCoverError carries the sniffed content type of an image we do not accept.
+type unsupportedCoverError string
+
+func (e unsupportedCoverError) Error() string {
+	return "unsupported cover type " + string(e)
+}
+
+// invalidCoverError reports an image that cannot be decoded or is too large.
+type invalidCoverError struct{ err error }
+
+func (e invalidCoverError) Error() string {
+	return e.err.Error()
+}
+
+// replaceCover validates an image, stores it with its thumbnails as the cover of book
+// and removes the previous cover.
+func replaceCover(ctx context.Context, book *models.Book, data []byte) (*dto.CoverResponse, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := coverFormats[contentType]
+	if !ok {
+		return nil, unsupportedCoverError(contentType)
+	}
+
+	dims, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == nil && dims.Width*dims.Height > maxCoverPixels {
+		err = fmt.Errorf("image is %dx%d pixels, which is too large", dims.Width, dims.Height)
+	}
+	var img image.Image
+	if err == nil {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, invalidCoverError{err}
+	}
+
+	// Every upload gets a fresh prefix so cached URLs of the old cover never serve the new one.
+	prefix := fmt.Sprintf("covers/%d/%d", book.ID, time.Now().UnixNano())
+	key, written, err := storeCover(ctx, prefix, ext, contentType, data, img)
+	if err == nil {
+		err = db.Model(book).Update("cover_key", key).Error
+	}
+	if err != nil {
+		for _, key := range written {
+			blobs.Delete(ctx, key)
+		}
+		return nil, err
+	}
+
+	deleteCoverBlobs(ctx, book.CoverKey)
+	book.CoverKey = key
+	forgetBooks([]uint{book.ID})
+	return toCoverResponse(key), nil
+}
+
+// DeleteBookCover godoc
+// @Summary Remove a book cover
+// @Description Remove the cover and its thumbnails from a book. Needs an admin token.
+// @Tags books
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/books/{id}/cover [delete]
+func DeleteBookCover(c *gin.Context) {
+	var book models.Book
+	if err := db.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return
+	}
+	if book.CoverKey == "" {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Cover not found",
+			Details: "The book has no cover",
+		})
+		return
+	}
+
+	if err := db.Model(&book).Update("cover_key", "").Error; err != nil {
+		respondCoverError(c, err)
+		return
+	}
+	deleteCoverBlobs(c.Request.Context(), book.CoverKey)
+	forgetBooks([]uint{book.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cover removed"})
+}
+
+// storeCover writes the original image and its thumbnails below prefix and returns the
+// key of the original together with every key written so far.
+func storeCover(ctx context.Context, prefix, ext, contentType string, data []byte, img image.Image) (string, []string, error) {
+	key := prefix + "/original." + ext
+	if err := blobs.Put(ctx, key, contentType, data); err != nil {
+		return "", nil, err
+	}
+	written := []string{key}
+
+	for _, size := range coverSizes {
+		var thumbnail bytes.Buffer
+		if err := jpeg.Encode(&thumbnail, imaging.Flatten(imaging.FitWidth(img, size.Width)), &jpeg.Options{Quality: 85}); err != nil {
+			return "", written, err
+		}
+		thumbnailKey := prefix + "/" + size.Name + ".jpg"
+		if err := blobs.Put(ctx, thumbnailKey, "image/jpeg", thumbnail.Bytes()); err != nil {
+			return "", written, err
+		}
+		written = append(written, thumbnailKey)
+	}
+	return key, written, nil
+}
+
+// deleteCoverBlobs removes a cover and its thumbnails. Failures only leave unreferenced
+// files behind, so they are not reported to the client.
+func deleteCoverBlobs(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	blobs.Delete(ctx, key)
+	for _, size := range coverSizes {
+		blobs.Delete(ctx, path.Dir(key)+"/"+size.Name+".jpg")
+	}
+}
+
+func toCoverResponse(key string) *dto.CoverResponse {
+	if key == "" || blobs == nil {
+		return nil
+	}
+	thumbnails := make(map[string]string, len(coverSizes))
+	for _, size := range coverSizes {
+		thumbnails[size.Name] = blobs.URL(path.Dir(key) + "/" + size.Name + ".jpg")
+	}
+	return &dto.CoverResponse{URL: blobs.URL(key), Thumbnails: thumbnails}
+}
+
+func respondCoverTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: "Cover too large",
+		Details: fmt.Sprintf("Covers may be at most %d bytes", cfg.CoverMaxBytes),
+	})
+}
+
+func respondCoverError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to store cover",
+		Details: err.Error(),
+	})
+}
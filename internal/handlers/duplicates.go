@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+)
+
+const (
+	defaultDuplicateThreshold = 0.6
+	// minDuplicateThreshold is the default pg_trgm.similarity_threshold; the % operator
+	// that uses the trigram index never matches below it.
+	minDuplicateThreshold = 0.3
+)
+
+// trigramAvailable reports whether the pg_trgm extension is installed.
+func trigramAvailable() bool {
+	var installed bool
+	db.Raw(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&installed)
+	return installed
+}
+
+// duplicateThreshold reads the threshold query parameter, clamped to what the trigram
+// index can answer.
+func duplicateThreshold(value string) float64 {
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultDuplicateThreshold
+	}
+	if threshold < minDuplicateThreshold {
+		return minDuplicateThreshold
+	}
+	if threshold > 1 {
+		return 1
+	}
+	return threshold
+}
+
+// duplicateSet collects candidate pairs, merging the reasons found for the same pair.
+type duplicateSet struct {
+	pairs map[[2]uint]*dto.DuplicateCandidate
+}
+
+func newDuplicateSet() *duplicateSet {
+	return &duplicateSet{pairs: map[[2]uint]*dto.DuplicateCandidate{}}
+}
+
+func (s *duplicateSet) add(candidate dto.DuplicateCandidate, reason string) {
+	if candidate.LeftID > candidate.RightID {
+		candidate.LeftID, candidate.RightID = candidate.RightID, candidate.LeftID
+		candidate.LeftName, candidate.RightName = candidate.RightName, candidate.LeftName
+	}
+	key := [2]uint{candidate.LeftID, candidate.RightID}
+	existing, ok := s.pairs[key]
+	if !ok {
+		candidate.Reasons = []string{reason}
+		s.pairs[key] = &candidate
+		return
+	}
+	if candidate.Similarity > existing.Similarity {
+		existing.Similarity = candidate.Similarity
+	}
+	for _, r := range existing.Reasons {
+		if r == reason {
+			return
+		}
+	}
+	existing.Reasons = append(existing.Reasons, reason)
+}
+
+// list returns the most similar pairs first.
+func (s *duplicateSet) list(limit int) []dto.DuplicateCandidate {
+	candidates := make([]dto.DuplicateCandidate, 0, len(s.pairs))
+	for _, candidate := range s.pairs {
+		candidates = append(candidates, *candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Similarity != candidates[j].Similarity {
+			return candidates[i].Similarity > candidates[j].Similarity
+		}
+		if len(candidates[i].Reasons) != len(candidates[j].Reasons) {
+			return len(candidates[i].Reasons) > len(candidates[j].Reasons)
+		}
+		return candidates[i].LeftID < candidates[j].LeftID
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+type namedRow struct {
+	ID   uint
+	Name string
+}
+
+// pairEqualKeys adds every pair of rows whose duplicateKey matches. accept, when set,
+// filters the pairs.
+func pairEqualKeys(set *duplicateSet, rows []namedRow, reason string, accept func(a, b uint) bool) {
+	groups := map[string][]namedRow{}
+	for _, row := range rows {
+		if key := models.SearchKey(row.Name); key != "" {
+			groups[key] = append(groups[key], row)
+		}
+	}
+	for _, group := range groups {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				if accept != nil && !accept(group[i].ID, group[j].ID) {
+					continue
+				}
+				set.add(dto.DuplicateCandidate{
+					LeftID:     group[i].ID,
+					LeftName:   group[i].Name,
+					RightID:    group[j].ID,
+					RightName:  group[j].Name,
+					Similarity: 1,
+				}, reason)
+			}
+		}
+	}
+}
+
+// findDuplicateAuthors pairs live authors with the same normalized name and, when
+// pg_trgm is installed, names at least threshold similar.
+func findDuplicateAuthors(threshold float64, limit int) ([]dto.DuplicateCandidate, error) {
+	set := newDuplicateSet()
+
+	var authors []namedRow
+	if err := db.Model(&models.Author{}).Select("id, name").Scan(&authors).Error; err != nil {
+		return nil, err
+	}
+	pairEqualKeys(set, authors, "same_normalized_name", nil)
+
+	if trigramAvailable() {
+		var similar []dto.DuplicateCandidate
+		err := db.Raw(`
+			SELECT a.id AS left_id, a.name AS left_name, b.id AS right_id, b.name AS right_name,
+				similarity(a.name, b.name) AS similarity
+			FROM authors a
+			JOIN authors b ON a.id < b.id AND a.name % b.name
+			WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL AND similarity(a.name, b.name) >= ?
+			ORDER BY similarity DESC
+			LIMIT ?`, threshold, limit).Scan(&similar).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range similar {
+			set.add(candidate, "similar_name")
+		}
+	}
+	return set.list(limit), nil
+}
+
+// findDuplicateBooks pairs live books with the same ISBN, and books sharing an author
+// whose titles are equal after normalization or, with pg_trgm, at least threshold
+// similar. Title matches with two different ISBNs are separate editions, not duplicates.
+func findDuplicateBooks(threshold float64, limit int) ([]dto.DuplicateCandidate, error) {
+	set := newDuplicateSet()
+
+	var sameISBN []dto.DuplicateCandidate
+	err := db.Raw(`
+		SELECT a.id AS left_id, a.title AS left_name, b.id AS right_id, b.title AS right_name, 1 AS similarity
+		FROM books a
+		JOIN books b ON a.id < b.id
+			AND REPLACE(REPLACE(UPPER(a.isbn), '-', ''), ' ', '') = REPLACE(REPLACE(UPPER(b.isbn), '-', ''), ' ', '')
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL AND a.isbn <> ''
+		LIMIT ?`, limit).Scan(&sameISBN).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range sameISBN {
+		set.add(candidate, "same_isbn")
+	}
+
+	var books []struct {
+		ID    uint
+		Title string
+		ISBN  string
+	}
+	if err := db.Model(&models.Book{}).Select("id, title, isbn").Scan(&books).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]namedRow, len(books))
+	isbns := map[uint]string{}
+	for i, book := range books {
+		rows[i] = namedRow{ID: book.ID, Name: book.Title}
+		isbns[book.ID] = models.SearchKey(book.ISBN)
+	}
+
+	var contributors []models.BookContributor
+	if err := db.Select("book_id, author_id").Find(&contributors).Error; err != nil {
+		return nil, err
+	}
+	authorsByBook := map[uint]map[uint]bool{}
+	for _, contributor := range contributors {
+		if authorsByBook[contributor.BookID] == nil {
+			authorsByBook[contributor.BookID] = map[uint]bool{}
+		}
+		authorsByBook[contributor.BookID][contributor.AuthorID] = true
+	}
+	pairEqualKeys(set, rows, "same_title_and_author", func(a, b uint) bool {
+		if isbns[a] != "" && isbns[b] != "" && isbns[a] != isbns[b] {
+			return false
+		}
+		for authorID := range authorsByBook[a] {
+			if authorsByBook[b][authorID] {
+				return true
+			}
+		}
+		return false
+	})
+
+	if trigramAvailable() {
+		var similar []dto.DuplicateCandidate
+		err := db.Raw(`
+			SELECT a.id AS left_id, a.title AS left_name, b.id AS right_id, b.title AS right_name,
+				similarity(a.title, b.title) AS similarity
+			FROM books a
+			JOIN books b ON a.id < b.id AND a.title % b.title
+			WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL AND similarity(a.title, b.title) >= ?
+				AND (a.isbn = '' OR b.isbn = ''
+					OR REPLACE(REPLACE(UPPER(a.isbn), '-', ''), ' ', '') = REPLACE(REPLACE(UPPER(b.isbn), '-', ''), ' ', ''))
+				AND EXISTS (
+					SELECT 1 FROM book_contributors x
+					JOIN book_contributors y ON y.author_id = x.author_id
+					WHERE x.book_id = a.id AND y.book_id = b.id)
+			ORDER BY similarity DESC
+			LIMIT ?`, threshold, limit).Scan(&similar).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range similar {
+			set.add(candidate, "similar_title")
+		}
+	}
+	return set.list(limit), nil
+}
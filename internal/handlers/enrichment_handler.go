@@ -0,0 +1,586 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/metadata"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// enrichmentDelay spaces out the lookups of a job to stay polite to public APIs.
+const enrichmentDelay = 500 * time.Millisecond
+
+// Finished jobs can be polled for enrichmentJobTTL, and at most
+// maxFinishedEnrichmentJobs of them are kept; the oldest go first.
+const (
+	enrichmentJobTTL          = time.Hour
+	maxFinishedEnrichmentJobs = 100
+)
+
+var provider metadata.MetadataProvider
+
+// InitMetadata sets the provider used to enrich books; nil turns enrichment off.
+func InitMetadata(p metadata.MetadataProvider) {
+	provider = p
+}
+
+// enrichmentJobs keeps the batch jobs of this process. They are not persisted, so a
+// restart forgets them.
+var enrichmentJobs = struct {
+	sync.Mutex
+	byID map[string]*dto.EnrichmentJobResponse
+}{byID: make(map[string]*dto.EnrichmentJobResponse)}
+
+// Jobs run under enrichmentCtx, which StopEnrichmentJobs cancels on shutdown.
+var (
+	enrichmentCtx, stopEnrichment = context.WithCancel(context.Background())
+	enrichmentRunning             sync.WaitGroup
+)
+
+// StopEnrichmentJobs cancels the running enrichment jobs and waits until they have
+// stopped or ctx is done. A job stops before its next book; lookups in flight are
+// aborted and books already enriched keep their changes.
+func StopEnrichmentJobs(ctx context.Context) {
+	stopEnrichment()
+	done := make(chan struct{})
+	go func() {
+		enrichmentRunning.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// GetBookEnrichment godoc
+// @Summary Preview metadata enrichment for a book
+// @Description Look the book up by ISBN in the configured metadata provider and list the fields that differ
+// @Tags enrichment
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} dto.EnrichmentProposal
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/admin/books/{id}/enrichment [get]
+func GetBookEnrichment(c *gin.Context) {
+	book, ok := loadEnrichmentBook(c)
+	if !ok {
+		return
+	}
+	proposal, _, err := proposeEnrichment(c.Request.Context(), book)
+	if err != nil {
+		respondEnrichmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proposal)
+}
+
+// ApplyBookEnrichment godoc
+// @Summary Apply metadata enrichment to a book
+// @Description Apply the proposed changes. Without fields only changes that fill empty fields are applied;
+// @Description listed fields are applied even when they overwrite existing values.
+// @Tags enrichment
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param request body dto.ApplyEnrichmentRequest false "Fields to apply"
+// @Success 200 {object} dto.EnrichmentProposal
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/admin/books/{id}/enrichment [post]
+func ApplyBookEnrichment(c *gin.Context) {
+	var req dto.ApplyEnrichmentRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid input data",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	book, ok := loadEnrichmentBook(c)
+	if !ok {
+		return
+	}
+	proposal, err := enrichBook(c.Request.Context(), book, req.Fields, true, actor(c))
+	if err != nil {
+		respondEnrichmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proposal)
+}
+
+// StartEnrichmentJob godoc
+// @Summary Start a batch enrichment job
+// @Description Propose or apply enrichment for many books in the background. Without book IDs the job picks
+// @Description books with an ISBN that lack a description, page count, cover or authors. Poll the returned job.
+// @Tags enrichment
+// @Accept json
+// @Produce json
+// @Param request body dto.EnrichmentJobRequest true "Job"
+// @Success 202 {object} dto.EnrichmentJobResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/admin/enrichment/jobs [post]
+func StartEnrichmentJob(c *gin.Context) {
+	if provider == nil {
+		respondEnrichmentError(c, errEnrichmentDisabled)
+		return
+	}
+	var req dto.EnrichmentJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+
+	query := db.Model(&models.Book{}).Where("isbn <> ''")
+	if len(req.BookIDs) > 0 {
+		query = query.Where("id IN ?", req.BookIDs)
+	} else {
+		query = query.Where("description = '' OR page_count = 0 OR cover_key = '' OR " +
+			"NOT EXISTS (SELECT 1 FROM book_contributors bc WHERE bc.book_id = books.id AND bc.role = 'author')")
+	}
+	var ids []uint
+	if err := query.Order("id").Limit(req.Limit).Pluck("id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start enrichment",
+			Details: err.Error(),
+		})
+		return
+	}
+	jobID, err := randomID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start enrichment",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	job := &dto.EnrichmentJobResponse{
+		ID:        jobID,
+		Status:    "running",
+		Apply:     req.Apply,
+		Total:     len(ids),
+		StartedAt: time.Now(),
+		Results:   []dto.EnrichmentProposal{},
+	}
+	enrichmentJobs.Lock()
+	pruneEnrichmentJobs(time.Now())
+	enrichmentJobs.byID[job.ID] = job
+	snapshot := *job
+	enrichmentJobs.Unlock()
+
+	changedBy := actor(c)
+	enrichmentRunning.Add(1)
+	go func() {
+		defer enrichmentRunning.Done()
+		runEnrichmentJob(enrichmentCtx, job, ids, func(ctx context.Context, id uint) dto.EnrichmentProposal {
+			return enrichJobBook(ctx, id, req.Fields, req.Apply, changedBy)
+		})
+	}()
+
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+// GetEnrichmentJob godoc
+// @Summary Get a batch enrichment job
+// @Description Get the progress and the per-book results of an enrichment job. Finished jobs are kept for an hour.
+// @Tags enrichment
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} dto.EnrichmentJobResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/admin/enrichment/jobs/{id} [get]
+func GetEnrichmentJob(c *gin.Context) {
+	enrichmentJobs.Lock()
+	pruneEnrichmentJobs(time.Now())
+	job, ok := enrichmentJobs.byID[c.Param("id")]
+	var snapshot dto.EnrichmentJobResponse
+	if ok {
+		snapshot = *job
+		snapshot.Results = append([]dto.EnrichmentProposal(nil), job.Results...)
+	}
+	enrichmentJobs.Unlock()
+
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Job not found",
+			Details: "The enrichment job with the given ID does not exist",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// runEnrichmentJob hands the books of a job to enrich one by one, recording the
+// results. It ends the job as cancelled when ctx is done before the last book.
+func runEnrichmentJob(ctx context.Context, job *dto.EnrichmentJobResponse, ids []uint, enrich func(context.Context, uint) dto.EnrichmentProposal) {
+	status := "done"
+	for i, id := range ids {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(enrichmentDelay):
+			}
+		}
+		if ctx.Err() != nil {
+			status = "cancelled"
+			break
+		}
+
+		proposal := enrich(ctx, id)
+		enrichmentJobs.Lock()
+		job.Results = append(job.Results, proposal)
+		job.Processed++
+		enrichmentJobs.Unlock()
+	}
+
+	enrichmentJobs.Lock()
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	pruneEnrichmentJobs(now)
+	enrichmentJobs.Unlock()
+}
+
+// enrichJobBook enriches one book of a job, reporting a failure in the proposal.
+func enrichJobBook(ctx context.Context, id uint, fields []string, apply bool, changedBy string) dto.EnrichmentProposal {
+	var proposal dto.EnrichmentProposal
+	var book models.Book
+	err := withContributors(db).Preload("Publisher").First(&book, id).Error
+	if err == nil {
+		var result *dto.EnrichmentProposal
+		result, err = enrichBook(ctx, book, fields, apply, changedBy)
+		if result != nil {
+			proposal = *result
+		}
+	}
+	if err != nil {
+		proposal.BookID = id
+		proposal.Error = err.Error()
+	}
+	// Keep job results small; the book itself can be fetched when needed.
+	proposal.Book = nil
+	return proposal
+}
+
+// pruneEnrichmentJobs forgets finished jobs older than enrichmentJobTTL and, past
+// maxFinishedEnrichmentJobs, the oldest finished ones. Running jobs are kept. Call it
+// with enrichmentJobs locked.
+func pruneEnrichmentJobs(now time.Time) {
+	var finished []*dto.EnrichmentJobResponse
+	for id, job := range enrichmentJobs.byID {
+		if job.FinishedAt == nil {
+			continue
+		}
+		if now.Sub(*job.FinishedAt) > enrichmentJobTTL {
+			delete(enrichmentJobs.byID, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+	if len(finished) <= maxFinishedEnrichmentJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedEnrichmentJobs] {
+		delete(enrichmentJobs.byID, job.ID)
+	}
+}
+
+var (
+	errEnrichmentDisabled = errors.New("metadata enrichment is not configured")
+	errNoISBN             = errors.New("the book has no ISBN to look up")
+)
+
+// enrichBook proposes changes for a book and, when apply is set, applies the selected
+// ones on behalf of changedBy. The proposal is returned even when applying fails.
+func enrichBook(ctx context.Context, book models.Book, fields []string, apply bool, changedBy string) (*dto.EnrichmentProposal, error) {
+	proposal, record, err := proposeEnrichment(ctx, book)
+	if err != nil || !apply {
+		return proposal, err
+	}
+
+	selected := make(map[string]bool)
+	for _, change := range proposal.Changes {
+		if len(fields) == 0 && change.FillsEmpty {
+			selected[change.Field] = true
+		}
+	}
+	for _, field := range fields {
+		selected[field] = true
+	}
+
+	applied, err := applyEnrichment(ctx, &book, record, proposal.Changes, selected, changedBy)
+	proposal.Applied = applied
+	if err != nil {
+		if len(applied) == 0 {
+			return proposal, err
+		}
+		// Only the cover failed; report it next to the fields that were written.
+		proposal.Error = err.Error()
+	}
+
+	var updated models.Book
+	if err := withBookDetails(db).First(&updated, book.ID).Error; err == nil {
+		response := toBookResponse(updated)
+		proposal.Book = &response
+	}
+	return proposal, nil
+}
+
+// proposeEnrichment looks the book up and lists the fields the provider knows
+// differently. The book needs its contributors and publisher loaded.
+func proposeEnrichment(ctx context.Context, book models.Book) (*dto.EnrichmentProposal, *metadata.Record, error) {
+	if provider == nil {
+		return nil, nil, errEnrichmentDisabled
+	}
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(book.ISBN))
+	if isbn == "" {
+		return nil, nil, errNoISBN
+	}
+	record, err := provider.Lookup(ctx, isbn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proposal := &dto.EnrichmentProposal{
+		BookID:   book.ID,
+		ISBN:     book.ISBN,
+		Provider: provider.Name(),
+		Changes:  []dto.FieldChange{},
+	}
+	text := func(field, current, proposed string) {
+		if proposed != "" && !strings.EqualFold(strings.TrimSpace(current), strings.TrimSpace(proposed)) {
+			proposal.Changes = append(proposal.Changes, dto.FieldChange{
+				Field: field, Current: current, Proposed: proposed, FillsEmpty: current == "",
+			})
+		}
+	}
+	number := func(field string, current, proposed int) {
+		if proposed != 0 && current != proposed {
+			proposal.Changes = append(proposal.Changes, dto.FieldChange{
+				Field: field, Current: current, Proposed: proposed, FillsEmpty: current == 0,
+			})
+		}
+	}
+
+	text("title", book.Title, record.Title)
+	authors := bookAuthorNames(book)
+	if len(record.Authors) > 0 && !strings.EqualFold(strings.Join(authors, "|"), strings.Join(record.Authors, "|")) {
+		proposal.Changes = append(proposal.Changes, dto.FieldChange{
+			Field: "authors", Current: authors, Proposed: record.Authors, FillsEmpty: len(authors) == 0,
+		})
+	}
+	text("description", book.Description, record.Description)
+	number("page_count", book.PageCount, record.PageCount)
+	number("publication_year", book.PublicationYear, record.PublicationYear)
+	var publisher string
+	if book.Publisher != nil {
+		publisher = book.Publisher.Name
+	}
+	text("publisher", publisher, record.Publisher)
+	if record.CoverURL != "" {
+		var current string
+		if cover := toCoverResponse(book.CoverKey); cover != nil {
+			current = cover.URL
+		}
+		proposal.Changes = append(proposal.Changes, dto.FieldChange{
+			Field: "cover", Current: current, Proposed: record.CoverURL, FillsEmpty: book.CoverKey == "",
+		})
+	}
+	return proposal, record, nil
+}
+
+// applyEnrichment writes the selected changes. Database fields change in one
+// transaction; the cover is downloaded and stored afterwards.
+func applyEnrichment(ctx context.Context, book *models.Book, record *metadata.Record, changes []dto.FieldChange, selected map[string]bool, changedBy string) ([]string, error) {
+	var applied []string
+	var cover bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{}
+		for _, change := range changes {
+			if !selected[change.Field] {
+				continue
+			}
+			switch change.Field {
+			case "title":
+				updates["title"] = record.Title
+			case "description":
+				updates["description"] = record.Description
+			case "page_count":
+				updates["page_count"] = record.PageCount
+			case "publication_year":
+				updates["publication_year"] = record.PublicationYear
+			case "publisher":
+				publisherID, err := findOrCreatePublisher(tx, record.Publisher)
+				if err != nil {
+					return err
+				}
+				// The old imprint belongs to the old publisher.
+				updates["publisher_id"] = publisherID
+				updates["imprint_id"] = nil
+			case "authors":
+				if err := replaceAuthors(tx, *book, record.Authors); err != nil {
+					return err
+				}
+			case "cover":
+				cover = true
+				continue
+			}
+			applied = append(applied, change.Field)
+		}
+		if len(applied) == 0 {
+			return nil
+		}
+		// New authors alone still make a new version of the book.
+		updates["updated_at"] = time.Now()
+		if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, historyBook, book.ID, models.RevisionUpdate, changedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	forgetBooks([]uint{book.ID})
+
+	if cover {
+		data, err := provider.FetchCover(ctx, record)
+		if err != nil {
+			return applied, err
+		}
+		if _, err := replaceCover(ctx, book, data); err != nil {
+			return applied, err
+		}
+		applied = append(applied, "cover")
+	}
+	return applied, nil
+}
+
+// replaceAuthors credits the named authors, creating missing ones, in place of the
+// current authors of the book. Editors, translators and illustrators are kept after them.
+func replaceAuthors(tx *gorm.DB, book models.Book, names []string) error {
+	var contributors []models.BookContributor
+	seen := make(map[uint]bool)
+	for _, name := range names {
+		authorID, err := findOrCreateAuthor(tx, name)
+		if err != nil {
+			return err
+		}
+		if seen[authorID] {
+			continue
+		}
+		seen[authorID] = true
+		contributors = append(contributors, models.BookContributor{AuthorID: authorID, Role: models.ContributorAuthor})
+	}
+	for _, contributor := range book.Contributors {
+		if contributor.Role != models.ContributorAuthor {
+			contributors = append(contributors, models.BookContributor{AuthorID: contributor.AuthorID, Role: contributor.Role})
+		}
+	}
+	for i := range contributors {
+		contributors[i].Position = i
+	}
+	return replaceContributors(tx, book.ID, contributors)
+}
+
+func findOrCreateAuthor(tx *gorm.DB, name string) (uint, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	var author models.Author
+	err := tx.Where("LOWER(name) = LOWER(?)", name).Order("id").First(&author).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		author = models.Author{Name: name}
+		err = tx.Create(&author).Error
+	}
+	return author.ID, err
+}
+
+func findOrCreatePublisher(tx *gorm.DB, name string) (uint, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	var publisher models.Publisher
+	err := tx.Where("LOWER(name) = LOWER(?)", name).Order("id").First(&publisher).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		publisher = models.Publisher{Name: name}
+		err = tx.Create(&publisher).Error
+	}
+	return publisher.ID, err
+}
+
+// bookAuthorNames lists the names credited with the author role, in credit order.
+func bookAuthorNames(book models.Book) []string {
+	names := []string{}
+	for _, contributor := range book.Contributors {
+		if contributor.Role == models.ContributorAuthor && contributor.Author.ID != 0 {
+			names = append(names, contributor.Author.Name)
+		}
+	}
+	return names
+}
+
+func loadEnrichmentBook(c *gin.Context) (models.Book, bool) {
+	var book models.Book
+	if err := withContributors(db).Preload("Publisher").First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return book, false
+	}
+	return book, true
+}
+
+func respondEnrichmentError(c *gin.Context, err error) {
+	status := http.StatusBadGateway
+	message := "Metadata lookup failed"
+	switch {
+	case errors.Is(err, errEnrichmentDisabled):
+		status, message = http.StatusServiceUnavailable, "Metadata enrichment is disabled"
+	case errors.Is(err, errNoISBN), errors.Is(err, metadata.ErrNotFound):
+		status, message = http.StatusUnprocessableEntity, "No metadata found"
+	}
+	var unsupported unsupportedCoverError
+	var invalid invalidCoverError
+	if errors.As(err, &unsupported) || errors.As(err, &invalid) {
+		status, message = http.StatusBadGateway, "The provider returned an unusable cover"
+	}
+	c.JSON(status, ErrorResponse{
+		Code:    status,
+		Message: message,
+		Details: err.Error(),
+	})
+}
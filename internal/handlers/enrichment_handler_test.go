@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/metadata"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useFixtureProvider serves the given files as METADATA_FIXTURE_DIR for one test.
+func useFixtureProvider(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixture, err := metadata.NewFixtureProvider(dir)
+	if err != nil {
+		t.Fatalf("NewFixtureProvider: %v", err)
+	}
+	previous := provider
+	InitMetadata(fixture)
+	t.Cleanup(func() { provider = previous })
+}
+
+func TestProposeEnrichment(t *testing.T) {
+	useFixtureProvider(t, map[string]string{
+		"9780261103573.json": `{
+			"title": "The Fellowship of the Ring",
+			"authors": ["J.R.R. Tolkien"],
+			"description": "The first part of The Lord of the Rings.",
+			"page_count": 423,
+			"publication_year": 1954,
+			"publisher": "Allen & Unwin",
+			"cover_url": "https://covers.example.com/9780261103573.jpg"
+		}`,
+	})
+
+	tolkien := models.Author{Name: "J.R.R. Tolkien"}
+	tolkien.ID = 1
+	lee := models.Author{Name: "Alan Lee"}
+	lee.ID = 2
+	book := models.Book{
+		Title:           "the fellowship of the ring",
+		ISBN:            "978-0-261-10357-3",
+		PublicationYear: 1991,
+		Contributors: []models.BookContributor{
+			{AuthorID: 1, Role: models.ContributorAuthor, Author: tolkien},
+			{AuthorID: 2, Role: models.ContributorIllustrator, Author: lee},
+		},
+	}
+	book.ID = 7
+
+	proposal, record, err := proposeEnrichment(context.Background(), book)
+	if err != nil {
+		t.Fatalf("proposeEnrichment: %v", err)
+	}
+	if record.Title != "The Fellowship of the Ring" {
+		t.Errorf("looked up %q, want the record of the ISBN without dashes", record.Title)
+	}
+	if proposal.BookID != 7 || proposal.Provider != "fixture" {
+		t.Errorf("proposal for book %d by %q, want book 7 by fixture", proposal.BookID, proposal.Provider)
+	}
+
+	changes := map[string]dto.FieldChange{}
+	for _, change := range proposal.Changes {
+		changes[change.Field] = change
+	}
+	// Titles differing only in case and authors already credited are not changes.
+	for _, field := range []string{"title", "authors"} {
+		if _, ok := changes[field]; ok {
+			t.Errorf("proposed a change of %s: %+v", field, changes[field])
+		}
+	}
+	want := map[string]bool{
+		"description":      true,
+		"page_count":       true,
+		"publication_year": false,
+		"publisher":        true,
+		"cover":            true,
+	}
+	for field, fillsEmpty := range want {
+		change, ok := changes[field]
+		if !ok {
+			t.Errorf("no change proposed for %s", field)
+			continue
+		}
+		if change.FillsEmpty != fillsEmpty {
+			t.Errorf("%s fills_empty = %v, want %v", field, change.FillsEmpty, fillsEmpty)
+		}
+	}
+	if len(changes) != len(want) {
+		t.Errorf("proposed %d changes, want %d: %+v", len(changes), len(want), proposal.Changes)
+	}
+}
+
+func TestProposeEnrichmentErrors(t *testing.T) {
+	book := models.Book{Title: "Unknown", ISBN: "9780000000002"}
+
+	previous := provider
+	provider = nil
+	_, _, err := proposeEnrichment(context.Background(), book)
+	provider = previous
+	if !errors.Is(err, errEnrichmentDisabled) {
+		t.Errorf("without a provider got %v, want errEnrichmentDisabled", err)
+	}
+
+	useFixtureProvider(t, nil)
+	if _, _, err := proposeEnrichment(context.Background(), book); !errors.Is(err, metadata.ErrNotFound) {
+		t.Errorf("for an unknown ISBN got %v, want metadata.ErrNotFound", err)
+	}
+	if _, _, err := proposeEnrichment(context.Background(), models.Book{Title: "No ISBN"}); !errors.Is(err, errNoISBN) {
+		t.Errorf("for a book without ISBN got %v, want errNoISBN", err)
+	}
+}
+
+func TestRespondEnrichmentError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{errEnrichmentDisabled, http.StatusServiceUnavailable},
+		{errNoISBN, http.StatusUnprocessableEntity},
+		{metadata.ErrNotFound, http.StatusUnprocessableEntity},
+		{errors.New("connection refused"), http.StatusBadGateway},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		respondEnrichmentError(c, tc.err)
+		if w.Code != tc.want {
+			t.Errorf("%v answered %d, want %d", tc.err, w.Code, tc.want)
+		}
+	}
+}
+
+func TestRunEnrichmentJob(t *testing.T) {
+	job := &dto.EnrichmentJobResponse{ID: "job-done", Status: "running", Total: 2, Results: []dto.EnrichmentProposal{}}
+	var seen []uint
+	runEnrichmentJob(context.Background(), job, []uint{3, 5}, func(_ context.Context, id uint) dto.EnrichmentProposal {
+		seen = append(seen, id)
+		return dto.EnrichmentProposal{BookID: id}
+	})
+
+	if job.Status != "done" || job.FinishedAt == nil {
+		t.Errorf("job ended %q at %v, want done with a finish time", job.Status, job.FinishedAt)
+	}
+	if job.Processed != 2 || len(job.Results) != 2 || job.Results[0].BookID != 3 || job.Results[1].BookID != 5 {
+		t.Errorf("processed %d with results %+v, want books 3 and 5 in order", job.Processed, job.Results)
+	}
+	if len(seen) != 2 {
+		t.Errorf("enriched %v, want both books", seen)
+	}
+}
+
+func TestRunEnrichmentJobCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &dto.EnrichmentJobResponse{ID: "job-cancelled", Status: "running", Total: 3, Results: []dto.EnrichmentProposal{}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runEnrichmentJob(ctx, job, []uint{1, 2, 3}, func(_ context.Context, id uint) dto.EnrichmentProposal {
+			// Shut down while the job waits before its next book.
+			cancel()
+			return dto.EnrichmentProposal{BookID: id}
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(enrichmentDelay / 2):
+		t.Fatal("the job did not stop promptly when cancelled")
+	}
+
+	if job.Status != "cancelled" || job.FinishedAt == nil {
+		t.Errorf("job ended %q at %v, want cancelled with a finish time", job.Status, job.FinishedAt)
+	}
+	if job.Processed != 1 {
+		t.Errorf("processed %d books, want 1", job.Processed)
+	}
+}
+
+func TestPruneEnrichmentJobs(t *testing.T) {
+	enrichmentJobs.Lock()
+	defer enrichmentJobs.Unlock()
+	saved := enrichmentJobs.byID
+	defer func() { enrichmentJobs.byID = saved }()
+
+	now := time.Now()
+	at := func(age time.Duration) *time.Time {
+		stamp := now.Add(-age)
+		return &stamp
+	}
+	enrichmentJobs.byID = map[string]*dto.EnrichmentJobResponse{
+		"running": {ID: "running", Status: "running"},
+		"fresh":   {ID: "fresh", Status: "done", FinishedAt: at(time.Minute)},
+		"expired": {ID: "expired", Status: "done", FinishedAt: at(enrichmentJobTTL + time.Minute)},
+	}
+	pruneEnrichmentJobs(now)
+	for id, want := range map[string]bool{"running": true, "fresh": true, "expired": false} {
+		if _, ok := enrichmentJobs.byID[id]; ok != want {
+			t.Errorf("job %s kept = %v, want %v", id, ok, want)
+		}
+	}
+
+	// Past the limit the oldest finished jobs go, running ones stay.
+	enrichmentJobs.byID = map[string]*dto.EnrichmentJobResponse{
+		"running": {ID: "running", Status: "running"},
+	}
+	for i := 0; i < maxFinishedEnrichmentJobs+5; i++ {
+		id := string(rune('a'+i/26)) + string(rune('a'+i%26))
+		enrichmentJobs.byID[id] = &dto.EnrichmentJobResponse{ID: id, Status: "done", FinishedAt: at(time.Duration(i) * time.Second)}
+	}
+	pruneEnrichmentJobs(now)
+	if got := len(enrichmentJobs.byID); got != maxFinishedEnrichmentJobs+1 {
+		t.Errorf("kept %d jobs, want %d finished and the running one", got, maxFinishedEnrichmentJobs)
+	}
+	if _, ok := enrichmentJobs.byID["running"]; !ok {
+		t.Error("the running job was pruned")
+	}
+	// The five oldest finished longest ago, at the end of the loop.
+	last := maxFinishedEnrichmentJobs + 4
+	oldest := string(rune('a'+last/26)) + string(rune('a'+last%26))
+	if _, ok := enrichmentJobs.byID[oldest]; ok {
+		t.Errorf("the oldest job %s was kept", oldest)
+	}
+}
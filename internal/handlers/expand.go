@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// includeAliases lets the singular name of a relation stand for the plural one.
+var includeAliases = map[string]string{
+	"author": "authors",
+	"review": "reviews",
+}
+
+// readOptions are the include and fields query parameters of a read endpoint.
+// include embeds related resources, fields trims the response to the named fields.
+type readOptions struct {
+	Include []string
+	Fields  []string
+}
+
+// parseReadOptions reads include and fields, checking include against the relations
+// the endpoint allows and fields against the JSON fields of response. Each endpoint
+// only allows relations it can load in a fixed number of queries. It answers 400
+// itself when a name is not known.
+func parseReadOptions(c *gin.Context, response interface{}, allowed ...string) (readOptions, bool) {
+	var options readOptions
+	for _, name := range splitQueryList(c.Query("include")) {
+		if alias, ok := includeAliases[name]; ok {
+			name = alias
+		}
+		if !containsString(allowed, name) {
+			details := "This endpoint does not embed related resources"
+			if len(allowed) > 0 {
+				details = "include accepts " + strings.Join(allowed, ", ")
+			}
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Cannot include %q", name),
+				Details: details,
+			})
+			return options, false
+		}
+		if !containsString(options.Include, name) {
+			options.Include = append(options.Include, name)
+		}
+	}
+
+	known := jsonFieldNames(response)
+	for _, name := range splitQueryList(c.Query("fields")) {
+		if !containsString(known, name) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Unknown field %q", name),
+				Details: "fields accepts " + strings.Join(known, ", "),
+			})
+			return options, false
+		}
+		if !containsString(options.Fields, name) {
+			options.Fields = append(options.Fields, name)
+		}
+	}
+	sort.Strings(options.Include)
+	sort.Strings(options.Fields)
+	return options, true
+}
+
+func (o readOptions) includes(name string) bool {
+	return containsString(o.Include, name)
+}
+
+// variant tells apart the representations of one record for its ETag. It is empty
+// without options, so plain responses keep their tags.
+func (o readOptions) variant() string {
+	if len(o.Include) == 0 && len(o.Fields) == 0 {
+		return ""
+	}
+	return "include=" + strings.Join(o.Include, ",") + ";fields=" + strings.Join(o.Fields, ",")
+}
+
+// shape trims response to the requested fields, always keeping id, and adds the
+// included resources. Without options it returns response untouched.
+func (o readOptions) shape(response interface{}, included map[string]interface{}) interface{} {
+	if len(o.Include) == 0 && len(o.Fields) == 0 {
+		return response
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return response
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return response
+	}
+	if len(o.Fields) > 0 {
+		for name := range object {
+			if name != "id" && !containsString(o.Fields, name) {
+				delete(object, name)
+			}
+		}
+	}
+	for _, name := range o.Include {
+		object[name] = included[name]
+	}
+	return object
+}
+
+// includedVersion sums up the included records for the ETag: how many there are and
+// when the latest of them changed.
+func includedVersion(stamps []time.Time) string {
+	var latest time.Time
+	for _, stamp := range stamps {
+		if stamp.After(latest) {
+			latest = stamp
+		}
+	}
+	return fmt.Sprintf(";%d@%d", len(stamps), latest.UnixMicro())
+}
+
+// creditedAuthors lists the authors credited on a preloaded book once each, in credit
+// order. Soft deleted authors are left out, as in the contributor list.
+func creditedAuthors(book models.Book) []dto.AuthorResponse {
+	authors := []dto.AuthorResponse{}
+	seen := map[uint]bool{}
+	for _, contributor := range book.Contributors {
+		if contributor.Author.ID == 0 || seen[contributor.AuthorID] {
+			continue
+		}
+		seen[contributor.AuthorID] = true
+		authors = append(authors, toAuthorResponse(contributor.Author))
+	}
+	return authors
+}
+
+// jsonFieldNames lists the JSON names of the fields of a response struct.
+func jsonFieldNames(response interface{}) []string {
+	responseType := reflect.TypeOf(response)
+	var names []string
+	for i := 0; i < responseType.NumField(); i++ {
+		name := strings.Split(responseType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
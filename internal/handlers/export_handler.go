@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rest-api-ozgur/internal/export"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportFlushEvery is how many rows are written between flushes to the client.
+const exportFlushEvery = 500
+
+// exportField is a column of an export together with the SQL that produces it.
+type exportField struct {
+	Name string
+	Expr string
+}
+
+// exportDataset describes one exportable table. filter applies the query parameters
+// of the request and returns a message for the client when one of them is invalid.
+type exportDataset struct {
+	Table  string
+	Fields []exportField
+	filter func(c *gin.Context, query *gorm.DB) (*gorm.DB, string)
+}
+
+var exportDatasets = map[string]exportDataset{
+	"books": {
+		Table: "books",
+		Fields: []exportField{
+			{"id", "books.id"},
+			{"title", "books.title"},
+			{"authors", "(SELECT string_agg(a.name, '; ' ORDER BY bc.position, bc.role) FROM book_contributors bc JOIN authors a ON a.id = bc.author_id WHERE bc.book_id = books.id AND bc.role = 'author')"},
+			{"contributors", "(SELECT string_agg(a.name || ' (' || bc.role || ')', '; ' ORDER BY bc.position, bc.role) FROM book_contributors bc JOIN authors a ON a.id = bc.author_id WHERE bc.book_id = books.id)"},
+			{"isbn", "books.isbn"},
+			{"publication_year", "books.publication_year"},
+			{"description", "books.description"},
+			{"work_id", "books.work_id"},
+			{"format", "books.format"},
+			{"publisher", "(SELECT p.name FROM publishers p WHERE p.id = books.publisher_id)"},
+			{"imprint", "(SELECT i.name FROM imprints i WHERE i.id = books.imprint_id)"},
+			{"page_count", "books.page_count"},
+			{"language", "books.language"},
+			{"genres", "(SELECT string_agg(g.name, '; ' ORDER BY g.name) FROM book_genres bg JOIN genres g ON g.id = bg.genre_id WHERE bg.book_id = books.id)"},
+			{"tags", "(SELECT string_agg(t.name, '; ' ORDER BY t.name) FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE bt.book_id = books.id)"},
+			{"created_at", "books.created_at"},
+			{"updated_at", "books.updated_at"},
+		},
+		filter: filterBookExport,
+	},
+	"authors": {
+		Table: "authors",
+		Fields: []exportField{
+			{"id", "authors.id"},
+			{"name", "authors.name"},
+			{"biography", "authors.biography"},
+			{"birth_date", partialDateExport("authors.born")},
+			{"death_date", partialDateExport("authors.died")},
+			{"nationality", "authors.nationality"},
+			{"aliases", "(SELECT string_agg(aa.name, '; ' ORDER BY aa.name) FROM author_aliases aa WHERE aa.author_id = authors.id)"},
+			{"book_count", "(SELECT COUNT(DISTINCT bc.book_id) FROM book_contributors bc JOIN books b ON b.id = bc.book_id WHERE bc.author_id = authors.id AND b.deleted_at IS NULL)"},
+			{"created_at", "authors.created_at"},
+			{"updated_at", "authors.updated_at"},
+		},
+		filter: filterAuthorExport,
+	},
+	"reviews": {
+		Table: "reviews",
+		Fields: []exportField{
+			{"id", "reviews.id"},
+			{"book_id", "reviews.book_id"},
+			{"book_title", "(SELECT b.title FROM books b WHERE b.id = reviews.book_id)"},
+			{"reviewer", "(SELECT u.username FROM users u WHERE u.id = reviews.user_id)"},
+			{"rating", "reviews.rating"},
+			{"status", "reviews.status"},
+			{"comment", "reviews.comment"},
+			{"date_posted", "reviews.date_posted"},
+			{"created_at", "reviews.created_at"},
+			{"updated_at", "reviews.updated_at"},
+		},
+		filter: filterReviewExport,
+	},
+}
+
+// ExportDataset godoc
+// @Summary Export books, authors or reviews
+// @Description Stream every row of a dataset as CSV, NDJSON or XLSX. Rows are read from the database with a
+// @Description cursor and written as they arrive, so exports of any size use constant memory. fields selects
+// @Description and orders the columns. Books can be filtered by genre, tag, language, book_format, publisher_id,
+// @Description year_from and year_to, authors by q, reviews by book_id, min_rating and max_rating, and all
+// @Description datasets by updated_since (RFC 3339 or YYYY-MM-DD).
+// @Tags export
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param dataset path string true "books, authors or reviews"
+// @Param format query string false "csv (default), ndjson or xlsx"
+// @Param fields query string false "Comma separated list of columns"
+// @Param updated_since query string false "Only rows changed since this time"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/export/{dataset} [get]
+func ExportDataset(c *gin.Context) {
+	name := c.Param("dataset")
+	dataset, ok := exportDatasets[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Dataset not found",
+			Details: "Exportable datasets are books, authors and reviews",
+		})
+		return
+	}
+	format, ok := export.ParseFormat(c.Query("format"))
+	if !ok {
+		respondInvalidExport(c, "format must be csv, ndjson or xlsx")
+		return
+	}
+	fields, message := selectExportFields(dataset, c.Query("fields"))
+	if message != "" {
+		respondInvalidExport(c, message)
+		return
+	}
+
+	query := db.Table(dataset.Table).Where(dataset.Table + ".deleted_at IS NULL")
+	if since := c.Query("updated_since"); since != "" {
+		t, err := parseExportTime(since)
+		if err != nil {
+			respondInvalidExport(c, "updated_since must be an RFC 3339 time or a YYYY-MM-DD date")
+			return
+		}
+		query = query.Where(dataset.Table+".updated_at >= ?", t)
+	}
+	query, message = dataset.filter(c, query)
+	if message != "" {
+		respondInvalidExport(c, message)
+		return
+	}
+
+	columns := make([]string, len(fields))
+	selects := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+		selects[i] = field.Expr + ` AS "` + field.Name + `"`
+	}
+
+	rows, err := query.Select(strings.Join(selects, ", ")).Order(dataset.Table + ".id").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to export " + name,
+			Details: err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102"), format.Extension())
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// From here on the status line is sent, so failures can only cut the stream short.
+	writer, err := export.NewWriter(format, c.Writer, name, columns)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	values := make([]interface{}, len(fields))
+	targets := make([]interface{}, len(fields))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for n := 1; rows.Next(); n++ {
+		if err := rows.Scan(targets...); err != nil {
+			c.Error(err)
+			return
+		}
+		if err := writer.WriteRow(values); err != nil {
+			c.Error(err)
+			return
+		}
+		if n%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// partialDateExport formats a models.PartialDate stored under the column prefix as far
+// as it is known.
+func partialDateExport(prefix string) string {
+	return fmt.Sprintf(`CASE %[1]s_precision WHEN 'year' THEN to_char(%[1]s_date, 'YYYY')
+		WHEN 'month' THEN to_char(%[1]s_date, 'YYYY-MM') ELSE to_char(%[1]s_date, 'YYYY-MM-DD') END`, prefix)
+}
+
+// selectExportFields resolves the fields query parameter; all fields are exported when
+// it is empty.
+func selectExportFields(dataset exportDataset, param string) ([]exportField, string) {
+	if strings.TrimSpace(param) == "" {
+		return dataset.Fields, ""
+	}
+
+	byName := make(map[string]exportField, len(dataset.Fields))
+	for _, field := range dataset.Fields {
+		byName[field.Name] = field
+	}
+	var fields []exportField
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		field, ok := byName[name]
+		if !ok {
+			names := make([]string, len(dataset.Fields))
+			for i, field := range dataset.Fields {
+				names[i] = field.Name
+			}
+			return nil, fmt.Sprintf("unknown field %q, available fields are %s", name, strings.Join(names, ", "))
+		}
+		seen[name] = true
+		fields = append(fields, field)
+	}
+	return fields, ""
+}
+
+func filterBookExport(c *gin.Context, query *gorm.DB) (*gorm.DB, string) {
+	if key := c.Query("genre"); key != "" {
+		genre, err := findGenre(key)
+		if err != nil {
+			return nil, "genre " + key + " does not exist"
+		}
+		ids, err := genreSubtreeIDs(genre.ID)
+		if err != nil {
+			return nil, err.Error()
+		}
+		query = query.Where("books.id IN (SELECT book_id FROM book_genres WHERE genre_id IN ?)", ids)
+	}
+	if tag := normalizeTags([]string{c.Query("tag")}); len(tag) > 0 {
+		query = query.Where("books.id IN (SELECT bt.book_id FROM book_tags bt JOIN tags t ON t.id = bt.tag_id WHERE t.name = ?)", tag[0])
+	}
+	if language := c.Query("language"); language != "" {
+		query = query.Where("books.language = ?", language)
+	}
+	if format := c.Query("book_format"); format != "" {
+		query = query.Where("books.format = ?", format)
+	}
+	for param, condition := range map[string]string{
+		"publisher_id": "books.publisher_id = ?",
+		"year_from":    "books.publication_year >= ?",
+		"year_to":      "books.publication_year <= ?",
+	} {
+		if value := c.Query(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, param + " must be a number"
+			}
+			query = query.Where(condition, n)
+		}
+	}
+	return query, ""
+}
+
+func filterAuthorExport(c *gin.Context, query *gorm.DB) (*gorm.DB, string) {
+	return searchAuthors(query, models.SearchKey(c.Query("q"))), ""
+}
+
+func filterReviewExport(c *gin.Context, query *gorm.DB) (*gorm.DB, string) {
+	for param, condition := range map[string]string{
+		"book_id":    "reviews.book_id = ?",
+		"min_rating": "reviews.rating >= ?",
+		"max_rating": "reviews.rating <= ?",
+	} {
+		if value := c.Query(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, param + " must be a number"
+			}
+			query = query.Where(condition, n)
+		}
+	}
+	return query, ""
+}
+
+func parseExportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func respondInvalidExport(c *gin.Context, details string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid export request",
+		Details: details,
+	})
+}
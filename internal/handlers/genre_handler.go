@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetGenres godoc
+// @Summary Get the genre tree
+// @Description Get all genres nested under their parents
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Success 200 {array} dto.GenreResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/genres [get]
+func GetGenres(c *gin.Context) {
+	var genres []models.Genre
+	if err := db.Order("name").Find(&genres).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve genres",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildGenreTree(genres))
+}
+
+// GetGenre godoc
+// @Summary Get a specific genre
+// @Description Get a genre by its ID or slug together with its subtree
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path string true "Genre ID or slug"
+// @Success 200 {object} dto.GenreResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/genres/{id} [get]
+func GetGenre(c *gin.Context) {
+	genre, err := findGenre(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Genre not found",
+			Details: "The genre with the given ID or slug does not exist",
+		})
+		return
+	}
+
+	ids, err := genreSubtreeIDs(genre.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve genre",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var subtree []models.Genre
+	if err := db.Where("id IN ?", ids).Order("name").Find(&subtree).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve genre",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for _, node := range buildGenreTree(subtree) {
+		if node.ID == genre.ID {
+			c.JSON(http.StatusOK, node)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, toGenreResponse(genre))
+}
+
+// CreateGenre godoc
+// @Summary Create a new genre
+// @Description Create a genre, optionally below a parent genre. The slug is derived from the name when omitted.
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genre body dto.CreateGenreRequest true "Create genre"
+// @Success 201 {object} dto.GenreResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/genres [post]
+func CreateGenre(c *gin.Context) {
+	var req dto.CreateGenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if req.ParentID != nil {
+		if exists, err := referenceExists(db, &models.Genre{}, *req.ParentID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to create genre",
+				Details: err.Error(),
+			})
+			return
+		} else if !exists {
+			respondMissingReference(c, "genre", *req.ParentID)
+			return
+		}
+	}
+
+	slug := slugify(req.Slug)
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	genre := models.Genre{
+		Name:     req.Name,
+		Slug:     slug,
+		ParentID: req.ParentID,
+	}
+
+	if err := db.Create(&genre).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "Genre already exists",
+				Details: "A genre with the slug " + slug + " already exists",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create genre",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toGenreResponse(genre))
+}
+
+// UpdateGenre godoc
+// @Summary Update a genre
+// @Description Rename a genre or move it below another parent. A genre cannot be moved into its own subtree.
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path string true "Genre ID or slug"
+// @Param genre body dto.UpdateGenreRequest true "Update genre"
+// @Success 200 {object} dto.GenreResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/genres/{id} [put]
+func UpdateGenre(c *gin.Context) {
+	var req dto.UpdateGenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	genre, err := findGenre(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Genre not found",
+			Details: "The genre with the given ID or slug does not exist",
+		})
+		return
+	}
+
+	if req.Name != "" {
+		genre.Name = req.Name
+	}
+	if slug := slugify(req.Slug); slug != "" {
+		genre.Slug = slug
+	}
+	if req.MoveToRoot {
+		genre.ParentID = nil
+	} else if req.ParentID != nil {
+		subtree, err := genreSubtreeIDs(genre.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update genre",
+				Details: err.Error(),
+			})
+			return
+		}
+		for _, id := range subtree {
+			if id == *req.ParentID {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Code:    http.StatusBadRequest,
+					Message: "Invalid input data",
+					Details: "A genre cannot be moved below itself or one of its descendants",
+				})
+				return
+			}
+		}
+		if exists, err := referenceExists(db, &models.Genre{}, *req.ParentID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update genre",
+				Details: err.Error(),
+			})
+			return
+		} else if !exists {
+			respondMissingReference(c, "genre", *req.ParentID)
+			return
+		}
+		genre.ParentID = req.ParentID
+	}
+
+	if err := db.Save(&genre).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "Genre already exists",
+				Details: "A genre with the slug " + genre.Slug + " already exists",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update genre",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, toGenreResponse(genre))
+}
+
+// DeleteGenre godoc
+// @Summary Delete a genre
+// @Description Delete a genre that has no child genres. Books are detached from it.
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path string true "Genre ID or slug"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/genres/{id} [delete]
+func DeleteGenre(c *gin.Context) {
+	genre, err := findGenre(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Genre not found",
+			Details: "The genre with the given ID or slug does not exist",
+		})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&models.Genre{}).Where("parent_id = ?", genre.ID).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return &dependentsError{Entity: "child genre", Count: children}
+		}
+		if err := tx.Model(&genre).Association("Books").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&genre).Error
+	})
+	var dependents *dependentsError
+	if errors.As(err, &dependents) {
+		respondDependents(c, "genre", dependents)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete genre",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Genre deleted"})
+}
+
+// GetTags godoc
+// @Summary Autocomplete tags
+// @Description Get the most used tags starting with the given prefix
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param q query string false "Tag prefix"
+// @Param limit query int false "Maximum number of suggestions (default 10, max 50)"
+// @Success 200 {array} dto.TagResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/tags [get]
+func GetTags(c *gin.Context) {
+	prefix := normalizeTags([]string{c.Query("q")})
+	limit := queryInt(c, "limit", 10, 50)
+
+	query := db.Table("tags").
+		Select("tags.name, COUNT(book_tags.book_id) AS books").
+		Joins("LEFT JOIN book_tags ON book_tags.tag_id = tags.id").
+		Group("tags.id, tags.name").
+		Order("books DESC, tags.name").
+		Limit(limit)
+	if len(prefix) > 0 {
+		query = query.Where("tags.name LIKE ?", escapeLike(prefix[0])+"%")
+	}
+
+	response := []dto.TagResponse{}
+	if err := query.Scan(&response).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve tags",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
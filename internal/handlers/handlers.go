@@ -1,9 +1,40 @@
 package handlers
 
-import "gorm.io/gorm"
+import (
+	"crypto/rand"
+	"encoding/hex"
 
-var db *gorm.DB
+	"go-rest-api-ozgur/internal/config"
+	"go-rest-api-ozgur/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+var (
+	db    *gorm.DB
+	cfg   = &config.Config{DeletePolicy: config.DeleteRestrict, CoverMaxBytes: 5 << 20, RatingPriorMean: 3, RatingPriorWeight: 10}
+	blobs storage.BlobStore
+)
 
 func InitDB(database *gorm.DB) {
 	db = database
 }
+
+// InitConfig hands the loaded configuration to the handlers.
+func InitConfig(c *config.Config) {
+	cfg = c
+}
+
+// InitStorage sets the blob store uploaded files are kept in.
+func InitStorage(store storage.BlobStore) {
+	blobs = store
+}
+
+// randomID returns 16 random hex digits, used to name enrichment jobs and cascades.
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
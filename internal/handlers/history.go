@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	historyBook   = "book"
+	historyAuthor = "author"
+	historyReview = "review"
+)
+
+// catalogEntities maps the entity names used in history and trash URLs to the stored ones.
+var catalogEntities = map[string]string{
+	"books":   historyBook,
+	"authors": historyAuthor,
+	"reviews": historyReview,
+}
+
+// The snapshots are what a revision stores. Covers are left out: their files are
+// deleted when replaced, so an old cover key cannot be restored.
+type bookSnapshot struct {
+	Title           string                `json:"title"`
+	ISBN            string                `json:"isbn"`
+	PublicationYear int                   `json:"publication_year"`
+	Description     string                `json:"description"`
+	Format          string                `json:"format"`
+	WorkID          *uint                 `json:"work_id"`
+	PublisherID     *uint                 `json:"publisher_id"`
+	ImprintID       *uint                 `json:"imprint_id"`
+	PageCount       int                   `json:"page_count"`
+	Language        string                `json:"language"`
+	Contributors    []contributorSnapshot `json:"contributors"`
+	GenreIDs        []uint                `json:"genre_ids"`
+	Tags            []string              `json:"tags"`
+}
+
+type contributorSnapshot struct {
+	AuthorID uint   `json:"author_id"`
+	Role     string `json:"role"`
+}
+
+type authorSnapshot struct {
+	Name        string   `json:"name"`
+	Biography   string   `json:"biography"`
+	BirthDate   string   `json:"birth_date"`
+	DeathDate   string   `json:"death_date"`
+	Nationality string   `json:"nationality"`
+	Aliases     []string `json:"aliases"`
+}
+
+type reviewSnapshot struct {
+	BookID     uint   `json:"book_id"`
+	Rating     int    `json:"rating"`
+	Comment    string `json:"comment"`
+	DatePosted string `json:"date_posted"`
+}
+
+// actor names the user making a request for the history, from the token if one was sent.
+func actor(c *gin.Context) string {
+	if username := c.GetString("username"); username != "" {
+		return username
+	}
+	return "anonymous"
+}
+
+// recordRevision stores the current state of an entity as its next version. Call it in
+// the transaction of the change, after writing, or before deleting for deletes.
+func recordRevision(tx *gorm.DB, entity string, id uint, action, changedBy string) error {
+	return insertRevision(tx, entity, id, action, changedBy, nil)
+}
+
+// RevisionRecorder records revisions as changedBy for writers outside the handlers,
+// such as the importer.
+func RevisionRecorder(changedBy string) func(tx *gorm.DB, entity string, id uint, action string) error {
+	return func(tx *gorm.DB, entity string, id uint, action string) error {
+		return recordRevision(tx, entity, id, action, changedBy)
+	}
+}
+
+func insertRevision(tx *gorm.DB, entity string, id uint, action, changedBy string, revertedFrom *int) error {
+	snapshot, err := takeSnapshot(tx, entity, id)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// Lock the entity so concurrent changes of it number their revisions one after the
+	// other instead of both taking the same next version.
+	var locked []uint
+	err = tx.Unscoped().Model(liveEntityModel(entity)).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		Pluck("id", &locked).Error
+	if err != nil {
+		return err
+	}
+
+	var version int
+	err = tx.Model(&models.Revision{}).
+		Where("entity = ? AND entity_id = ?", entity, id).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.Revision{
+		Entity:       entity,
+		EntityID:     id,
+		Version:      version + 1,
+		Action:       action,
+		RevertedFrom: revertedFrom,
+		ChangedBy:    changedBy,
+		Snapshot:     string(data),
+	}).Error
+}
+
+// takeSnapshot reads the stored state of an entity, including soft deleted rows.
+func takeSnapshot(tx *gorm.DB, entity string, id uint) (interface{}, error) {
+	tx = tx.Unscoped()
+	switch entity {
+	case historyBook:
+		var book models.Book
+		err := tx.Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+			Preload("Genres", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+			Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+			First(&book, id).Error
+		if err != nil {
+			return nil, err
+		}
+		snapshot := bookSnapshot{
+			Title:           book.Title,
+			ISBN:            book.ISBN,
+			PublicationYear: book.PublicationYear,
+			Description:     book.Description,
+			Format:          string(book.Format),
+			WorkID:          book.WorkID,
+			PublisherID:     book.PublisherID,
+			ImprintID:       book.ImprintID,
+			PageCount:       book.PageCount,
+			Language:        book.Language,
+			Contributors:    []contributorSnapshot{},
+			GenreIDs:        []uint{},
+			Tags:            toTagNames(book.Tags),
+		}
+		for _, contributor := range book.Contributors {
+			snapshot.Contributors = append(snapshot.Contributors, contributorSnapshot{
+				AuthorID: contributor.AuthorID,
+				Role:     string(contributor.Role),
+			})
+		}
+		for _, genre := range book.Genres {
+			snapshot.GenreIDs = append(snapshot.GenreIDs, genre.ID)
+		}
+		if snapshot.Tags == nil {
+			snapshot.Tags = []string{}
+		}
+		return snapshot, nil
+
+	case historyAuthor:
+		var author models.Author
+		if err := withAliases(tx).First(&author, id).Error; err != nil {
+			return nil, err
+		}
+		return toAuthorSnapshot(author), nil
+
+	case historyReview:
+		var review models.Review
+		if err := tx.First(&review, id).Error; err != nil {
+			return nil, err
+		}
+		return reviewSnapshot{
+			BookID:     review.BookID,
+			Rating:     review.Rating,
+			Comment:    review.Comment,
+			DatePosted: review.DatePosted,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown history entity %q", entity)
+}
+
+// missingReferenceError is returned when a snapshot refers to a record that does not exist.
+type missingReferenceError struct {
+	Entity string
+	ID     uint
+}
+
+func (e *missingReferenceError) Error() string {
+	return fmt.Sprintf("there is no %s with ID %d", e.Entity, e.ID)
+}
+
+// applySnapshot writes a snapshot to the live entity, replacing all of its fields.
+func applySnapshot(tx *gorm.DB, entity string, id uint, data string) error {
+	switch entity {
+	case historyBook:
+		var snapshot bookSnapshot
+		if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+			return err
+		}
+		for _, ref := range []struct {
+			entity string
+			model  interface{}
+			id     *uint
+		}{
+			{"work", &models.Work{}, snapshot.WorkID},
+			{"publisher", &models.Publisher{}, snapshot.PublisherID},
+			{"imprint", &models.Imprint{}, snapshot.ImprintID},
+		} {
+			if ref.id == nil {
+				continue
+			}
+			if exists, err := referenceExists(tx, ref.model, *ref.id); err != nil {
+				return err
+			} else if !exists {
+				return &missingReferenceError{Entity: ref.entity, ID: *ref.id}
+			}
+		}
+
+		reqs := make([]dto.ContributorRequest, len(snapshot.Contributors))
+		for i, contributor := range snapshot.Contributors {
+			reqs[i] = dto.ContributorRequest{AuthorID: contributor.AuthorID, Role: contributor.Role}
+		}
+		contributors, missing, err := buildContributors(tx, reqs)
+		if err != nil {
+			return err
+		}
+		if missing != 0 {
+			return &missingReferenceError{Entity: "author", ID: missing}
+		}
+		genres, missing, err := resolveGenres(tx, snapshot.GenreIDs)
+		if err != nil {
+			return err
+		}
+		if missing != 0 {
+			return &missingReferenceError{Entity: "genre", ID: missing}
+		}
+		tags, err := resolveTags(tx, snapshot.Tags)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Book{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"title":            snapshot.Title,
+			"isbn":             snapshot.ISBN,
+			"publication_year": snapshot.PublicationYear,
+			"description":      snapshot.Description,
+			"format":           snapshot.Format,
+			"work_id":          snapshot.WorkID,
+			"publisher_id":     snapshot.PublisherID,
+			"imprint_id":       snapshot.ImprintID,
+			"page_count":       snapshot.PageCount,
+			"language":         snapshot.Language,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := replaceContributors(tx, id, contributors); err != nil {
+			return err
+		}
+		book := models.Book{}
+		book.ID = id
+		if err := replaceAssociation(tx, &book, "Genres", genres); err != nil {
+			return err
+		}
+		if err := replaceAssociation(tx, &book, "Tags", tags); err != nil {
+			return err
+		}
+		forgetBooks([]uint{id})
+		return nil
+
+	case historyAuthor:
+		var snapshot authorSnapshot
+		if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+			return err
+		}
+		born, died, err := parseAuthorDates(snapshot.BirthDate, snapshot.DeathDate)
+		if err != nil {
+			return err
+		}
+		forgetAuthorBooks(tx, id)
+		err = tx.Model(&models.Author{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"name":           snapshot.Name,
+			"name_key":       models.SearchKey(snapshot.Name),
+			"sort_key":       models.SortKey(snapshot.Name),
+			"biography":      snapshot.Biography,
+			"born_date":      born.Date,
+			"born_precision": born.Precision,
+			"died_date":      died.Date,
+			"died_precision": died.Precision,
+			"nationality":    snapshot.Nationality,
+		}).Error
+		if err != nil {
+			return err
+		}
+		_, err = replaceAliases(tx, id, snapshot.Aliases)
+		return err
+
+	case historyReview:
+		var snapshot reviewSnapshot
+		if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+			return err
+		}
+		if exists, err := referenceExists(tx, &models.Book{}, snapshot.BookID); err != nil {
+			return err
+		} else if !exists {
+			return &missingReferenceError{Entity: "book", ID: snapshot.BookID}
+		}
+		var current models.Review
+		if err := tx.First(&current, id).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Review{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"book_id":     snapshot.BookID,
+			"rating":      snapshot.Rating,
+			"comment":     snapshot.Comment,
+			"date_posted": snapshot.DatePosted,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return refreshRatings(tx, current.BookID, snapshot.BookID)
+	}
+	return fmt.Errorf("unknown history entity %q", entity)
+}
+
+// liveEntityModel returns an empty model of the entity, to check that it still exists.
+func liveEntityModel(entity string) interface{} {
+	switch entity {
+	case historyBook:
+		return &models.Book{}
+	case historyAuthor:
+		return &models.Author{}
+	default:
+		return &models.Review{}
+	}
+}
+
+// diffSnapshots lists the top-level fields that differ between two snapshots.
+func diffSnapshots(from, to string) ([]dto.RevisionChange, error) {
+	var before, after map[string]interface{}
+	if err := json.Unmarshal([]byte(from), &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(to), &after); err != nil {
+		return nil, err
+	}
+
+	changes := []dto.RevisionChange{}
+	for _, field := range snapshotFields(before, after) {
+		if !jsonEqual(before[field], after[field]) {
+			changes = append(changes, dto.RevisionChange{Field: field, From: before[field], To: after[field]})
+		}
+	}
+	return changes, nil
+}
+
+// snapshotFields returns the keys of both snapshots in a stable order.
+func snapshotFields(snapshots ...map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var fields []string
+	for _, snapshot := range snapshots {
+		for field := range snapshot {
+			if !seen[field] {
+				seen[field] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func jsonEqual(a, b interface{}) bool {
+	left, err1 := json.Marshal(a)
+	right, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(left) == string(right)
+}
+
+func toRevisionResponse(revision models.Revision) dto.RevisionResponse {
+	return dto.RevisionResponse{
+		Entity:       revision.Entity,
+		EntityID:     revision.EntityID,
+		Version:      revision.Version,
+		Action:       revision.Action,
+		RevertedFrom: revision.RevertedFrom,
+		ChangedBy:    revision.ChangedBy,
+		ChangedAt:    revision.CreatedAt,
+		Snapshot:     json.RawMessage(revision.Snapshot),
+	}
+}
+
+var errUnknownHistoryEntity = errors.New("history is kept for books, authors and reviews")
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetRevisions godoc
+// @Summary List the revisions of an entity
+// @Description List the recorded versions of a book, author or review, newest first, with who made each change,
+// @Description when, which fields it changed and the complete state after it.
+// @Tags history
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Entity ID"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Revisions per page (default 20, max 100)"
+// @Success 200 {array} dto.RevisionResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/history/{entity}/{id} [get]
+func GetRevisions(c *gin.Context) {
+	entity, id, ok := historyTarget(c)
+	if !ok {
+		return
+	}
+
+	query := db.Where("entity = ? AND entity_id = ?", entity, id)
+	paged, err := paginate(c, query, &models.Revision{})
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	var revisions []models.Revision
+	if err := paged.Order("version DESC").Find(&revisions).Error; err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "No revisions found",
+			Details: fmt.Sprintf("No changes to the %s with ID %d have been recorded", entity, id),
+		})
+		return
+	}
+
+	// The version before the oldest one on the page is needed to tell what it changed.
+	var previous models.Revision
+	oldest := revisions[len(revisions)-1].Version
+	err = db.Where("entity = ? AND entity_id = ? AND version < ?", entity, id, oldest).
+		Order("version DESC").Limit(1).Find(&previous).Error
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+
+	response := make([]dto.RevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		response[i] = toRevisionResponse(revision)
+		before := previous
+		if i+1 < len(revisions) {
+			before = revisions[i+1]
+		}
+		if before.ID == 0 {
+			continue
+		}
+		changes, err := diffSnapshots(before.Snapshot, revision.Snapshot)
+		if err != nil {
+			respondHistoryError(c, err)
+			return
+		}
+		for _, change := range changes {
+			response[i].Changed = append(response[i].Changed, change.Field)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRevision godoc
+// @Summary Get a revision
+// @Description Get one version of a book, author or review.
+// @Tags history
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Entity ID"
+// @Param version path int true "Version"
+// @Success 200 {object} dto.RevisionResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/history/{entity}/{id}/{version} [get]
+func GetRevision(c *gin.Context) {
+	entity, id, ok := historyTarget(c)
+	if !ok {
+		return
+	}
+	revision, ok := findRevision(c, entity, id, c.Param("version"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toRevisionResponse(revision))
+}
+
+// GetRevisionAt godoc
+// @Summary Get an entity as it was at a point in time
+// @Description Get the version of a book, author or review that was current at the given time.
+// @Tags history
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Entity ID"
+// @Param time query string true "RFC 3339 timestamp or YYYY-MM-DD"
+// @Success 200 {object} dto.RevisionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/history/{entity}/{id}/at [get]
+func GetRevisionAt(c *gin.Context) {
+	entity, id, ok := historyTarget(c)
+	if !ok {
+		return
+	}
+	at, err := parseHistoryTime(c.Query("time"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid time",
+			Details: "Use an RFC 3339 timestamp such as 2024-05-01T12:00:00Z or a date such as 2024-05-01",
+		})
+		return
+	}
+
+	var revision models.Revision
+	err = db.Where("entity = ? AND entity_id = ? AND created_at <= ?", entity, id, at).
+		Order("version DESC").
+		First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Revision not found",
+			Details: fmt.Sprintf("No version of the %s with ID %d was recorded before %s", entity, id, at.Format(time.RFC3339)),
+		})
+		return
+	}
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toRevisionResponse(revision))
+}
+
+// DiffRevisions godoc
+// @Summary Compare two revisions
+// @Description List the fields that differ between two versions of a book, author or review. to defaults to the
+// @Description latest version and from to the version before to.
+// @Tags history
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Entity ID"
+// @Param from query int false "Older version"
+// @Param to query int false "Newer version"
+// @Success 200 {object} dto.RevisionDiffResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/history/{entity}/{id}/diff [get]
+func DiffRevisions(c *gin.Context) {
+	entity, id, ok := historyTarget(c)
+	if !ok {
+		return
+	}
+
+	to := c.Query("to")
+	if to == "" {
+		var latest int
+		err := db.Model(&models.Revision{}).
+			Where("entity = ? AND entity_id = ?", entity, id).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			respondHistoryError(c, err)
+			return
+		}
+		to = strconv.Itoa(latest)
+	}
+	newer, ok := findRevision(c, entity, id, to)
+	if !ok {
+		return
+	}
+	from := c.Query("from")
+	if from == "" {
+		from = strconv.Itoa(newer.Version - 1)
+	}
+	older, ok := findRevision(c, entity, id, from)
+	if !ok {
+		return
+	}
+
+	changes, err := diffSnapshots(older.Snapshot, newer.Snapshot)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RevisionDiffResponse{
+		Entity:   entity,
+		EntityID: id,
+		From:     older.Version,
+		To:       newer.Version,
+		Changes:  changes,
+	})
+}
+
+// RevertRevision godoc
+// @Summary Revert to a revision
+// @Description Restore a book, author or review to the state of an earlier version. The revert is recorded as a
+// @Description new version, so it can be undone in turn. Books get their contributors, genres and tags back, but
+// @Description not their cover.
+// @Tags history
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Entity ID"
+// @Param version path int true "Version to restore"
+// @Success 200 {object} dto.RevisionResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/history/{entity}/{id}/{version}/revert [post]
+func RevertRevision(c *gin.Context) {
+	entity, id, ok := historyTarget(c)
+	if !ok {
+		return
+	}
+	revision, ok := findRevision(c, entity, id, c.Param("version"))
+	if !ok {
+		return
+	}
+	if revision.Action == models.RevisionDelete {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Cannot revert to a deletion",
+			Details: "Pick the version before the deletion",
+		})
+		return
+	}
+
+	var latest models.Revision
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the live row, so the entity cannot be deleted while it is reverted.
+		var live []uint
+		err := tx.Model(liveEntityModel(entity)).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).Pluck("id", &live).Error
+		if err != nil {
+			return err
+		}
+		if len(live) == 0 {
+			return errRevertDeleted
+		}
+		if err := applySnapshot(tx, entity, id, revision.Snapshot); err != nil {
+			return err
+		}
+		version := revision.Version
+		if err := insertRevision(tx, entity, id, models.RevisionRevert, actor(c), &version); err != nil {
+			return err
+		}
+		return tx.Where("entity = ? AND entity_id = ?", entity, id).Order("version DESC").First(&latest).Error
+	})
+	if errors.Is(err, errRevertDeleted) {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "Cannot revert a deleted " + entity,
+			Details: fmt.Sprintf("The %s with ID %d has been deleted", entity, id),
+		})
+		return
+	}
+	var missing *missingReferenceError
+	if errors.As(err, &missing) {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Cannot revert " + entity,
+			Details: fmt.Sprintf("The %s with ID %d no longer exists", missing.Entity, missing.ID),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to revert " + entity,
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, toRevisionResponse(latest))
+}
+
+// errRevertDeleted is returned inside a revert when the entity is no longer live.
+var errRevertDeleted = errors.New("the entity has been deleted")
+
+// historyTarget reads the entity and ID of a history URL, answering 404 for entities
+// without history.
+func historyTarget(c *gin.Context) (string, uint, bool) {
+	entity, ok := catalogEntities[c.Param("entity")]
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Unknown entity",
+			Details: errUnknownHistoryEntity.Error(),
+		})
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Revision not found",
+			Details: "The ID must be a positive number",
+		})
+		return "", 0, false
+	}
+	return entity, uint(id), true
+}
+
+func findRevision(c *gin.Context, entity string, id uint, version string) (models.Revision, bool) {
+	var revision models.Revision
+	number, err := strconv.Atoi(version)
+	if err == nil {
+		err = db.Where("entity = ? AND entity_id = ? AND version = ?", entity, id, number).First(&revision).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, strconv.ErrSyntax) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "Revision not found",
+				Details: fmt.Sprintf("The %s with ID %d has no version %s", entity, id, version),
+			})
+			return revision, false
+		}
+		respondHistoryError(c, err)
+		return revision, false
+	}
+	return revision, true
+}
+
+// parseHistoryTime accepts RFC 3339 timestamps and plain dates, which mean the end of that day.
+func parseHistoryTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+func respondHistoryError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to read history",
+		Details: err.Error(),
+	})
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/importer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBytes limits the size of an uploaded import file.
+const maxImportBytes = 32 << 20
+
+// ImportBooks godoc
+// @Summary Import books in bulk
+// @Description Import books from a CSV, NDJSON, ONIX 3.0, MARC21 or MARCXML body. Authors and publishers are
+// @Description matched by name and created when missing, and every row is validated with the same rules as
+// @Description creating a single book. Without chunk_size the whole file is imported in one transaction that is
+// @Description rolled back if any row fails; with chunk_size rows are committed in chunks and invalid rows are
+// @Description skipped. dry_run only validates. With upsert, which is the default for ONIX and MARC feeds, a row
+// @Description updates the book with the same ISBN instead of being rejected. The report counts the source
+// @Description fields that could not be mapped.
+// @Tags import
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept application/xml
+// @Accept application/marc
+// @Produce json
+// @Param format query string false "csv, ndjson, onix, marc or marcxml, defaults to the Content-Type"
+// @Param dry_run query bool false "Validate without writing"
+// @Param chunk_size query int false "Commit every N rows instead of all or nothing"
+// @Param upsert query bool false "Update books with the same ISBN"
+// @Success 200 {object} dto.ImportReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/import/books [post]
+func ImportBooks(c *gin.Context) {
+	format, ok := importFormat(c)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Unsupported import format",
+			Details: "Send text/csv, application/x-ndjson or application/marc, or set format to csv, ndjson, onix, marc or marcxml",
+		})
+		return
+	}
+
+	opts := importer.Options{
+		DryRun:    c.Query("dry_run") == "true" || c.Query("dry_run") == "1",
+		ChunkSize: queryInt(c, "chunk_size", 0, 10000),
+		Upsert:    format == importer.FormatONIX || format == importer.FormatMARC || format == importer.FormatMARCXML,
+		Record:    RevisionRecorder(actor(c)),
+	}
+	if upsert := c.Query("upsert"); upsert != "" {
+		opts.Upsert = upsert == "true" || upsert == "1"
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	rows, err := importer.Parse(c.Request.Body, format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+				Code:    http.StatusRequestEntityTooLarge,
+				Message: "Import file too large",
+				Details: fmt.Sprintf("Import files may be at most %d bytes", maxImportBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid import file",
+			Details: err.Error(),
+		})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid import file",
+			Details: "The file contains no rows",
+		})
+		return
+	}
+
+	report, err := importer.New(db).Run(rows, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to import books",
+			Details: err.Error(),
+		})
+		return
+	}
+	report.Format = string(format)
+
+	var updated []uint
+	for _, row := range report.Rows {
+		if row.Status == importer.StatusUpdated {
+			updated = append(updated, row.BookID)
+		}
+	}
+	forgetBooks(updated)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importFormat picks the import format from the format query parameter, falling back
+// to the Content-Type of the request. ONIX has no media type of its own, so ONIX feeds
+// need format=onix.
+func importFormat(c *gin.Context) (importer.Format, bool) {
+	switch c.Query("format") {
+	case "csv":
+		return importer.FormatCSV, true
+	case "ndjson", "jsonl":
+		return importer.FormatNDJSON, true
+	case "onix":
+		return importer.FormatONIX, true
+	case "marc", "mrc":
+		return importer.FormatMARC, true
+	case "marcxml":
+		return importer.FormatMARCXML, true
+	case "":
+	default:
+		return "", false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return importer.FormatCSV, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/json":
+		return importer.FormatNDJSON, true
+	case "application/marc":
+		return importer.FormatMARC, true
+	case "application/marcxml+xml":
+		return importer.FormatMARCXML, true
+	}
+	return "", false
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/cache"
+	"go-rest-api-ozgur/internal/config"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dependentsError is returned when the restrict policy blocks a delete.
+type dependentsError struct {
+	Entity string
+	Count  int64
+}
+
+func (e *dependentsError) Error() string {
+	return fmt.Sprintf("still referenced by %d %s(s)", e.Count, e.Entity)
+}
+
+// referenceExists reports whether a live (not soft deleted) row with the given ID exists.
+func referenceExists(tx *gorm.DB, model interface{}, id uint) (bool, error) {
+	var count int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// respondMissingReference answers with 422 naming the entity that could not be found.
+func respondMissingReference(c *gin.Context, entity string, id uint) {
+	c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+		Code:    http.StatusUnprocessableEntity,
+		Message: "Referenced " + entity + " does not exist",
+		Details: fmt.Sprintf("There is no %s with ID %d", entity, id),
+	})
+}
+
+// respondDependents answers with 409 when the restrict policy refused a delete.
+func respondDependents(c *gin.Context, entity string, err *dependentsError) {
+	c.JSON(http.StatusConflict, ErrorResponse{
+		Code:    http.StatusConflict,
+		Message: "Cannot delete " + entity,
+		Details: err.Error(),
+	})
+}
+
+// deleteAuthor removes an author and, depending on the policy, the books they are the
+// only remaining contributor of together with those books' reviews. Co-authored books
+// survive and merely lose the author from their credits. Every book and review deleted
+// along with the author gets a revision by changedBy.
+func deleteAuthor(tx *gorm.DB, author *models.Author, policy config.DeletePolicy, changedBy string) error {
+	switch policy {
+	case config.DeleteCascade:
+		// Soft deleted books still hold the foreign key, so they have to go as well.
+		bookIDs, err := soleContributionBookIDs(tx.Unscoped(), author.ID)
+		if err != nil {
+			return err
+		}
+		forgetAuthorBooks(tx, author.ID)
+		if err := recordCascade(tx, bookIDs, true, changedBy); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("book_id IN ?", bookIDs).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("author_id = ? OR book_id IN ?", author.ID, bookIDs).Delete(&models.BookContributor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", bookIDs).Delete(&models.Book{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(author).Error
+
+	case config.DeleteSoftCascade:
+		bookIDs, err := soleContributionBookIDs(tx, author.ID)
+		if err != nil {
+			return err
+		}
+		forgetAuthorBooks(tx, author.ID)
+		if err := recordCascade(tx, bookIDs, true, changedBy); err != nil {
+			return err
+		}
+		cascade, err := randomID()
+		if err != nil {
+			return err
+		}
+		if err := tagCascade(tx, cascade, &models.Review{}, "book_id IN ?", bookIDs); err != nil {
+			return err
+		}
+		if err := tagCascade(tx, cascade, &models.Book{}, "id IN ?", bookIDs); err != nil {
+			return err
+		}
+		if err := tagCascade(tx, cascade, &models.Author{}, "id = ?", author.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("book_id IN ?", bookIDs).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", bookIDs).Delete(&models.Book{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(author).Error
+
+	default:
+		var count int64
+		err := tx.Model(&models.Book{}).
+			Where("id IN (SELECT book_id FROM book_contributors WHERE author_id = ?)", author.ID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return &dependentsError{Entity: "book", Count: count}
+		}
+		return tx.Delete(author).Error
+	}
+}
+
+// soleContributionBookIDs lists the books credited to the author that have no other live contributor.
+// Pass an unscoped session to include soft deleted books.
+func soleContributionBookIDs(tx *gorm.DB, authorID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.Book{}).
+		Where("id IN (SELECT book_id FROM book_contributors WHERE author_id = ?)", authorID).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM book_contributors bc
+			JOIN authors a ON a.id = bc.author_id AND a.deleted_at IS NULL
+			WHERE bc.book_id = books.id AND bc.author_id <> ?)`, authorID).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// forgetAuthorBooks drops the cache of every book the author was credited on, since their
+// contributor lists change even when the book itself survives.
+func forgetAuthorBooks(tx *gorm.DB, authorID uint) {
+	var ids []uint
+	tx.Model(&models.BookContributor{}).Where("author_id = ?", authorID).Pluck("book_id", &ids)
+	forgetBooks(ids)
+}
+
+// deleteBook removes a book and, depending on the policy, its reviews, each of which
+// gets a revision by changedBy. The revision of the book itself is left to the caller.
+func deleteBook(tx *gorm.DB, book *models.Book, policy config.DeletePolicy, changedBy string) error {
+	switch policy {
+	case config.DeleteCascade:
+		if err := recordCascade(tx, []uint{book.ID}, false, changedBy); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("book_id = ?", book.ID).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(book).Error; err != nil {
+			return err
+		}
+
+	case config.DeleteSoftCascade:
+		if err := recordCascade(tx, []uint{book.ID}, false, changedBy); err != nil {
+			return err
+		}
+		cascade, err := randomID()
+		if err != nil {
+			return err
+		}
+		if err := tagCascade(tx, cascade, &models.Review{}, "book_id = ?", book.ID); err != nil {
+			return err
+		}
+		if err := tagCascade(tx, cascade, &models.Book{}, "id = ?", book.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("book_id = ?", book.ID).Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(book).Error; err != nil {
+			return err
+		}
+
+	default:
+		var count int64
+		if err := tx.Model(&models.Review{}).Where("book_id = ?", book.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return &dependentsError{Entity: "review", Count: count}
+		}
+		if err := tx.Delete(book).Error; err != nil {
+			return err
+		}
+	}
+
+	forgetBooks([]uint{book.ID})
+	return nil
+}
+
+// recordCascade records the deletion of the live reviews of the books, and with books
+// set of the live books themselves, before a cascade removes them. Rows already in the
+// trash got their delete revision when they were deleted.
+func recordCascade(tx *gorm.DB, bookIDs []uint, books bool, changedBy string) error {
+	if len(bookIDs) == 0 {
+		return nil
+	}
+	var reviewIDs []uint
+	if err := tx.Model(&models.Review{}).Where("book_id IN ?", bookIDs).Pluck("id", &reviewIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range reviewIDs {
+		if err := recordRevision(tx, historyReview, id, models.RevisionDelete, changedBy); err != nil {
+			return err
+		}
+	}
+	if !books {
+		return nil
+	}
+	var liveBookIDs []uint
+	if err := tx.Model(&models.Book{}).Where("id IN ?", bookIDs).Pluck("id", &liveBookIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range liveBookIDs {
+		if err := recordRevision(tx, historyBook, id, models.RevisionDelete, changedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagCascade marks the live rows matching the condition as deleted by the cascade, right
+// before it soft deletes them. Rows already in the trash keep their own cascade.
+func tagCascade(tx *gorm.DB, cascade string, model interface{}, query string, args ...interface{}) error {
+	return tx.Model(model).Where(query, args...).UpdateColumn("cascade_id", cascade).Error
+}
+
+// forgetBooks drops cached book responses so deleted books are not served from Redis.
+func forgetBooks(ids []uint) {
+	for _, id := range ids {
+		cache.Delete(fmt.Sprintf("book:%d", id))
+	}
+}
@@ -0,0 +1,468 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	redirectAuthor = "author"
+	redirectBook   = "book"
+)
+
+// mergeError is returned for a merge request that names an invalid duplicate.
+type mergeError struct {
+	Message string
+}
+
+func (e *mergeError) Error() string {
+	return e.Message
+}
+
+// GetDuplicateAuthors godoc
+// @Summary Find duplicate authors
+// @Description List pairs of authors that probably are the same person: names that are equal once accents,
+// @Description case, spacing and punctuation are ignored, and, when pg_trgm is installed, names whose trigram
+// @Description similarity reaches the threshold.
+// @Tags duplicates
+// @Produce json
+// @Param threshold query number false "Minimum trigram similarity between 0.3 and 1, default 0.6"
+// @Param limit query int false "Maximum number of pairs, default 100"
+// @Success 200 {array} dto.DuplicateCandidate
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/duplicates/authors [get]
+func GetDuplicateAuthors(c *gin.Context) {
+	candidates, err := findDuplicateAuthors(duplicateThreshold(c.Query("threshold")), queryInt(c, "limit", 100, 1000))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to find duplicate authors",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, candidates)
+}
+
+// GetDuplicateBooks godoc
+// @Summary Find duplicate books
+// @Description List pairs of books that probably are the same edition: the same ISBN, or a shared author and
+// @Description a title that is equal after normalization or, when pg_trgm is installed, similar enough. Books
+// @Description with two different ISBNs are editions of a work and are not reported.
+// @Tags duplicates
+// @Produce json
+// @Param threshold query number false "Minimum trigram similarity between 0.3 and 1, default 0.6"
+// @Param limit query int false "Maximum number of pairs, default 100"
+// @Success 200 {array} dto.DuplicateCandidate
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/duplicates/books [get]
+func GetDuplicateBooks(c *gin.Context) {
+	candidates, err := findDuplicateBooks(duplicateThreshold(c.Query("threshold")), queryInt(c, "limit", 100, 1000))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to find duplicate books",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, candidates)
+}
+
+// MergeAuthors godoc
+// @Summary Merge duplicate authors
+// @Description Fold the duplicate authors into the author in the path in one transaction. Their book credits
+// @Description move to the survivor, its empty biography, date and nationality fields are filled from the
+// @Description duplicates, whose names become its aliases, and the duplicates are deleted. Requests for a merged
+// @Description ID are redirected to the survivor.
+// @Tags duplicates
+// @Accept json
+// @Produce json
+// @Param id path int true "Surviving author ID"
+// @Param merge body dto.MergeRequest true "Authors to merge"
+// @Success 200 {object} dto.MergeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/authors/{id}/merge [post]
+func MergeAuthors(c *gin.Context) {
+	var req dto.MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var survivor models.Author
+	if err := withAliases(db).First(&survivor, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Author not found",
+			Details: "The author with the given ID does not exist",
+		})
+		return
+	}
+
+	response := dto.MergeResponse{SurvivorID: survivor.ID}
+	var affected []uint
+	aliases := aliasNames(survivor.Aliases)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range req.DuplicateIDs {
+			if id == survivor.ID {
+				return &mergeError{Message: "An author cannot be merged into itself"}
+			}
+			var duplicate models.Author
+			if err := withAliases(tx).First(&duplicate, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return &mergeError{Message: fmt.Sprintf("There is no author with ID %d", id)}
+				}
+				return err
+			}
+
+			var bookIDs []uint
+			if err := tx.Model(&models.BookContributor{}).Where("author_id = ?", id).Pluck("book_id", &bookIDs).Error; err != nil {
+				return err
+			}
+			affected = append(affected, bookIDs...)
+
+			// A credit the survivor already holds in the same role would collide with the primary key.
+			err := tx.Exec(`
+				DELETE FROM book_contributors d
+				WHERE d.author_id = ? AND EXISTS (
+					SELECT 1 FROM book_contributors s
+					WHERE s.book_id = d.book_id AND s.role = d.role AND s.author_id = ?)`, id, survivor.ID).Error
+			if err != nil {
+				return err
+			}
+			moved := tx.Model(&models.BookContributor{}).Where("author_id = ?", id).Update("author_id", survivor.ID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			response.ContributionsMoved += moved.RowsAffected
+
+			if survivor.Biography == "" {
+				survivor.Biography = duplicate.Biography
+			}
+			if survivor.Born.Date == nil {
+				survivor.Born = duplicate.Born
+			}
+			if survivor.Died.Date == nil {
+				survivor.Died = duplicate.Died
+			}
+			if survivor.Nationality == "" {
+				survivor.Nationality = duplicate.Nationality
+			}
+			aliases = append(aliases, duplicate.Name)
+			aliases = append(aliases, aliasNames(duplicate.Aliases)...)
+			if err := recordRevision(tx, historyAuthor, id, models.RevisionMerge, actor(c)); err != nil {
+				return err
+			}
+			if err := tx.Delete(&duplicate).Error; err != nil {
+				return err
+			}
+			if err := leaveRedirect(tx, c, redirectAuthor, id, survivor.ID); err != nil {
+				return err
+			}
+			response.MergedIDs = append(response.MergedIDs, id)
+		}
+		err := tx.Model(&survivor).
+			Select("biography", "born_date", "born_precision", "died_date", "died_precision", "nationality").
+			Updates(&survivor).Error
+		if err != nil {
+			return err
+		}
+		kept := aliases[:0]
+		for _, alias := range aliases {
+			if !strings.EqualFold(alias, survivor.Name) {
+				kept = append(kept, alias)
+			}
+		}
+		if _, err := replaceAliases(tx, survivor.ID, kept); err != nil {
+			return err
+		}
+		// The books now credit the survivor, which makes them a new version.
+		if err := tx.Model(&models.Book{}).Where("id IN ?", affected).Update("updated_at", time.Now()).Error; err != nil {
+			return err
+		}
+		for _, bookID := range uniqueIDs(affected) {
+			if err := recordRevision(tx, historyBook, bookID, models.RevisionMerge, actor(c)); err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, historyAuthor, survivor.ID, models.RevisionMerge, actor(c))
+	})
+	if err != nil {
+		respondMergeError(c, "authors", err)
+		return
+	}
+	forgetBooks(affected)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MergeBooks godoc
+// @Summary Merge duplicate books
+// @Description Fold the duplicate books into the book in the path in one transaction. Reviews, contributors,
+// @Description genres and tags move to the survivor, its empty fields are filled from the duplicates, and the
+// @Description duplicates are deleted. Requests for a merged ID are redirected to the survivor.
+// @Tags duplicates
+// @Accept json
+// @Produce json
+// @Param id path int true "Surviving book ID"
+// @Param merge body dto.MergeRequest true "Books to merge"
+// @Success 200 {object} dto.MergeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/books/{id}/merge [post]
+func MergeBooks(c *gin.Context) {
+	var req dto.MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var survivor models.Book
+	if err := db.First(&survivor, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return
+	}
+
+	response := dto.MergeResponse{SurvivorID: survivor.ID}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range req.DuplicateIDs {
+			if id == survivor.ID {
+				return &mergeError{Message: "A book cannot be merged into itself"}
+			}
+			var duplicate models.Book
+			if err := tx.First(&duplicate, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return &mergeError{Message: fmt.Sprintf("There is no book with ID %d", id)}
+				}
+				return err
+			}
+
+			// A user who reviewed both books keeps their review of the survivor; the other
+			// one is deleted with the duplicate.
+			var doubleReviewIDs []uint
+			err := tx.Model(&models.Review{}).
+				Where("book_id = ? AND user_id IN (SELECT user_id FROM reviews WHERE book_id = ? AND deleted_at IS NULL)", id, survivor.ID).
+				Pluck("id", &doubleReviewIDs).Error
+			if err != nil {
+				return err
+			}
+			for _, reviewID := range doubleReviewIDs {
+				if err := recordRevision(tx, historyReview, reviewID, models.RevisionDelete, actor(c)); err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("id IN ?", doubleReviewIDs).Delete(&models.Review{}).Error; err != nil {
+				return err
+			}
+
+			var reviewIDs []uint
+			if err := tx.Unscoped().Model(&models.Review{}).Where("book_id = ?", id).Pluck("id", &reviewIDs).Error; err != nil {
+				return err
+			}
+			moved := tx.Unscoped().Model(&models.Review{}).Where("book_id = ?", id).Update("book_id", survivor.ID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			response.ReviewsMoved += moved.RowsAffected
+			for _, reviewID := range reviewIDs {
+				if err := recordRevision(tx, historyReview, reviewID, models.RevisionMerge, actor(c)); err != nil {
+					return err
+				}
+			}
+
+			// Credits of the duplicate are appended after the survivor's own.
+			inserted := tx.Exec(`
+				INSERT INTO book_contributors (book_id, author_id, role, position, created_at)
+				SELECT ?, d.author_id, d.role,
+					d.position + (SELECT COALESCE(MAX(s.position) + 1, 0) FROM book_contributors s WHERE s.book_id = ?),
+					d.created_at
+				FROM book_contributors d
+				WHERE d.book_id = ?
+				ON CONFLICT DO NOTHING`, survivor.ID, survivor.ID, id)
+			if inserted.Error != nil {
+				return inserted.Error
+			}
+			response.ContributionsMoved += inserted.RowsAffected
+			if err := tx.Where("book_id = ?", id).Delete(&models.BookContributor{}).Error; err != nil {
+				return err
+			}
+
+			for _, statement := range []string{
+				`INSERT INTO book_genres (book_id, genre_id) SELECT ?, genre_id FROM book_genres WHERE book_id = ? ON CONFLICT DO NOTHING`,
+				`INSERT INTO book_tags (book_id, tag_id) SELECT ?, tag_id FROM book_tags WHERE book_id = ? ON CONFLICT DO NOTHING`,
+			} {
+				if err := tx.Exec(statement, survivor.ID, id).Error; err != nil {
+					return err
+				}
+			}
+
+			fillEmptyBookFields(&survivor, &duplicate)
+			if survivor.CoverKey == duplicate.CoverKey && duplicate.CoverKey != "" {
+				// The survivor took the cover over; the duplicate must not delete it later.
+				if err := tx.Model(&models.Book{}).Where("id = ?", id).Update("cover_key", "").Error; err != nil {
+					return err
+				}
+			}
+			if err := recordRevision(tx, historyBook, id, models.RevisionMerge, actor(c)); err != nil {
+				return err
+			}
+			if err := tx.Delete(&duplicate).Error; err != nil {
+				return err
+			}
+			if err := leaveRedirect(tx, c, redirectBook, id, survivor.ID); err != nil {
+				return err
+			}
+			response.MergedIDs = append(response.MergedIDs, id)
+		}
+		err := tx.Model(&models.Book{}).Where("id = ?", survivor.ID).Updates(map[string]interface{}{
+			"isbn":             survivor.ISBN,
+			"publication_year": survivor.PublicationYear,
+			"description":      survivor.Description,
+			"format":           survivor.Format,
+			"work_id":          survivor.WorkID,
+			"publisher_id":     survivor.PublisherID,
+			"imprint_id":       survivor.ImprintID,
+			"page_count":       survivor.PageCount,
+			"language":         survivor.Language,
+			"cover_key":        survivor.CoverKey,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := refreshRatings(tx, append([]uint{survivor.ID}, response.MergedIDs...)...); err != nil {
+			return err
+		}
+		return recordRevision(tx, historyBook, survivor.ID, models.RevisionMerge, actor(c))
+	})
+	if err != nil {
+		respondMergeError(c, "books", err)
+		return
+	}
+	forgetBooks(append([]uint{survivor.ID}, response.MergedIDs...))
+
+	c.JSON(http.StatusOK, response)
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// fillEmptyBookFields copies the fields the survivor lacks from a duplicate.
+func fillEmptyBookFields(survivor, duplicate *models.Book) {
+	if survivor.ISBN == "" {
+		survivor.ISBN = duplicate.ISBN
+	}
+	if survivor.PublicationYear == 0 {
+		survivor.PublicationYear = duplicate.PublicationYear
+	}
+	if survivor.Description == "" {
+		survivor.Description = duplicate.Description
+	}
+	if survivor.Format == "" {
+		survivor.Format = duplicate.Format
+	}
+	if survivor.WorkID == nil {
+		survivor.WorkID = duplicate.WorkID
+	}
+	if survivor.PublisherID == nil {
+		survivor.PublisherID, survivor.ImprintID = duplicate.PublisherID, duplicate.ImprintID
+	}
+	if survivor.PageCount == 0 {
+		survivor.PageCount = duplicate.PageCount
+	}
+	if survivor.Language == "" {
+		survivor.Language = duplicate.Language
+	}
+	if survivor.CoverKey == "" {
+		survivor.CoverKey = duplicate.CoverKey
+	}
+}
+
+// leaveRedirect records that fromID was merged into toID. Redirects that pointed at
+// fromID are moved to toID so a chain of merges resolves in one hop.
+func leaveRedirect(tx *gorm.DB, c *gin.Context, entity string, fromID, toID uint) error {
+	err := tx.Model(&models.MergeRedirect{}).
+		Where("entity = ? AND to_id = ?", entity, fromID).
+		Update("to_id", toID).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.MergeRedirect{
+		Entity:   entity,
+		FromID:   fromID,
+		ToID:     toID,
+		MergedBy: c.GetString("username"),
+	}).Error
+}
+
+// redirectMerged answers with 301 to the survivor when the requested ID was merged away,
+// keeping the query string. It reports whether it did.
+func redirectMerged(c *gin.Context, entity, id string) bool {
+	fromID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return false
+	}
+	var redirect models.MergeRedirect
+	if err := db.Where("entity = ? AND from_id = ?", entity, fromID).First(&redirect).Error; err != nil {
+		return false
+	}
+	location := fmt.Sprintf("/api/v1/%ss/%d", entity, redirect.ToID)
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+	return true
+}
+
+func respondMergeError(c *gin.Context, entity string, err error) {
+	var invalid *mergeError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Cannot merge " + entity,
+			Details: invalid.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to merge " + entity,
+		Details: err.Error(),
+	})
+}
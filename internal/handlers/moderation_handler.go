@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// moderationStatuses maps the actions that set a status to the status they set.
+var moderationStatuses = map[string]string{
+	models.ModerationPublish: models.ReviewPublished,
+	models.ModerationHide:    models.ReviewHidden,
+	models.ModerationReject:  models.ReviewRejected,
+}
+
+// FlagReview godoc
+// @Summary Flag a review
+// @Description Report a review to the moderators as spam, abuse, a spoiler, off topic or for another reason
+// @Description explained in the note. The review joins the moderation queue until a moderator acts on it.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param flag body dto.FlagReviewRequest true "Reason for the flag"
+// @Success 201 {object} dto.FlagResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The user already flagged the review"
+// @Failure 422 {object} ErrorResponse "Users cannot flag their own reviews"
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reviews/{id}/flags [post]
+func FlagReview(c *gin.Context) {
+	var req dto.FlagReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+	userID := currentUserID(c)
+	if userID == 0 {
+		respondTokenWithoutUser(c)
+		return
+	}
+
+	var review models.Review
+	if err := publishedReviews(db).First(&review, c.Param("id")).Error; err != nil {
+		respondReviewNotFound(c)
+		return
+	}
+	if review.UserID != nil && *review.UserID == userID {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Cannot flag your own review",
+			Details: "Edit or delete the review instead",
+		})
+		return
+	}
+
+	flag := models.ReviewFlag{
+		ReviewID: review.ID,
+		UserID:   userID,
+		Reason:   req.Reason,
+		Note:     strings.TrimSpace(req.Note),
+	}
+	if err := db.Omit("User", "Review").Create(&flag).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "Review already flagged",
+				Details: "Your flag on this review is waiting for a moderator",
+			})
+			return
+		}
+		respondModerationError(c, "Failed to flag the review", err)
+		return
+	}
+
+	flag.User = models.User{Username: c.GetString("username")}
+	c.JSON(http.StatusCreated, toFlagResponse(flag))
+}
+
+// GetModerationQueue godoc
+// @Summary List reviews awaiting moderation
+// @Description List the reviews that are pending publication or have open flags, the most flagged first and
+// @Description then the oldest, each with its open flags.
+// @Tags moderation
+// @Produce json
+// @Param status query string false "Only reviews with this status: pending, published, hidden or rejected"
+// @Param reason query string false "Only reviews flagged for this reason"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Records per page (default 20, max 100)"
+// @Success 200 {array} dto.ModerationQueueItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/moderation/queue [get]
+func GetModerationQueue(c *gin.Context) {
+	query := db.Model(&models.Review{}).
+		Where("reviews.status = ? OR EXISTS (SELECT 1 FROM review_flags f WHERE f.review_id = reviews.id AND f.resolved_at IS NULL)", models.ReviewPending)
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.ReviewPending, models.ReviewPublished, models.ReviewHidden, models.ReviewRejected:
+			query = query.Where("reviews.status = ?", status)
+		default:
+			respondInvalidModerationFilter(c, "status takes pending, published, hidden or rejected")
+			return
+		}
+	}
+	if reason := c.Query("reason"); reason != "" {
+		switch reason {
+		case models.FlagSpam, models.FlagAbuse, models.FlagSpoiler, models.FlagOffTopic, models.FlagOther:
+			query = query.Where("EXISTS (SELECT 1 FROM review_flags f WHERE f.review_id = reviews.id AND f.resolved_at IS NULL AND f.reason = ?)", reason)
+		default:
+			respondInvalidModerationFilter(c, "reason takes spam, abuse, spoiler, off_topic or other")
+			return
+		}
+	}
+
+	paged, err := paginate(c, query, &models.Review{})
+	if err != nil {
+		respondModerationError(c, "Failed to read the moderation queue", err)
+		return
+	}
+	var reviews []models.Review
+	err = withReviewer(paged).
+		Order("(SELECT COUNT(*) FROM review_flags f WHERE f.review_id = reviews.id AND f.resolved_at IS NULL) DESC, reviews.created_at, reviews.id").
+		Find(&reviews).Error
+	if err != nil {
+		respondModerationError(c, "Failed to read the moderation queue", err)
+		return
+	}
+
+	ids := make([]uint, 0, len(reviews))
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+	}
+	var flags []models.ReviewFlag
+	if err := db.Preload("User").Where("review_id IN ? AND resolved_at IS NULL", ids).Order("created_at").Find(&flags).Error; err != nil {
+		respondModerationError(c, "Failed to read the moderation queue", err)
+		return
+	}
+	flagsByReview := map[uint][]dto.FlagResponse{}
+	for _, flag := range flags {
+		flagsByReview[flag.ReviewID] = append(flagsByReview[flag.ReviewID], toFlagResponse(flag))
+	}
+
+	queue := make([]dto.ModerationQueueItem, 0, len(reviews))
+	for _, review := range reviews {
+		item := dto.ModerationQueueItem{Review: toReviewResponse(review), Flags: flagsByReview[review.ID]}
+		if item.Flags == nil {
+			item.Flags = []dto.FlagResponse{}
+		}
+		queue = append(queue, item)
+	}
+	c.JSON(http.StatusOK, queue)
+}
+
+// ModerateReview godoc
+// @Summary Act on a review
+// @Description Publish, hide or reject a review, or dismiss its flags without changing it. Every action resolves
+// @Description the open flags on the review and is kept in its moderation history.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param action body dto.ModerateReviewRequest true "Action to take"
+// @Success 200 {object} dto.ModerationActionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/moderation/reviews/{id} [post]
+func ModerateReview(c *gin.Context) {
+	var req dto.ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var review models.Review
+	if err := db.First(&review, c.Param("id")).Error; err != nil {
+		respondReviewNotFound(c)
+		return
+	}
+
+	var action models.ModerationAction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		action, err = moderateReview(tx, review, req.Action, strings.TrimSpace(req.Note), actor(c))
+		return err
+	})
+	if err != nil {
+		respondModerationError(c, "Failed to moderate the review", err)
+		return
+	}
+	c.JSON(http.StatusOK, toModerationActionResponse(action))
+}
+
+// GetModerationHistory godoc
+// @Summary List the moderation history of a review
+// @Description List what moderators did to a review, oldest first.
+// @Tags moderation
+// @Produce json
+// @Param id path string true "Review ID"
+// @Success 200 {array} dto.ModerationActionResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/moderation/reviews/{id}/history [get]
+func GetModerationHistory(c *gin.Context) {
+	var review models.Review
+	if err := db.Unscoped().Select("id").First(&review, c.Param("id")).Error; err != nil {
+		respondReviewNotFound(c)
+		return
+	}
+
+	var actions []models.ModerationAction
+	if err := db.Where("review_id = ?", review.ID).Order("created_at, id").Find(&actions).Error; err != nil {
+		respondModerationError(c, "Failed to read the moderation history", err)
+		return
+	}
+	history := make([]dto.ModerationActionResponse, 0, len(actions))
+	for _, action := range actions {
+		history = append(history, toModerationActionResponse(action))
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// moderateReview applies a moderator action to a review, resolves its open flags and
+// records the action.
+func moderateReview(tx *gorm.DB, review models.Review, action, note, moderator string) (models.ModerationAction, error) {
+	record := models.ModerationAction{
+		ReviewID:   review.ID,
+		Action:     action,
+		FromStatus: review.Status,
+		ToStatus:   review.Status,
+		Note:       note,
+		Moderator:  moderator,
+	}
+	if status, ok := moderationStatuses[action]; ok && status != review.Status {
+		if err := tx.Model(&models.Review{}).Where("id = ?", review.ID).Update("status", status).Error; err != nil {
+			return record, err
+		}
+		if err := refreshRatings(tx, review.BookID); err != nil {
+			return record, err
+		}
+		record.ToStatus = status
+	}
+
+	resolved := tx.Model(&models.ReviewFlag{}).
+		Where("review_id = ? AND resolved_at IS NULL", review.ID).
+		Update("resolved_at", time.Now())
+	if resolved.Error != nil {
+		return record, resolved.Error
+	}
+	record.FlagsResolved = int(resolved.RowsAffected)
+	return record, tx.Omit("Review").Create(&record).Error
+}
+
+// initialReviewStatus is the status a new review by the user starts in: pending while
+// their account is younger than the pre-moderation period, published otherwise.
+func initialReviewStatus(c *gin.Context, userID uint) (string, error) {
+	if cfg.PremoderationDays <= 0 || isModerator(c) {
+		return models.ReviewPublished, nil
+	}
+	var user models.User
+	if err := db.Select("id", "created_at").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	if time.Since(user.CreatedAt) < time.Duration(cfg.PremoderationDays)*24*time.Hour {
+		return models.ReviewPending, nil
+	}
+	return models.ReviewPublished, nil
+}
+
+// publishedReviews limits a query to the reviews readers may see.
+func publishedReviews(query *gorm.DB) *gorm.DB {
+	return query.Where("reviews.status = ?", models.ReviewPublished)
+}
+
+func toFlagResponse(flag models.ReviewFlag) dto.FlagResponse {
+	return dto.FlagResponse{
+		ID:        flag.ID,
+		ReviewID:  flag.ReviewID,
+		Reason:    flag.Reason,
+		Note:      flag.Note,
+		FlaggedBy: flag.User.Username,
+		CreatedAt: flag.CreatedAt,
+	}
+}
+
+func toModerationActionResponse(action models.ModerationAction) dto.ModerationActionResponse {
+	return dto.ModerationActionResponse{
+		ID:            action.ID,
+		ReviewID:      action.ReviewID,
+		Action:        action.Action,
+		FromStatus:    action.FromStatus,
+		ToStatus:      action.ToStatus,
+		Note:          action.Note,
+		FlagsResolved: action.FlagsResolved,
+		Moderator:     action.Moderator,
+		CreatedAt:     action.CreatedAt,
+	}
+}
+
+func respondReviewNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{
+		Code:    http.StatusNotFound,
+		Message: "Review not found",
+		Details: "The review with the given ID does not exist",
+	})
+}
+
+func respondTokenWithoutUser(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, ErrorResponse{
+		Code:    http.StatusUnauthorized,
+		Message: "Sign in again",
+		Details: "The token does not name a user; log in again to get one that does",
+	})
+}
+
+func respondInvalidModerationFilter(c *gin.Context, details string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid filter",
+		Details: details,
+	})
+}
+
+func respondModerationError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+		Details: err.Error(),
+	})
+}
@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+const (
+	mediaMergePatch = "application/merge-patch+json"
+	mediaJSONPatch  = "application/json-patch+json"
+)
+
+// patchError is returned when a patch cannot be applied to the document. Conflict
+// marks a failed JSON Patch test, which means the record is not in the expected state.
+type patchError struct {
+	Message  string
+	Conflict bool
+}
+
+func (e *patchError) Error() string {
+	return e.Message
+}
+
+// patchOperation is one step of an RFC 6902 JSON Patch. Value is a json.RawMessage
+// rather than a pointer so that a null value can be told apart from a missing one.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// patchDocument reads the PATCH body and applies it to document, the current state of
+// the record in the shape of its create request. The result is decoded into req and
+// validated with the rules of that request, where a field counts as given when it is
+// present and not null, so required fields may be set to zero values but not removed.
+// It answers the request itself when it fails and reports whether it succeeded.
+func patchDocument(c *gin.Context, document interface{}, req interface{}) bool {
+	body, err := c.GetRawData()
+	if err != nil {
+		respondInvalidPatch(c, err.Error())
+		return false
+	}
+	var doc interface{}
+	data, err := json.Marshal(document)
+	if err == nil {
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Internal server error",
+			Details: err.Error(),
+		})
+		return false
+	}
+
+	switch c.ContentType() {
+	case mediaMergePatch:
+		var patch interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			respondInvalidPatch(c, err.Error())
+			return false
+		}
+		doc = mergePatch(doc, patch)
+	case mediaJSONPatch:
+		var operations []patchOperation
+		if err := json.Unmarshal(body, &operations); err != nil {
+			respondInvalidPatch(c, err.Error())
+			return false
+		}
+		doc, err = applyJSONPatch(doc, operations)
+		var failed *patchError
+		if errors.As(err, &failed) && failed.Conflict {
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "Patch test failed",
+				Details: failed.Message,
+			})
+			return false
+		}
+		if errors.As(err, &failed) {
+			respondUnprocessablePatch(c, failed.Message)
+			return false
+		}
+		if err != nil {
+			respondInvalidPatch(c, err.Error())
+			return false
+		}
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Unsupported patch format",
+			Details: "Send the patch as " + mediaMergePatch + " or " + mediaJSONPatch,
+		})
+		return false
+	}
+
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		respondUnprocessablePatch(c, "The patched document must be a JSON object")
+		return false
+	}
+	data, err = json.Marshal(object)
+	if err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(req)
+	}
+	if err != nil {
+		respondUnprocessablePatch(c, err.Error())
+		return false
+	}
+	if err := validatePatched(req, object); err != nil {
+		respondUnprocessablePatch(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// validatePatched runs the binding rules of req, ignoring required on top-level fields
+// that the patched document still carries with a non-null value.
+func validatePatched(req interface{}, document map[string]interface{}) error {
+	err := binding.Validator.ValidateStruct(req)
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	reqType := reflect.TypeOf(req)
+	for reqType.Kind() == reflect.Ptr {
+		reqType = reqType.Elem()
+	}
+	var messages []string
+	for _, fieldErr := range invalid {
+		if fieldErr.Tag() == "required" && strings.Count(fieldErr.StructNamespace(), ".") == 1 {
+			if field, ok := reqType.FieldByName(fieldErr.StructField()); ok {
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if value, present := document[name]; present && value != nil {
+					continue
+				}
+			}
+		}
+		messages = append(messages, fieldErr.Error())
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(messages, "\n"))
+}
+
+// mergePatch applies an RFC 7396 JSON Merge Patch: objects are merged key by key, null
+// removes a key and any other value replaces what was there.
+func mergePatch(target, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = mergePatch(object[name], value)
+	}
+	return object
+}
+
+// applyJSONPatch applies the operations of an RFC 6902 JSON Patch in order. Malformed
+// operations are reported as plain errors, operations that do not fit the document as
+// *patchError.
+func applyJSONPatch(doc interface{}, operations []patchOperation) (interface{}, error) {
+	for i, operation := range operations {
+		if operation.Path == nil {
+			return nil, fmt.Errorf("operation %d has no path", i)
+		}
+		path, err := parseJSONPointer(*operation.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		var value interface{}
+		switch operation.Op {
+		case "add", "replace", "test":
+			if len(operation.Value) == 0 {
+				return nil, fmt.Errorf("operation %d (%s) has no value", i, operation.Op)
+			}
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		var from []string
+		switch operation.Op {
+		case "move", "copy":
+			if operation.From == nil {
+				return nil, fmt.Errorf("operation %d (%s) has no from", i, operation.Op)
+			}
+			if from, err = parseJSONPointer(*operation.From); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+
+		switch operation.Op {
+		case "add":
+			doc, err = setPointer(doc, path, value, false)
+		case "replace":
+			doc, err = setPointer(doc, path, value, true)
+		case "remove":
+			doc, _, err = removePointer(doc, path)
+		case "move":
+			if strings.HasPrefix(*operation.Path+"/", *operation.From+"/") && *operation.Path != *operation.From {
+				err = &patchError{Message: "a value cannot be moved into itself"}
+				break
+			}
+			var moved interface{}
+			if doc, moved, err = removePointer(doc, from); err == nil {
+				doc, err = setPointer(doc, path, moved, false)
+			}
+		case "copy":
+			var copied interface{}
+			if copied, err = getPointer(doc, from); err == nil {
+				if copied, err = deepCopyJSON(copied); err == nil {
+					doc, err = setPointer(doc, path, copied, false)
+				}
+			}
+		case "test":
+			var current interface{}
+			if current, err = getPointer(doc, path); err == nil && !jsonEqual(current, value) {
+				return nil, &patchError{Message: fmt.Sprintf("%s is not %s", *operation.Path, operation.Value), Conflict: true}
+			}
+		default:
+			return nil, fmt.Errorf("operation %d has an unknown op %q", i, operation.Op)
+		}
+		var failed *patchError
+		if errors.As(err, &failed) {
+			return nil, &patchError{Message: fmt.Sprintf("operation %d (%s %s): %s", i, operation.Op, *operation.Path, failed.Message)}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q is not a JSON Pointer", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex reads a JSON Pointer token as an index into an array of the given length.
+// Indices up to limit are accepted.
+func arrayIndex(token string, limit int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, &patchError{Message: fmt.Sprintf("%q is not an array index", token)}
+	}
+	if index > limit {
+		return 0, &patchError{Message: fmt.Sprintf("index %d is out of range", index)}
+	}
+	return index, nil
+}
+
+func getPointer(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, errMissingPath
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, errMissingPath
+		}
+	}
+	return doc, nil
+}
+
+// setPointer adds value at path or, with replace, overwrites the value that is there.
+// Adding to an array inserts before the index, or appends for "-".
+func setPointer(doc interface{}, path []string, value interface{}, replace bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		current, ok := node[token]
+		if len(rest) == 0 {
+			if replace && !ok {
+				return nil, errMissingPath
+			}
+			node[token] = value
+			return node, nil
+		}
+		if !ok {
+			return nil, errMissingPath
+		}
+		updated, err := setPointer(current, rest, value, replace)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = updated
+		return node, nil
+	case []interface{}:
+		if len(rest) == 0 && !replace {
+			index := len(node)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			node[index] = value
+			return node, nil
+		}
+		updated, err := setPointer(node[index], rest, value, replace)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	}
+	return nil, errMissingPath
+}
+
+// removePointer removes the value at path and returns it.
+func removePointer(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, &patchError{Message: "the whole document cannot be removed"}
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		current, ok := node[token]
+		if !ok {
+			return nil, nil, errMissingPath
+		}
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, current, nil
+		}
+		updated, removed, err := removePointer(current, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = updated
+		return node, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[index]
+			return append(node[:index], node[index+1:]...), removed, nil
+		}
+		updated, removed, err := removePointer(node[index], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[index] = updated
+		return node, removed, nil
+	}
+	return nil, nil, errMissingPath
+}
+
+var errMissingPath = &patchError{Message: "the path does not exist"}
+
+func deepCopyJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	return copied, json.Unmarshal(data, &copied)
+}
+
+// commitPatch writes the patched snapshot of a record and records the change, guarded
+// by If-Match like a PUT. A non-nil after runs in the same transaction once the snapshot
+// is written, before the revision is recorded. It answers the request itself when it fails.
+func commitPatch(c *gin.Context, entity string, model interface{}, id uint, readAt time.Time, snapshot interface{}, after func(tx *gorm.DB) error) bool {
+	data, err := json.Marshal(snapshot)
+	if err == nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := guardVersion(c, tx, model, id, readAt); err != nil {
+				return err
+			}
+			if err := applySnapshot(tx, entity, id, string(data)); err != nil {
+				return err
+			}
+			if after != nil {
+				if err := after(tx); err != nil {
+					return err
+				}
+			}
+			return recordRevision(tx, entity, id, models.RevisionUpdate, actor(c))
+		})
+	}
+
+	var missing *missingReferenceError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &missing):
+		respondMissingReference(c, missing.Entity, missing.ID)
+	case errors.Is(err, errDuplicateContributor):
+		respondUnprocessablePatch(c, err.Error())
+	case errors.Is(err, errPreconditionFailed):
+		respondPreconditionFailed(c, entity, "")
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update " + entity,
+			Details: err.Error(),
+		})
+	}
+	return false
+}
+
+func respondInvalidPatch(c *gin.Context, details string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid patch document",
+		Details: details,
+	})
+}
+
+func respondUnprocessablePatch(c *gin.Context, details string) {
+	c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+		Code:    http.StatusUnprocessableEntity,
+		Message: "Cannot apply patch",
+		Details: details,
+	})
+}
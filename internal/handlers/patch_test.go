@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-rest-api-ozgur/internal/dto"
+)
+
+func decodeJSON(t *testing.T, document string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatalf("decoding %s: %v", document, err)
+	}
+	return value
+}
+
+func TestParseJSONPointer(t *testing.T) {
+	for _, tc := range []struct {
+		pointer string
+		want    []string
+	}{
+		{"", []string{}},
+		{"/", []string{""}},
+		{"/title", []string{"title"}},
+		{"/contributors/0/role", []string{"contributors", "0", "role"}},
+		{"/a~1b", []string{"a/b"}},
+		{"/m~0n", []string{"m~n"}},
+		// ~1 is unescaped after ~0 would have been, so ~01 is a literal ~1.
+		{"/~01", []string{"~1"}},
+		{"/~10", []string{"/0"}},
+	} {
+		got, err := parseJSONPointer(tc.pointer)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseJSONPointer(%q) = %q, %v; want %q", tc.pointer, got, err, tc.want)
+		}
+	}
+	if _, err := parseJSONPointer("title"); err == nil {
+		t.Error("parseJSONPointer accepted a pointer without a leading slash")
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		doc        string
+		operations string
+		want       string
+		// fails is "" for success, "conflict" for a failed test, "unprocessable" for an
+		// operation that does not fit the document and "invalid" for a malformed one.
+		fails string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, ""},
+		{"add replaces a member", `{"a":1}`, `[{"op":"add","path":"/a","value":null}]`, `{"a":null}`, ""},
+		{"add escaped slash", `{}`, `[{"op":"add","path":"/a~1b","value":1}]`, `{"a/b":1}`, ""},
+		{"add escaped tilde", `{}`, `[{"op":"add","path":"/m~0n","value":1}]`, `{"m~n":1}`, ""},
+		{"add inserts into an array", `{"l":[1,3]}`, `[{"op":"add","path":"/l/1","value":2}]`, `{"l":[1,2,3]}`, ""},
+		{"add appends with -", `{"l":[1]}`, `[{"op":"add","path":"/l/-","value":2}]`, `{"l":[1,2]}`, ""},
+		{"add at the array length", `{"l":[1]}`, `[{"op":"add","path":"/l/1","value":2}]`, `{"l":[1,2]}`, ""},
+		{"add past the array end", `{"l":[1]}`, `[{"op":"add","path":"/l/2","value":2}]`, "", "unprocessable"},
+		{"add with a leading zero", `{"l":[1,2]}`, `[{"op":"add","path":"/l/01","value":2}]`, "", "unprocessable"},
+		{"add under a missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, "", "unprocessable"},
+		{"replace", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`, ""},
+		{"replace the whole document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`, ""},
+		{"replace missing member", `{}`, `[{"op":"replace","path":"/a","value":1}]`, "", "unprocessable"},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`, ""},
+		{"remove array element", `{"l":[1,2,3]}`, `[{"op":"remove","path":"/l/1"}]`, `{"l":[1,3]}`, ""},
+		{"remove missing member", `{}`, `[{"op":"remove","path":"/a"}]`, "", "unprocessable"},
+		{"remove the whole document", `{}`, `[{"op":"remove","path":""}]`, "", "unprocessable"},
+		{"move", `{"a":{"x":1},"b":{}}`, `[{"op":"move","from":"/a/x","path":"/b/x"}]`, `{"a":{},"b":{"x":1}}`, ""},
+		{"move within an array", `{"l":[1,2,3]}`, `[{"op":"move","from":"/l/0","path":"/l/-"}]`, `{"l":[2,3,1]}`, ""},
+		{"move to itself", `{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`, ""},
+		{"move into itself", `{"a":{"x":1}}`, `[{"op":"move","from":"/a","path":"/a/x"}]`, "", "unprocessable"},
+		{"move to a sibling with a common prefix", `{"a":1}`, `[{"op":"move","from":"/a","path":"/ab"}]`, `{"ab":1}`, ""},
+		{"move missing member", `{}`, `[{"op":"move","from":"/a","path":"/b"}]`, "", "unprocessable"},
+		{"copy is deep", `{"a":{"x":1}}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"/b/x","value":2}]`, `{"a":{"x":1},"b":{"x":2}}`, ""},
+		{"copy missing member", `{}`, `[{"op":"copy","from":"/a","path":"/b"}]`, "", "unprocessable"},
+		{"test passes", `{"a":[1,{"b":"c"}]}`, `[{"op":"test","path":"/a","value":[1,{"b":"c"}]},{"op":"remove","path":"/a"}]`, `{}`, ""},
+		{"test compares numbers by value", `{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`, `{"a":1}`, ""},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null}]`, `{"a":null}`, ""},
+		{"test fails", `{"a":1}`, `[{"op":"test","path":"/a","value":2},{"op":"remove","path":"/a"}]`, "", "conflict"},
+		{"test missing member", `{}`, `[{"op":"test","path":"/a","value":null}]`, "", "unprocessable"},
+		{"no path", `{}`, `[{"op":"remove"}]`, "", "invalid"},
+		{"path without slash", `{}`, `[{"op":"add","path":"a","value":1}]`, "", "invalid"},
+		{"no value", `{}`, `[{"op":"add","path":"/a"}]`, "", "invalid"},
+		{"no from", `{"a":1}`, `[{"op":"move","path":"/b"}]`, "", "invalid"},
+		{"unknown op", `{}`, `[{"op":"increment","path":"/a","value":1}]`, "", "invalid"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var operations []patchOperation
+			if err := json.Unmarshal([]byte(tc.operations), &operations); err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyJSONPatch(decodeJSON(t, tc.doc), operations)
+
+			fails := ""
+			var failed *patchError
+			switch {
+			case errors.As(err, &failed) && failed.Conflict:
+				fails = "conflict"
+			case errors.As(err, &failed):
+				fails = "unprocessable"
+			case err != nil:
+				fails = "invalid"
+			}
+			if fails != tc.fails {
+				t.Fatalf("applyJSONPatch returned %v, %v; want failure %q", got, err, tc.fails)
+			}
+			if tc.fails == "" && !reflect.DeepEqual(got, decodeJSON(t, tc.want)) {
+				t.Errorf("applyJSONPatch returned %v, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7396, appendix A.
+	for _, tc := range []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		got := mergePatch(decodeJSON(t, tc.target), decodeJSON(t, tc.patch))
+		if !reflect.DeepEqual(got, decodeJSON(t, tc.want)) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tc.target, tc.patch, got, tc.want)
+		}
+	}
+}
+
+func TestValidatePatched(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		document string
+		// want is a field named in the error, or "" when the document is valid.
+		want string
+	}{
+		{"complete", `{"name":"Ursula K. Le Guin","biography":"Wrote Earthsea.","birth_date":"1929-10-21"}`, ""},
+		{"required field set to its zero value", `{"name":"Ursula K. Le Guin","biography":"","birth_date":"1929"}`, ""},
+		{"required field null", `{"name":"Ursula K. Le Guin","biography":null,"birth_date":"1929"}`, "Biography"},
+		{"required field removed", `{"name":"Ursula K. Le Guin","birth_date":"1929"}`, "Biography"},
+		{"other rules still apply", `{"name":"Ursula K. Le Guin","biography":"","birth_date":"1929","nationality":"` + strings.Repeat("x", 101) + `"}`, "Nationality"},
+		{"required inside a list still applies", `{"name":"Ursula K. Le Guin","biography":"","birth_date":"1929","aliases":[""]}`, "Aliases[0]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			document := decodeJSON(t, tc.document).(map[string]interface{})
+			var req dto.CreateAuthorRequest
+			if err := json.Unmarshal([]byte(tc.document), &req); err != nil {
+				t.Fatal(err)
+			}
+			err := validatePatched(&req, document)
+			switch {
+			case tc.want == "" && err != nil:
+				t.Errorf("validatePatched rejected the document: %v", err)
+			case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+				t.Errorf("validatePatched returned %v, want an error about %s", err, tc.want)
+			}
+		})
+	}
+}
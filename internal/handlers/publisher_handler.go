@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"errors"
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreatePublisher godoc
+// @Summary Create a new publisher
+// @Description Create a new publisher with the input payload
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param publisher body dto.CreatePublisherRequest true "Create publisher"
+// @Success 201 {object} dto.PublisherResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers [post]
+func CreatePublisher(c *gin.Context) {
+	var req dto.CreatePublisherRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	publisher := models.Publisher{
+		Name:    req.Name,
+		Website: req.Website,
+		Country: req.Country,
+	}
+
+	if err := db.Create(&publisher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create publisher", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toPublisherResponse(publisher))
+}
+
+// GetPublishers godoc
+// @Summary Get all publishers
+// @Description Get a list of all publishers with their imprints
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Success 200 {array} dto.PublisherResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers [get]
+func GetPublishers(c *gin.Context) {
+	var publishers []models.Publisher
+
+	if err := withImprints(db).Order("name").Find(&publishers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publishers", "details": err.Error()})
+		return
+	}
+
+	response := make([]dto.PublisherResponse, 0, len(publishers))
+	for _, publisher := range publishers {
+		response = append(response, toPublisherResponse(publisher))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPublisher godoc
+// @Summary Get specific publisher info
+// @Description Get a publisher by given id
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Success 200 {object} dto.PublisherResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/publishers/{id} [get]
+func GetPublisher(c *gin.Context) {
+	id := c.Param("id")
+
+	var publisher models.Publisher
+	if err := withImprints(db).First(&publisher, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no publisher with such id"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toPublisherResponse(publisher))
+}
+
+// UpdatePublisher godoc
+// @Summary Update a publisher
+// @Description Update a publisher with the input payload
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param publisher body dto.UpdatePublisherRequest true "Update publisher"
+// @Success 200 {object} dto.PublisherResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers/{id} [put]
+func UpdatePublisher(c *gin.Context) {
+	id := c.Param("id")
+
+	var req dto.UpdatePublisherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	var publisher models.Publisher
+	if err := withImprints(db).First(&publisher, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no publisher with such id"})
+		return
+	}
+
+	if req.Name != "" {
+		publisher.Name = req.Name
+	}
+	if req.Website != "" {
+		publisher.Website = req.Website
+	}
+	if req.Country != "" {
+		publisher.Country = req.Country
+	}
+
+	if err := db.Omit("Imprints").Save(&publisher).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update publisher", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toPublisherResponse(publisher))
+}
+
+// DeletePublisher godoc
+// @Summary Delete a publisher
+// @Description Delete a publisher and its imprints. Publishers that still have books cannot be deleted.
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers/{id} [delete]
+func DeletePublisher(c *gin.Context) {
+	id := c.Param("id")
+
+	var publisher models.Publisher
+	if err := db.First(&publisher, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no publisher with such id"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Book{}).Where("publisher_id = ?", publisher.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return &dependentsError{Entity: "book", Count: count}
+		}
+		if err := tx.Where("publisher_id = ?", publisher.ID).Delete(&models.Imprint{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&publisher).Error
+	})
+	var dependents *dependentsError
+	if errors.As(err, &dependents) {
+		respondDependents(c, "publisher", dependents)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete publisher", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Publisher deleted"})
+}
+
+// GetPublisherBooks godoc
+// @Summary Get the books of a publisher
+// @Description Get the books released by a publisher under any of its imprints
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Books per page (default 20, max 100)"
+// @Param format query string false "json (default), bibtex, ris, csl-json or marcxml"
+// @Param include query string false "Related resources to embed: authors"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
+// @Success 200 {array} dto.BookResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/publishers/{id}/books [get]
+func GetPublisherBooks(c *gin.Context) {
+	id := c.Param("id")
+
+	var publisher models.Publisher
+	if err := db.First(&publisher, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no publisher with such id"})
+		return
+	}
+
+	listBooks(c, db.Model(&models.Book{}).Where("publisher_id = ?", publisher.ID), "books.id", "This publisher has no books")
+}
+
+// CreateImprint godoc
+// @Summary Add an imprint to a publisher
+// @Description Create a new imprint under the given publisher
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param imprint body dto.ImprintRequest true "Create imprint"
+// @Success 201 {object} dto.ImprintResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers/{id}/imprints [post]
+func CreateImprint(c *gin.Context) {
+	id := c.Param("id")
+
+	var req dto.ImprintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	var publisher models.Publisher
+	if err := db.First(&publisher, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no publisher with such id"})
+		return
+	}
+
+	imprint := models.Imprint{PublisherID: publisher.ID, Name: req.Name}
+	if err := db.Create(&imprint).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create imprint", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toImprintResponse(imprint))
+}
+
+// UpdateImprint godoc
+// @Summary Rename an imprint
+// @Description Update an imprint of the given publisher
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param imprint_id path string true "Imprint ID"
+// @Param imprint body dto.ImprintRequest true "Update imprint"
+// @Success 200 {object} dto.ImprintResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers/{id}/imprints/{imprint_id} [put]
+func UpdateImprint(c *gin.Context) {
+	var req dto.ImprintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	var imprint models.Imprint
+	if err := db.Where("publisher_id = ?", c.Param("id")).First(&imprint, c.Param("imprint_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no imprint with such id for this publisher"})
+		return
+	}
+
+	imprint.Name = req.Name
+	if err := db.Save(&imprint).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update imprint", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toImprintResponse(imprint))
+}
+
+// DeleteImprint godoc
+// @Summary Delete an imprint
+// @Description Delete an imprint of the given publisher. Its books stay with the publisher.
+// @Tags publishers
+// @Accept json
+// @Produce json
+// @Param id path string true "Publisher ID"
+// @Param imprint_id path string true "Imprint ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/publishers/{id}/imprints/{imprint_id} [delete]
+func DeleteImprint(c *gin.Context) {
+	var imprint models.Imprint
+	if err := db.Where("publisher_id = ?", c.Param("id")).First(&imprint, c.Param("imprint_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "There is no imprint with such id for this publisher"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Book{}).Where("imprint_id = ?", imprint.ID).Update("imprint_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&imprint).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete imprint", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Imprint deleted"})
+}
+
+// resolvePublisher checks the publisher and imprint given for a book, responding with
+// 422 when either is missing or the imprint belongs to another publisher. When only the
+// imprint is given the publisher is taken from it.
+func resolvePublisher(c *gin.Context, publisherID, imprintID *uint) (*uint, *uint, bool) {
+	if imprintID != nil {
+		var imprint models.Imprint
+		if err := db.First(&imprint, *imprintID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				respondMissingReference(c, "imprint", *imprintID)
+			} else {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{
+					Code:    http.StatusInternalServerError,
+					Message: "Internal server error",
+					Details: err.Error(),
+				})
+			}
+			return nil, nil, false
+		}
+		if publisherID != nil && *publisherID != imprint.PublisherID {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Imprint does not belong to the publisher",
+				Details: "Imprint " + strconv.FormatUint(uint64(imprint.ID), 10) + " belongs to publisher " + strconv.FormatUint(uint64(imprint.PublisherID), 10),
+			})
+			return nil, nil, false
+		}
+		publisherID = &imprint.PublisherID
+	}
+
+	if publisherID != nil {
+		if exists, err := referenceExists(db, &models.Publisher{}, *publisherID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Internal server error",
+				Details: err.Error(),
+			})
+			return nil, nil, false
+		} else if !exists {
+			respondMissingReference(c, "publisher", *publisherID)
+			return nil, nil, false
+		}
+	}
+
+	return publisherID, imprintID, true
+}
+
+// withImprints preloads the imprints of a publisher by name.
+func withImprints(query *gorm.DB) *gorm.DB {
+	return query.Preload("Imprints", func(db *gorm.DB) *gorm.DB { return db.Order("name") })
+}
+
+func toPublisherResponse(publisher models.Publisher) dto.PublisherResponse {
+	imprints := make([]dto.ImprintResponse, 0, len(publisher.Imprints))
+	for _, imprint := range publisher.Imprints {
+		imprints = append(imprints, toImprintResponse(imprint))
+	}
+
+	return dto.PublisherResponse{
+		ID:       publisher.ID,
+		Name:     publisher.Name,
+		Website:  publisher.Website,
+		Country:  publisher.Country,
+		Imprints: imprints,
+	}
+}
+
+func toImprintResponse(imprint models.Imprint) dto.ImprintResponse {
+	return dto.ImprintResponse{
+		ID:          imprint.ID,
+		PublisherID: imprint.PublisherID,
+		Name:        imprint.Name,
+	}
+}
+
+func toPublisherSummary(publisher *models.Publisher) *dto.PublisherSummary {
+	if publisher == nil {
+		return nil
+	}
+	return &dto.PublisherSummary{ID: publisher.ID, Name: publisher.Name}
+}
+
+func toImprintSummary(imprint *models.Imprint) *dto.ImprintSummary {
+	if imprint == nil {
+		return nil
+	}
+	return &dto.ImprintSummary{ID: imprint.ID, Name: imprint.Name}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// queryInt reads a positive integer query parameter, falling back to def when it is
+// missing or invalid and capping it at max.
+func queryInt(c *gin.Context, key string, def, max int) int {
+	value, err := strconv.Atoi(c.Query(key))
+	if err != nil || value < 1 {
+		return def
+	}
+	if max > 0 && value > max {
+		return max
+	}
+	return value
+}
+
+// escapeLike escapes the LIKE wildcards in user input.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// paginate applies the page and page_size query parameters to a query, counting the
+// matching rows first. The totals are reported through X-Total-Count, X-Page and
+// X-Page-Size so list responses can stay plain arrays.
+func paginate(c *gin.Context, query *gorm.DB, model interface{}) (*gorm.DB, error) {
+	page := queryInt(c, "page", 1, 0)
+	size := queryInt(c, "page_size", defaultPageSize, maxPageSize)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(model).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.Header("X-Page", strconv.Itoa(page))
+	c.Header("X-Page-Size", strconv.Itoa(size))
+	return query.Offset((page - 1) * size).Limit(size), nil
+}
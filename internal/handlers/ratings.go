@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+	"go-rest-api-ozgur/internal/ratings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ratingColumns hold the aggregates kept by the ratings package. Saving a book leaves
+// them out, so a review written meanwhile is not undone by the numbers it was read with.
+var ratingColumns = []string{
+	"rating_count", "rating_average", "rating_score",
+	"rating_stars1", "rating_stars2", "rating_stars3", "rating_stars4", "rating_stars5",
+}
+
+// bookOrders are the orders books can be listed in, by the value of sort. Ties are
+// broken by ID.
+var bookOrders = map[string]string{
+	"id":      "books.id",
+	"title":   "books.title, books.id",
+	"rating":  "books.rating_score DESC, books.rating_count DESC, books.id",
+	"average": "books.rating_average DESC, books.rating_count DESC, books.id",
+	"ratings": "books.rating_count DESC, books.rating_score DESC, books.id",
+}
+
+// refreshRatings recomputes the rating aggregates of the books in tx and drops them
+// from the cache. Call it whenever reviews are written, moved or change status.
+func refreshRatings(tx *gorm.DB, bookIDs ...uint) error {
+	if err := ratings.Refresh(tx, ratings.PriorFromConfig(cfg), bookIDs...); err != nil {
+		return err
+	}
+	forgetBooks(bookIDs)
+	return nil
+}
+
+func toRatingSummary(rating models.BookRating) dto.RatingSummary {
+	summary := dto.RatingSummary{
+		Count: rating.Count,
+		Score: rating.Score,
+		Histogram: map[string]int{
+			"1": rating.Stars1,
+			"2": rating.Stars2,
+			"3": rating.Stars3,
+			"4": rating.Stars4,
+			"5": rating.Stars5,
+		},
+	}
+	if rating.Count > 0 {
+		average := rating.Average
+		summary.Average = &average
+	}
+	return summary
+}
+
+// bookOrder reads the sort parameter of a book list, defaulting to id. It answers 400
+// itself for an unknown order.
+func bookOrder(c *gin.Context) (string, bool) {
+	name := c.DefaultQuery("sort", "id")
+	order, ok := bookOrders[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Cannot sort by %q", name),
+			Details: "sort accepts id, title, rating, average and ratings",
+		})
+	}
+	return order, ok
+}
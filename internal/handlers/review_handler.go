@@ -1,29 +1,55 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"go-rest-api-ozgur/internal/contentfilter"
 	"go-rest-api-ozgur/internal/dto"
 	"go-rest-api-ozgur/internal/models"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetReviewsForBook godoc
 // @Summary Get reviews for a specific book
-// @Description Get a list of reviews for a book by its ID
+// @Description Get a page of the published reviews of a book by its ID. sort=helpful, the default, puts the
+// @Description reviews readers found most helpful first, ranked by the Wilson score of their votes so a review
+// @Description with few votes does not outrank one with many; newest, highest and lowest sort by date or rating.
 // @Tags reviews
 // @Accept json
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param sort query string false "helpful, newest, highest or lowest"
+// @Param include query string false "Related resources to embed: book"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Records per page (default 20, max 100)"
 // @Success 200 {array} dto.ReviewResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books/{id}/reviews [get]
 func GetReviewsForBook(c *gin.Context) {
 	bookID := c.Param("id")
+	options, ok := parseReadOptions(c, dto.ReviewResponse{}, "book")
+	if !ok {
+		return
+	}
+	order, ok := reviewOrder(c, "helpful")
+	if !ok {
+		return
+	}
 
+	query := publishedReviews(db.Model(&models.Review{})).Where("book_id = ?", bookID)
+	paged, err := paginate(c, query, &models.Review{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews for the book", "We honestly dont know why": err.Error()})
+		return
+	}
 	var reviews []models.Review
-	if err := db.Where("book_id = ?", bookID).Find(&reviews).Error; err != nil {
+	if err := withReviewer(paged).Order(order).Find(&reviews).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews for the book", "We honestly dont know why": err.Error()})
 		return
 	}
@@ -32,15 +58,10 @@ func GetReviewsForBook(c *gin.Context) {
 		return
 	}
 
-	var response []dto.ReviewResponse
-	for _, review := range reviews {
-		response = append(response, dto.ReviewResponse{
-			ID:         review.ID,
-			Rating:     review.Rating,
-			Comment:    review.Comment,
-			DatePosted: review.DatePosted,
-			BookID:     review.BookID,
-		})
+	response, err := shapeReviews(options, reviews)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews for the book", "We honestly dont know why": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -48,13 +69,21 @@ func GetReviewsForBook(c *gin.Context) {
 
 // CreateReview godoc
 // @Summary Create a new review
-// @Description Create a new review for a book
+// @Description Review the book in the path as the signed in user. Each user reviews a book once; to change a
+// @Description review, update it instead. Reviews by accounts younger than PREMODERATION_DAYS start out pending
+// @Description and are only shown once a moderator publishes them. The content filters may also hold a review as
+// @Description pending or refuse it with 422.
 // @Tags reviews
 // @Accept json
 // @Produce json
+// @Param id path string true "Book ID"
 // @Param review body dto.CreateReviewRequest true "Create review"
 // @Success 201 {object} dto.ReviewResponse
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The user already reviewed the book"
+// @Failure 422 {object} ErrorResponse "The content filters refused the review"
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/books/{id}/reviews [post]
 func CreateReview(c *gin.Context) {
@@ -68,38 +97,109 @@ func CreateReview(c *gin.Context) {
 		return
 	}
 
+	userID := currentUserID(c)
+	if userID == 0 {
+		respondTokenWithoutUser(c)
+		return
+	}
+
+	var book models.Book
+	if err := db.Select("id").First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return
+	}
+
+	var existing models.Review
+	err := db.Select("id").Where("book_id = ? AND user_id = ?", book.ID, userID).Limit(1).Find(&existing).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review", "Maybe you are not worthy?": err.Error()})
+		return
+	}
+	if existing.ID != 0 {
+		respondAlreadyReviewed(c, existing.ID)
+		return
+	}
+	status, err := initialReviewStatus(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review", "Maybe you are not worthy?": err.Error()})
+		return
+	}
+	screening, err := screenReview(c, userID, 0, req.Comment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review", "Maybe you are not worthy?": err.Error()})
+		return
+	}
+	switch screening.Action {
+	case contentfilter.Reject:
+		respondRejectedReview(c, screening)
+		return
+	case contentfilter.Hold:
+		status = models.ReviewPending
+	}
+
 	review := models.Review{
 		Rating:     req.Rating,
 		Comment:    req.Comment,
-		BookID:     req.BookID, // Use req.BookID directly
+		BookID:     book.ID,
+		UserID:     &userID,
+		Status:     status,
 		DatePosted: time.Now().Format("2006-01-02 15:04:05"),
 	}
 
-	if err := db.Create(&review).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+		if screening.Action == contentfilter.Hold {
+			if err := recordHold(tx, review.ID, "", screening); err != nil {
+				return err
+			}
+		}
+		if err := refreshRatings(tx, review.BookID); err != nil {
+			return err
+		}
+		return recordRevision(tx, historyReview, review.ID, models.RevisionCreate, actor(c))
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			respondMissingReference(c, "book", book.ID)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondAlreadyReviewed(c, 0)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review", "Maybe you are not worthy?": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, dto.ReviewResponse{
-		ID:         review.ID,
-		Rating:     review.Rating,
-		Comment:    review.Comment,
-		DatePosted: review.DatePosted,
-		BookID:     review.BookID,
-	})
+	review.User = &models.User{Model: gorm.Model{ID: userID}, Username: c.GetString("username")}
+	c.Header("ETag", entityTag(review.ID, review.UpdatedAt, ""))
+	c.JSON(http.StatusCreated, toReviewResponse(review))
 }
 
 // UpdateReview godoc
 // @Summary Update a review
-// @Description Update a review by its ID
+// @Description Update a review by its ID. Only its author or a moderator can change it. A changed comment is
+// @Description screened again: the content filters may take a published review back to pending or refuse the change.
 // @Tags reviews
 // @Accept json
 // @Produce json
 // @Param id path string true "Review ID"
 // @Param review body dto.UpdateReviewRequest true "Update review"
+// @Param If-Match header string false "ETag of the version being changed"
 // @Success 200 {object} dto.ReviewResponse
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "The content filters refused the comment"
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/reviews/{id} [put]
 func UpdateReview(c *gin.Context) {
@@ -112,41 +212,184 @@ func UpdateReview(c *gin.Context) {
 	}
 
 	var review models.Review
-	if err := db.First(&review, reviewID).Error; err != nil {
+	if err := withReviewer(db).First(&review, reviewID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found", "There is not...": err.Error()})
 		return
 	}
+	if !canEditReview(c, review) {
+		respondNotReviewOwner(c)
+		return
+	}
+	if !checkIfMatch(c, "review", entityTag(review.ID, review.UpdatedAt, "")) {
+		return
+	}
+	readAt := review.UpdatedAt
 
 	if req.Rating != 0 {
 		review.Rating = req.Rating
 	}
-	if req.Comment != "" {
+	screening := contentfilter.Result{Action: contentfilter.Publish}
+	if req.Comment != "" && req.Comment != review.Comment {
 		review.Comment = req.Comment
+		var err error
+		if screening, err = screenReview(c, reviewOwner(review), review.ID, review.Comment); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review", "We like it the way it is": err.Error()})
+			return
+		}
+		if screening.Action == contentfilter.Reject {
+			respondRejectedReview(c, screening)
+			return
+		}
+	}
+	held := screening.Action == contentfilter.Hold && review.Status == models.ReviewPublished
+	if held {
+		review.Status = models.ReviewPending
 	}
 
-	if err := db.Save(&review).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := guardVersion(c, tx, &models.Review{}, review.ID, readAt); err != nil {
+			return err
+		}
+		if err := tx.Omit("User").Save(&review).Error; err != nil {
+			return err
+		}
+		if held {
+			if err := recordHold(tx, review.ID, models.ReviewPublished, screening); err != nil {
+				return err
+			}
+		}
+		if err := refreshRatings(tx, review.BookID); err != nil {
+			return err
+		}
+		return recordRevision(tx, historyReview, review.ID, models.RevisionUpdate, actor(c))
+	})
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(c, "review", "")
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review", "We like it the way it is": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.ReviewResponse{
-		ID:         review.ID,
-		Rating:     review.Rating,
-		Comment:    review.Comment,
-		DatePosted: review.DatePosted,
+	c.Header("ETag", entityTag(review.ID, review.UpdatedAt, ""))
+	c.JSON(http.StatusOK, toReviewResponse(review))
+}
+
+// PatchReview godoc
+// @Summary Patch a review
+// @Description Change the rating or comment of a review with a JSON Merge Patch (RFC 7396,
+// @Description application/merge-patch+json) or a JSON Patch (RFC 6902, application/json-patch+json) against the
+// @Description fields of dto.CreateReviewRequest. The result is validated and screened like a new review. Only
+// @Description its author or a moderator can change a review.
+// @Tags reviews
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Param If-Match header string false "ETag of the version being changed"
+// @Success 200 {object} dto.ReviewResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "A JSON Patch test failed"
+// @Failure 412 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "The patch is invalid or the content filters refused the comment"
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reviews/{id} [patch]
+func PatchReview(c *gin.Context) {
+	var review models.Review
+	if err := db.First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Review not found",
+			Details: "The review with the given ID does not exist",
+		})
+		return
+	}
+	if !canEditReview(c, review) {
+		respondNotReviewOwner(c)
+		return
+	}
+	if !checkIfMatch(c, "review", entityTag(review.ID, review.UpdatedAt, "")) {
+		return
+	}
+
+	var req dto.CreateReviewRequest
+	current := dto.CreateReviewRequest{Rating: review.Rating, Comment: review.Comment}
+	if !patchDocument(c, current, &req) {
+		return
+	}
+	screening := contentfilter.Result{Action: contentfilter.Publish}
+	if req.Comment != review.Comment {
+		var err error
+		if screening, err = screenReview(c, reviewOwner(review), review.ID, req.Comment); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update review",
+				Details: err.Error(),
+			})
+			return
+		}
+		if screening.Action == contentfilter.Reject {
+			respondRejectedReview(c, screening)
+			return
+		}
+	}
+	snapshot := reviewSnapshot{
 		BookID:     review.BookID,
-	})
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+		DatePosted: review.DatePosted,
+	}
+	// A held comment goes to the queue in the same transaction, so it is never public.
+	var hold func(tx *gorm.DB) error
+	if screening.Action == contentfilter.Hold && review.Status == models.ReviewPublished {
+		hold = func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Review{}).Where("id = ?", review.ID).Update("status", models.ReviewPending).Error; err != nil {
+				return err
+			}
+			if err := refreshRatings(tx, review.BookID); err != nil {
+				return err
+			}
+			return recordHold(tx, review.ID, models.ReviewPublished, screening)
+		}
+	}
+	if !commitPatch(c, historyReview, &models.Review{}, review.ID, review.UpdatedAt, snapshot, hold) {
+		return
+	}
+
+	if err := withReviewer(db).First(&review, review.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update review",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.Header("ETag", entityTag(review.ID, review.UpdatedAt, ""))
+	c.JSON(http.StatusOK, toReviewResponse(review))
 }
 
 // DeleteReview godoc
 // @Summary Delete a review
-// @Description Delete a review by its ID
+// @Description Delete a review by its ID. Only admins and moderators can delete reviews; their
+// @Description authors can edit them but not remove them.
 // @Tags reviews
 // @Accept json
 // @Produce json
 // @Param id path string true "Review ID"
+// @Param If-Match header string false "ETag of the version being changed"
 // @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} ErrorResponse
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/reviews/{id} [delete]
 func DeleteReview(c *gin.Context) {
@@ -157,11 +400,167 @@ func DeleteReview(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Review could not be found"})
 		return
 	}
+	if !checkIfMatch(c, "review", entityTag(review.ID, review.UpdatedAt, "")) {
+		return
+	}
 
-	if err := db.Delete(&review).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := guardVersion(c, tx, &models.Review{}, review.ID, review.UpdatedAt); err != nil {
+			return err
+		}
+		if err := recordRevision(tx, historyReview, review.ID, models.RevisionDelete, actor(c)); err != nil {
+			return err
+		}
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return refreshRatings(tx, review.BookID)
+	})
+	if errors.Is(err, errPreconditionFailed) {
+		respondPreconditionFailed(c, "review", "")
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review", "cant cancel everyone you know..": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Freedom of speech purged successfully"})
 }
+
+// reviewOrders are the orders reviews can be listed in, by the value of sort. Ties are
+// broken by the more helpful, then the newer review.
+var reviewOrders = map[string]string{
+	"helpful": "reviews.helpful_score DESC, reviews.created_at DESC, reviews.id DESC",
+	"newest":  "reviews.created_at DESC, reviews.id DESC",
+	"highest": "reviews.rating DESC, reviews.helpful_score DESC, reviews.id DESC",
+	"lowest":  "reviews.rating, reviews.helpful_score DESC, reviews.id DESC",
+}
+
+// reviewOrder reads the sort parameter, falling back to def. It answers 400 itself for
+// an unknown order.
+func reviewOrder(c *gin.Context, def string) (string, bool) {
+	name := c.DefaultQuery("sort", def)
+	order, ok := reviewOrders[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Cannot sort by %q", name),
+			Details: "sort accepts helpful, newest, highest and lowest",
+		})
+	}
+	return order, ok
+}
+
+// currentUserID is the ID of the user the token was issued to, or zero without one.
+func currentUserID(c *gin.Context) uint {
+	return c.GetUint("user_id")
+}
+
+// isModerator tells whether the user may act on content written by others.
+func isModerator(c *gin.Context) bool {
+	role := models.Role(c.GetString("role"))
+	return role == models.RoleAdmin || role == models.RoleModerator
+}
+
+// reviewOwner is the ID of the user who wrote a review, or zero for reviews written
+// before reviews had owners.
+func reviewOwner(review models.Review) uint {
+	if review.UserID == nil {
+		return 0
+	}
+	return *review.UserID
+}
+
+// canEditReview lets the author of a review and moderators change it. Reviews written
+// before reviews had owners are left to moderators.
+func canEditReview(c *gin.Context, review models.Review) bool {
+	return ownsOrModerates(c, review.UserID)
+}
+
+// ownsOrModerates tells whether the user is the owner of something written by ownerID,
+// or a moderator.
+func ownsOrModerates(c *gin.Context, ownerID *uint) bool {
+	if isModerator(c) {
+		return true
+	}
+	userID := currentUserID(c)
+	return userID != 0 && ownerID != nil && *ownerID == userID
+}
+
+func respondNotReviewOwner(c *gin.Context) {
+	c.JSON(http.StatusForbidden, ErrorResponse{
+		Code:    http.StatusForbidden,
+		Message: "Not your review",
+		Details: "Only the author of a review or a moderator can change it",
+	})
+}
+
+// respondAlreadyReviewed answers with 409 pointing at the user's review of the book,
+// when its ID is known.
+func respondAlreadyReviewed(c *gin.Context, reviewID uint) {
+	details := "Each user reviews a book once; update your review instead"
+	if reviewID != 0 {
+		details = fmt.Sprintf("Each user reviews a book once; update your review with ID %d instead", reviewID)
+	}
+	c.JSON(http.StatusConflict, ErrorResponse{
+		Code:    http.StatusConflict,
+		Message: "Book already reviewed",
+		Details: details,
+	})
+}
+
+// withReviewer loads the user who wrote each review.
+func withReviewer(query *gorm.DB) *gorm.DB {
+	return query.Preload("User")
+}
+
+func toReviewResponse(review models.Review) dto.ReviewResponse {
+	response := dto.ReviewResponse{
+		ID:             review.ID,
+		Rating:         review.Rating,
+		Comment:        review.Comment,
+		DatePosted:     review.DatePosted,
+		Status:         review.Status,
+		BookID:         review.BookID,
+		UserID:         review.UserID,
+		HelpfulVotes:   review.HelpfulVotes,
+		UnhelpfulVotes: review.UnhelpfulVotes,
+		HelpfulScore:   review.HelpfulScore,
+		CommentCount:   review.CommentCount,
+		UpdatedAt:      review.UpdatedAt,
+	}
+	if review.User != nil {
+		response.Reviewer = review.User.Username
+	}
+	return response
+}
+
+// shapeReviews renders reviews with the requested fields and, when included, their
+// books, which are loaded for all reviews in one query.
+func shapeReviews(options readOptions, reviews []models.Review) ([]interface{}, error) {
+	books := map[uint]dto.BookResponse{}
+	if options.includes("book") {
+		ids := make([]uint, 0, len(reviews))
+		for _, review := range reviews {
+			ids = append(ids, review.BookID)
+		}
+		var found []models.Book
+		if err := withBookDetails(db).Where("books.id IN ?", ids).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range found {
+			books[book.ID] = toBookResponse(book)
+		}
+	}
+
+	response := make([]interface{}, 0, len(reviews))
+	for _, review := range reviews {
+		included := map[string]interface{}{}
+		if book, ok := books[review.BookID]; ok {
+			included["book"] = book
+		}
+		response = append(response, options.shape(toReviewResponse(review), included))
+	}
+	return response, nil
+}
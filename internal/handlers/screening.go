@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"go-rest-api-ozgur/internal/contentfilter"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// screenedBy names the content filters as the moderator of the reviews they hold.
+const screenedBy = "content filter"
+
+// screeningHistory is how many of an author's earlier reviews a review is compared with.
+const screeningHistory = 20
+
+var contentFilter *contentfilter.Pipeline
+
+// InitContentFilter sets the filters new and edited reviews are screened with.
+func InitContentFilter(p *contentfilter.Pipeline) {
+	contentFilter = p
+}
+
+// screenReview runs the content filters over the comment of a review by userID,
+// comparing it with the author's other reviews. Moderators are not screened.
+func screenReview(c *gin.Context, userID, reviewID uint, comment string) (contentfilter.Result, error) {
+	if contentFilter == nil || isModerator(c) {
+		return contentfilter.Result{Action: contentfilter.Publish}, nil
+	}
+	var previous []string
+	if userID != 0 {
+		err := db.Model(&models.Review{}).
+			Where("user_id = ? AND id <> ?", userID, reviewID).
+			Order("created_at DESC").
+			Limit(screeningHistory).
+			Pluck("comment", &previous).Error
+		if err != nil {
+			return contentfilter.Result{}, err
+		}
+	}
+	return contentFilter.Run(c.Request.Context(), contentfilter.Content{Text: comment, Previous: previous})
+}
+
+// recordHold keeps in the moderation history why the content filters held a review.
+func recordHold(tx *gorm.DB, reviewID uint, fromStatus string, result contentfilter.Result) error {
+	return tx.Omit("Review").Create(&models.ModerationAction{
+		ReviewID:   reviewID,
+		Action:     models.ModerationHold,
+		FromStatus: fromStatus,
+		ToStatus:   models.ReviewPending,
+		Note:       strings.Join(result.Reasons(), "; "),
+		Moderator:  screenedBy,
+	}).Error
+}
+
+// respondRejectedReview answers with 422 telling the author what the filters found.
+func respondRejectedReview(c *gin.Context, result contentfilter.Result) {
+	c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+		Code:    http.StatusUnprocessableEntity,
+		Message: "Review refused",
+		Details: strings.Join(result.Reasons(), "; "),
+	})
+}
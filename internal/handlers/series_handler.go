@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAllSeries godoc
+// @Summary Get all series
+// @Description Get a list of all series with their works in reading order
+// @Tags series
+// @Accept json
+// @Produce json
+// @Success 200 {array} dto.SeriesResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/series [get]
+func GetAllSeries(c *gin.Context) {
+	var series []models.Series
+	if err := withSeriesWorks(db).Order("name").Find(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve series",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response := make([]dto.SeriesResponse, 0, len(series))
+	for _, s := range series {
+		response = append(response, toSeriesResponse(s))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSeries godoc
+// @Summary Get a specific series
+// @Description Get a series by its ID with its works in reading order
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} dto.SeriesResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/series/{id} [get]
+func GetSeries(c *gin.Context) {
+	var series models.Series
+	if err := withSeriesWorks(db).First(&series, c.Param("id")).Error; err != nil {
+		respondSeriesNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSeriesResponse(series))
+}
+
+// CreateSeries godoc
+// @Summary Create a new series
+// @Description Create a series. Works join it through their series_id.
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param series body dto.CreateSeriesRequest true "Create series"
+// @Success 201 {object} dto.SeriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/series [post]
+func CreateSeries(c *gin.Context) {
+	var req dto.CreateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	series := models.Series{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := db.Create(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create series",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSeriesResponse(series))
+}
+
+// UpdateSeries godoc
+// @Summary Update a series
+// @Description Update a series with the input payload
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param series body dto.UpdateSeriesRequest true "Update series"
+// @Success 200 {object} dto.SeriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/series/{id} [put]
+func UpdateSeries(c *gin.Context) {
+	var req dto.UpdateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var series models.Series
+	if err := db.First(&series, c.Param("id")).Error; err != nil {
+		respondSeriesNotFound(c)
+		return
+	}
+
+	if req.Name != "" {
+		series.Name = req.Name
+	}
+	if req.Description != "" {
+		series.Description = req.Description
+	}
+
+	if err := db.Save(&series).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update series",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := withSeriesWorks(db).First(&series, series.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update series",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSeriesResponse(series))
+}
+
+// DeleteSeries godoc
+// @Summary Delete a series
+// @Description Delete a series. Its works are kept and simply leave the series.
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/series/{id} [delete]
+func DeleteSeries(c *gin.Context) {
+	var series models.Series
+	if err := db.First(&series, c.Param("id")).Error; err != nil {
+		respondSeriesNotFound(c)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Work{}).
+			Where("series_id = ?", series.ID).
+			Updates(map[string]interface{}{"series_id": nil, "series_position": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&series).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete series",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Series deleted"})
+}
+
+func respondSeriesNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{
+		Code:    http.StatusNotFound,
+		Message: "Series not found",
+		Details: "The series with the given ID does not exist",
+	})
+}
+
+// withSeriesWorks preloads the works of a series in reading order.
+func withSeriesWorks(query *gorm.DB) *gorm.DB {
+	return query.Preload("Works", func(db *gorm.DB) *gorm.DB { return db.Order("series_position, id") })
+}
+
+func toSeriesResponse(series models.Series) dto.SeriesResponse {
+	works := make([]dto.SeriesWork, 0, len(series.Works))
+	for _, work := range series.Works {
+		works = append(works, dto.SeriesWork{
+			ID:             work.ID,
+			Title:          work.Title,
+			SeriesPosition: work.SeriesPosition,
+		})
+	}
+
+	return dto.SeriesResponse{
+		ID:          series.ID,
+		Name:        series.Name,
+		Description: series.Description,
+		Works:       works,
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// slugify lower-cases a name and joins its words with dashes.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// genreSubtreeIDs returns the genre itself followed by all of its live descendants.
+func genreSubtreeIDs(id uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM genres WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT g.id FROM genres g JOIN subtree s ON g.parent_id = s.id WHERE g.deleted_at IS NULL
+		)
+		SELECT id FROM subtree`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// findGenre looks a genre up by numeric ID or by slug.
+func findGenre(key string) (models.Genre, error) {
+	var genre models.Genre
+	query := db.Where("slug = ?", key)
+	if isNumeric(key) {
+		query = db.Where("id = ?", key)
+	}
+	err := query.First(&genre).Error
+	return genre, err
+}
+
+func isNumeric(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveGenres loads the requested genres. When one of them does not exist its ID is returned as missing.
+func resolveGenres(tx *gorm.DB, ids []uint) (genres []models.Genre, missing uint, err error) {
+	if len(ids) == 0 {
+		return []models.Genre{}, 0, nil
+	}
+	if err := tx.Where("id IN ?", ids).Find(&genres).Error; err != nil {
+		return nil, 0, err
+	}
+	known := make(map[uint]bool, len(genres))
+	for _, genre := range genres {
+		known[genre.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return nil, id, nil
+		}
+	}
+	return genres, 0, nil
+}
+
+// normalizeTags trims, lower-cases and de-duplicates tag names, dropping empty ones.
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// resolveTags returns the tags with the given names, creating the ones that do not exist yet.
+func resolveTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	names = normalizeTags(names)
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	tags = tags[:0]
+	err := tx.Where("name IN ?", names).Find(&tags).Error
+	return tags, err
+}
+
+// replaceAssociation swaps a many-to-many association of the book for the given records.
+func replaceAssociation[T any](tx *gorm.DB, book *models.Book, name string, values []T) error {
+	association := tx.Model(book).Omit(name + ".*").Association(name)
+	if len(values) == 0 {
+		return association.Clear()
+	}
+	return association.Replace(values)
+}
+
+func toGenreSummaries(genres []models.Genre) []dto.GenreSummary {
+	response := make([]dto.GenreSummary, 0, len(genres))
+	for _, genre := range genres {
+		response = append(response, dto.GenreSummary{ID: genre.ID, Name: genre.Name, Slug: genre.Slug})
+	}
+	return response
+}
+
+func toTagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// buildGenreTree nests a flat list of genres under their parents. Genres whose parent is
+// not in the list become roots.
+func buildGenreTree(genres []models.Genre) []dto.GenreResponse {
+	children := make(map[uint][]models.Genre)
+	present := make(map[uint]bool, len(genres))
+	for _, genre := range genres {
+		present[genre.ID] = true
+	}
+
+	var roots []models.Genre
+	for _, genre := range genres {
+		if genre.ParentID != nil && present[*genre.ParentID] {
+			children[*genre.ParentID] = append(children[*genre.ParentID], genre)
+		} else {
+			roots = append(roots, genre)
+		}
+	}
+
+	var build func(genre models.Genre) dto.GenreResponse
+	build = func(genre models.Genre) dto.GenreResponse {
+		response := toGenreResponse(genre)
+		for _, child := range children[genre.ID] {
+			response.Children = append(response.Children, build(child))
+		}
+		return response
+	}
+
+	tree := make([]dto.GenreResponse, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+	return tree
+}
+
+func toGenreResponse(genre models.Genre) dto.GenreResponse {
+	return dto.GenreResponse{
+		ID:       genre.ID,
+		Name:     genre.Name,
+		Slug:     genre.Slug,
+		ParentID: genre.ParentID,
+	}
+}
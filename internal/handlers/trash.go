@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// trashTables names the table and the column shown as the name of a trashed row.
+var trashTables = map[string]struct {
+	table string
+	name  string
+	model interface{}
+}{
+	historyBook:   {"books", "title", &models.Book{}},
+	historyAuthor: {"authors", "name", &models.Author{}},
+	historyReview: {"reviews", "LEFT(comment, 80)", &models.Review{}},
+}
+
+// trashConflictError is returned when a record cannot be restored before its parent.
+type trashConflictError struct {
+	Message string
+}
+
+func (e *trashConflictError) Error() string {
+	return e.Message
+}
+
+// restoreBook brings a soft deleted book back together with its work, if that was
+// deleted as well, and the reviews deleted along with it.
+func restoreBook(tx *gorm.DB, id uint, changedBy string, result *dto.TrashRestoreResponse) error {
+	var book models.Book
+	if err := tx.Unscoped().First(&book, id).Error; err != nil {
+		return err
+	}
+	if !book.DeletedAt.Valid {
+		return nil
+	}
+
+	if err := untrash(tx, &models.Book{}, "id = ?", id); err != nil {
+		return err
+	}
+	if book.WorkID != nil {
+		restored := tx.Unscoped().Model(&models.Work{}).
+			Where("id = ? AND deleted_at IS NOT NULL", *book.WorkID).
+			Update("deleted_at", nil)
+		if restored.Error != nil {
+			return restored.Error
+		}
+		if restored.RowsAffected > 0 {
+			result.RestoredWorks = append(result.RestoredWorks, *book.WorkID)
+		}
+	}
+
+	var reviewIDs []uint
+	if book.CascadeID != nil {
+		err := tx.Unscoped().Model(&models.Review{}).
+			Where("book_id = ? AND cascade_id = ? AND deleted_at IS NOT NULL", id, *book.CascadeID).
+			Pluck("id", &reviewIDs).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(reviewIDs) > 0 {
+		if err := untrash(tx, &models.Review{}, "id IN ?", reviewIDs); err != nil {
+			return err
+		}
+	}
+	for _, reviewID := range reviewIDs {
+		if err := recordRevision(tx, historyReview, reviewID, models.RevisionRestore, changedBy); err != nil {
+			return err
+		}
+	}
+	result.RestoredReviews = append(result.RestoredReviews, reviewIDs...)
+	if err := refreshRatings(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Where("entity = ? AND from_id = ?", redirectBook, id).Delete(&models.MergeRedirect{}).Error; err != nil {
+		return err
+	}
+	result.RestoredBooks = append(result.RestoredBooks, id)
+	forgetBooks([]uint{id})
+	return recordRevision(tx, historyBook, id, models.RevisionRestore, changedBy)
+}
+
+// restoreAuthor brings a soft deleted author back together with the books that were
+// deleted along with them. An author that was merged comes back without credits, as
+// those moved to the survivor.
+func restoreAuthor(tx *gorm.DB, id uint, changedBy string, result *dto.TrashRestoreResponse) error {
+	var author models.Author
+	if err := tx.Unscoped().First(&author, id).Error; err != nil {
+		return err
+	}
+	if !author.DeletedAt.Valid {
+		return nil
+	}
+
+	if err := untrash(tx, &models.Author{}, "id = ?", id); err != nil {
+		return err
+	}
+	var bookIDs []uint
+	if author.CascadeID != nil {
+		err := tx.Unscoped().Model(&models.Book{}).
+			Where("cascade_id = ? AND deleted_at IS NOT NULL", *author.CascadeID).
+			Where("id IN (SELECT book_id FROM book_contributors WHERE author_id = ?)", id).
+			Pluck("id", &bookIDs).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, bookID := range bookIDs {
+		if err := restoreBook(tx, bookID, changedBy, result); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("entity = ? AND from_id = ?", redirectAuthor, id).Delete(&models.MergeRedirect{}).Error; err != nil {
+		return err
+	}
+	forgetAuthorBooks(tx, id)
+	return recordRevision(tx, historyAuthor, id, models.RevisionRestore, changedBy)
+}
+
+// restoreReview brings a soft deleted review back. Its book has to be live, and its
+// author must not have reviewed the book again since.
+func restoreReview(tx *gorm.DB, id uint, changedBy string, result *dto.TrashRestoreResponse) error {
+	var review models.Review
+	if err := tx.Unscoped().First(&review, id).Error; err != nil {
+		return err
+	}
+	if !review.DeletedAt.Valid {
+		return nil
+	}
+	if exists, err := referenceExists(tx, &models.Book{}, review.BookID); err != nil {
+		return err
+	} else if !exists {
+		return &trashConflictError{Message: fmt.Sprintf("Restore the book with ID %d first", review.BookID)}
+	}
+	if review.UserID != nil {
+		var newer models.Review
+		if err := tx.Select("id").Where("book_id = ? AND user_id = ?", review.BookID, *review.UserID).Limit(1).Find(&newer).Error; err != nil {
+			return err
+		}
+		if newer.ID != 0 {
+			return &trashConflictError{Message: fmt.Sprintf("Its author has written the review with ID %d of the book since", newer.ID)}
+		}
+	}
+
+	if err := untrash(tx, &models.Review{}, "id = ?", id); err != nil {
+		return err
+	}
+	result.RestoredReviews = append(result.RestoredReviews, id)
+	if err := refreshRatings(tx, review.BookID); err != nil {
+		return err
+	}
+	return recordRevision(tx, historyReview, id, models.RevisionRestore, changedBy)
+}
+
+// untrash brings the matching rows back and forgets the cascade that deleted them.
+func untrash(tx *gorm.DB, model interface{}, query string, args ...interface{}) error {
+	return tx.Unscoped().Model(model).Where(query, args...).
+		Updates(map[string]interface{}{"deleted_at": nil, "cascade_id": nil}).Error
+}
+
+// purgeBook permanently removes a book with its reviews, credits, genre and tag links
+// and history. It returns the cover key, whose files are deleted after commit.
+func purgeBook(tx *gorm.DB, id uint) (string, error) {
+	var book models.Book
+	if err := tx.Unscoped().First(&book, id).Error; err != nil {
+		return "", err
+	}
+
+	var reviewIDs []uint
+	if err := tx.Unscoped().Model(&models.Review{}).Where("book_id = ?", id).Pluck("id", &reviewIDs).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Where("entity = ? AND entity_id IN ?", historyReview, reviewIDs).Delete(&models.Revision{}).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Unscoped().Where("book_id = ?", id).Delete(&models.Review{}).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Where("book_id = ?", id).Delete(&models.BookContributor{}).Error; err != nil {
+		return "", err
+	}
+	for _, statement := range []string{
+		`DELETE FROM book_genres WHERE book_id = ?`,
+		`DELETE FROM book_tags WHERE book_id = ?`,
+	} {
+		if err := tx.Exec(statement, id).Error; err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Where("entity = ? AND entity_id = ?", historyBook, id).Delete(&models.Revision{}).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Unscoped().Delete(&book).Error; err != nil {
+		return "", err
+	}
+	forgetBooks([]uint{id})
+	return book.CoverKey, nil
+}
+
+// purgeAuthor permanently removes an author and their history. Books that still
+// credit the author lose the credit.
+func purgeAuthor(tx *gorm.DB, id uint) error {
+	forgetAuthorBooks(tx, id)
+	if err := tx.Where("author_id = ?", id).Delete(&models.BookContributor{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("entity = ? AND entity_id = ?", historyAuthor, id).Delete(&models.Revision{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id = ?", id).Delete(&models.Author{}).Error
+}
+
+// purgeReview permanently removes a review and its history.
+func purgeReview(tx *gorm.DB, id uint) error {
+	if err := tx.Where("entity = ? AND entity_id = ?", historyReview, id).Delete(&models.Revision{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id = ?", id).Delete(&models.Review{}).Error
+}
+
+// purgeTrashed permanently removes one trashed record in its own transaction.
+func purgeTrashed(ctx context.Context, entity string, id uint) error {
+	var coverKey string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		switch entity {
+		case historyBook:
+			var err error
+			coverKey, err = purgeBook(tx, id)
+			return err
+		case historyAuthor:
+			return purgeAuthor(tx, id)
+		default:
+			return purgeReview(tx, id)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	deleteCoverBlobs(ctx, coverKey)
+	return nil
+}
+
+// PurgeExpiredTrash permanently removes the books, authors and reviews that were
+// deleted before the cutoff. Every record is purged in its own transaction, so one
+// failure does not keep the rest in the trash.
+func PurgeExpiredTrash(ctx context.Context, cutoff time.Time) (dto.TrashPurgeReport, error) {
+	report := dto.TrashPurgeReport{DeletedBefore: cutoff}
+	var errs []error
+	for _, entity := range []string{historyReview, historyBook, historyAuthor} {
+		var ids []uint
+		err := db.Unscoped().Model(trashTables[entity].model).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Pluck("id", &ids).Error
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			if err := purgeTrashed(ctx, entity, id); err != nil {
+				errs = append(errs, fmt.Errorf("%s %d: %w", entity, id, err))
+				continue
+			}
+			switch entity {
+			case historyBook:
+				report.Books++
+			case historyAuthor:
+				report.Authors++
+			default:
+				report.Reviews++
+			}
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// RunTrashRetention purges records that have been in the trash longer than retention,
+// once at start and then every interval until ctx is done. Each run is handed to done.
+func RunTrashRetention(ctx context.Context, retention, interval time.Duration, done func(dto.TrashPurgeReport, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done(PurgeExpiredTrash(ctx, time.Now().Add(-retention)))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
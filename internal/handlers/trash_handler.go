@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTrash godoc
+// @Summary List deleted records
+// @Description List the soft deleted books, authors or reviews, most recently deleted first, with who deleted
+// @Description them, the record they were merged into if any and when the retention job will purge them.
+// @Tags trash
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Records per page (default 20, max 100)"
+// @Success 200 {array} dto.TrashItem
+// @Success 200 {object} map[string]string "The trash is empty"
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/trash/{entity} [get]
+func GetTrash(c *gin.Context) {
+	entity, ok := catalogEntities[c.Param("entity")]
+	if !ok {
+		respondUnknownTrashEntity(c)
+		return
+	}
+	table := trashTables[entity]
+
+	query := db.Unscoped().Model(table.model).Where("deleted_at IS NOT NULL")
+	paged, err := paginate(c, query, table.model)
+	if err != nil {
+		respondTrashError(c, "Failed to list the trash", err)
+		return
+	}
+	var items []dto.TrashItem
+	err = paged.Select(fmt.Sprintf(`id, %s AS name, deleted_at,
+		COALESCE((SELECT r.changed_by FROM revisions r
+			WHERE r.entity = ? AND r.entity_id = %s.id AND r.action IN ?
+			ORDER BY r.version DESC LIMIT 1), '') AS deleted_by,
+		(SELECT m.to_id FROM merge_redirects m WHERE m.entity = ? AND m.from_id = %s.id) AS merged_into`,
+		table.name, table.table, table.table),
+		entity, []string{models.RevisionDelete, models.RevisionMerge}, entity).
+		Order("deleted_at DESC").
+		Scan(&items).Error
+	if err != nil {
+		respondTrashError(c, "Failed to list the trash", err)
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "The trash is empty"})
+		return
+	}
+
+	for i := range items {
+		items[i].Entity = entity
+		if cfg.TrashRetentionDays > 0 {
+			purgeAt := items[i].DeletedAt.AddDate(0, 0, cfg.TrashRetentionDays)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreTrash godoc
+// @Summary Restore a deleted record
+// @Description Undelete a book, author or review. A book comes back with its work and the reviews deleted along
+// @Description with it, an author with the books deleted along with them. A review needs its book to be live.
+// @Tags trash
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Record ID"
+// @Success 200 {object} dto.TrashRestoreResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/trash/{entity}/{id}/restore [post]
+func RestoreTrash(c *gin.Context) {
+	entity, id, ok := trashTarget(c)
+	if !ok {
+		return
+	}
+
+	result := dto.TrashRestoreResponse{Entity: entity, ID: id}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		switch entity {
+		case historyBook:
+			return restoreBook(tx, id, actor(c), &result)
+		case historyAuthor:
+			return restoreAuthor(tx, id, actor(c), &result)
+		default:
+			return restoreReview(tx, id, actor(c), &result)
+		}
+	})
+	var conflict *trashConflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "Cannot restore " + entity,
+			Details: conflict.Error(),
+		})
+		return
+	}
+	if err != nil {
+		respondTrashError(c, "Failed to restore "+entity, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// PurgeTrash godoc
+// @Summary Permanently delete a record
+// @Description Remove a deleted book, author or review for good, together with its history. Purging a book also
+// @Description removes its reviews and cover; books crediting a purged author lose the credit.
+// @Tags trash
+// @Produce json
+// @Param entity path string true "books, authors or reviews"
+// @Param id path int true "Record ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/admin/trash/{entity}/{id} [delete]
+func PurgeTrash(c *gin.Context) {
+	entity, id, ok := trashTarget(c)
+	if !ok {
+		return
+	}
+	if err := purgeTrashed(c.Request.Context(), entity, id); err != nil {
+		respondTrashError(c, "Failed to purge "+entity, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("The %s with ID %d is gone for good", entity, id)})
+}
+
+// trashTarget reads the entity and ID of a trash URL and checks that the record is in
+// the trash.
+func trashTarget(c *gin.Context) (string, uint, bool) {
+	entity, ok := catalogEntities[c.Param("entity")]
+	if !ok {
+		respondUnknownTrashEntity(c)
+		return "", 0, false
+	}
+	var count int64
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err == nil {
+		err = db.Unscoped().Model(trashTables[entity].model).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error
+		if err != nil {
+			respondTrashError(c, "Failed to read the trash", err)
+			return "", 0, false
+		}
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Not in the trash",
+			Details: fmt.Sprintf("There is no deleted %s with ID %s", entity, c.Param("id")),
+		})
+		return "", 0, false
+	}
+	return entity, uint(id), true
+}
+
+func respondUnknownTrashEntity(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{
+		Code:    http.StatusNotFound,
+		Message: "Unknown entity",
+		Details: "The trash holds books, authors and reviews",
+	})
+}
+
+func respondTrashError(c *gin.Context, message string, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: message,
+		Details: err.Error(),
+	})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// wilsonZ is the z-score of the 95% confidence level used for helpful scores.
+const wilsonZ = 1.96
+
+// VoteReview godoc
+// @Summary Vote on whether a review was helpful
+// @Description Vote a published review helpful or not as the signed in user. Voting again replaces the earlier
+// @Description vote. Authors cannot vote on their own reviews.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param vote body dto.VoteReviewRequest true "Whether the review was helpful"
+// @Success 200 {object} dto.ReviewVoteResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "Authors cannot vote on their own reviews"
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reviews/{id}/vote [put]
+func VoteReview(c *gin.Context) {
+	var req dto.VoteReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+	review, userID, ok := votableReview(c)
+	if !ok {
+		return
+	}
+
+	vote := models.ReviewVote{ReviewID: review.ID, UserID: userID, Helpful: *req.Helpful}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("Review", "User").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "review_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"helpful", "updated_at"}),
+		}).Create(&vote).Error
+		if err != nil {
+			return err
+		}
+		return countVotes(tx, &review)
+	})
+	if err != nil {
+		respondVoteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toReviewVoteResponse(review, req.Helpful))
+}
+
+// UnvoteReview godoc
+// @Summary Take back a vote on a review
+// @Description Remove the signed in user's helpful or unhelpful vote from a review.
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Review ID"
+// @Success 200 {object} dto.ReviewVoteResponse
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reviews/{id}/vote [delete]
+func UnvoteReview(c *gin.Context) {
+	review, userID, ok := votableReview(c)
+	if !ok {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("review_id = ? AND user_id = ?", review.ID, userID).Delete(&models.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		return countVotes(tx, &review)
+	})
+	if err != nil {
+		respondVoteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toReviewVoteResponse(review, nil))
+}
+
+// votableReview loads the published review in the path for the signed in user to vote
+// on, answering the error itself when they cannot.
+func votableReview(c *gin.Context) (models.Review, uint, bool) {
+	var review models.Review
+	userID := currentUserID(c)
+	if userID == 0 {
+		respondTokenWithoutUser(c)
+		return review, 0, false
+	}
+	if err := publishedReviews(db).First(&review, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondReviewNotFound(c)
+		} else {
+			respondVoteError(c, err)
+		}
+		return review, 0, false
+	}
+	if reviewOwner(review) == userID {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Cannot vote on your own review",
+			Details: "Votes tell other readers how helpful a review was",
+		})
+		return review, 0, false
+	}
+	return review, userID, true
+}
+
+// countVotes recounts the votes of a review and stores the totals and helpful score
+// on it. The columns are written without touching updated_at, so votes do not change
+// the review's ETag under its author; responses embedding the review add
+// reviewCounters to their ETag instead.
+func countVotes(tx *gorm.DB, review *models.Review) error {
+	var counts struct {
+		Helpful   int
+		Unhelpful int
+	}
+	err := tx.Model(&models.ReviewVote{}).
+		Select("COUNT(*) FILTER (WHERE helpful) AS helpful, COUNT(*) FILTER (WHERE NOT helpful) AS unhelpful").
+		Where("review_id = ?", review.ID).
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+	review.HelpfulVotes = counts.Helpful
+	review.UnhelpfulVotes = counts.Unhelpful
+	review.HelpfulScore = wilsonLowerBound(counts.Helpful, counts.Unhelpful)
+	return tx.Model(&models.Review{}).Where("id = ?", review.ID).UpdateColumns(map[string]interface{}{
+		"helpful_votes":   review.HelpfulVotes,
+		"unhelpful_votes": review.UnhelpfulVotes,
+		"helpful_score":   review.HelpfulScore,
+	}).Error
+}
+
+// reviewCounters sums up the counters of a review that change without its updated_at,
+// for the ETags of responses that embed the review.
+func reviewCounters(review models.Review) string {
+	return fmt.Sprintf("%d/%d/%d", review.HelpfulVotes, review.UnhelpfulVotes, review.CommentCount)
+}
+
+// wilsonLowerBound is the lower bound of the Wilson score interval for the share of
+// helpful votes: how helpful a review at least is, given how few votes may be behind
+// it. One helpful vote out of one scores lower than 90 out of 100.
+func wilsonLowerBound(helpful, unhelpful int) float64 {
+	n := float64(helpful + unhelpful)
+	if n == 0 {
+		return 0
+	}
+	p := float64(helpful) / n
+	z2 := wilsonZ * wilsonZ
+	centre := p + z2/(2*n)
+	margin := wilsonZ * math.Sqrt((p*(1-p)+z2/(4*n))/n)
+	return (centre - margin) / (1 + z2/n)
+}
+
+func toReviewVoteResponse(review models.Review, helpful *bool) dto.ReviewVoteResponse {
+	return dto.ReviewVoteResponse{
+		ReviewID:       review.ID,
+		Helpful:        helpful,
+		HelpfulVotes:   review.HelpfulVotes,
+		UnhelpfulVotes: review.UnhelpfulVotes,
+		HelpfulScore:   review.HelpfulScore,
+	}
+}
+
+func respondVoteError(c *gin.Context, err error) {
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to record the vote",
+		Details: err.Error(),
+	})
+}
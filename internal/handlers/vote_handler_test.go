@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWilsonLowerBound(t *testing.T) {
+	for _, tc := range []struct {
+		helpful, unhelpful int
+		want               float64
+	}{
+		{0, 0, 0},
+		{0, 1, 0},
+		{1, 0, 0.2065},
+		{2, 0, 0.3424},
+		{5, 5, 0.2366},
+		{9, 1, 0.5958},
+		{90, 10, 0.8256},
+		{100, 0, 0.9630},
+	} {
+		if got := wilsonLowerBound(tc.helpful, tc.unhelpful); math.Abs(got-tc.want) > 5e-5 {
+			t.Errorf("wilsonLowerBound(%d, %d) = %.4f, want %.4f", tc.helpful, tc.unhelpful, got, tc.want)
+		}
+	}
+}
+
+func TestWilsonLowerBoundOrdering(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		lower, higher [2]int
+	}{
+		{"more votes at the same share", [2]int{9, 1}, [2]int{90, 10}},
+		{"one vote against many", [2]int{1, 0}, [2]int{90, 10}},
+		{"one more helpful vote", [2]int{3, 2}, [2]int{4, 2}},
+		{"one less unhelpful vote", [2]int{4, 3}, [2]int{4, 2}},
+		{"no votes against a helpful one", [2]int{0, 0}, [2]int{1, 0}},
+	} {
+		lower := wilsonLowerBound(tc.lower[0], tc.lower[1])
+		higher := wilsonLowerBound(tc.higher[0], tc.higher[1])
+		if lower >= higher {
+			t.Errorf("%s: %v scores %.4f, not below %v at %.4f", tc.name, tc.lower, lower, tc.higher, higher)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetWorks godoc
+// @Summary Get all works
+// @Description Get a list of all works with their editions
+// @Tags works
+// @Accept json
+// @Produce json
+// @Success 200 {array} dto.WorkResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/works [get]
+func GetWorks(c *gin.Context) {
+	var works []models.Work
+	if err := withEditions(db).Order("title").Find(&works).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve works",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response := make([]dto.WorkResponse, 0, len(works))
+	for _, work := range works {
+		response = append(response, toWorkResponse(work))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWork godoc
+// @Summary Get a specific work
+// @Description Get a work by its ID together with its editions
+// @Tags works
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Success 200 {object} dto.WorkResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/works/{id} [get]
+func GetWork(c *gin.Context) {
+	var work models.Work
+	if err := withEditions(db).First(&work, c.Param("id")).Error; err != nil {
+		respondWorkNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, toWorkResponse(work))
+}
+
+// CreateWork godoc
+// @Summary Create a new work
+// @Description Create a work, optionally as part of a series
+// @Tags works
+// @Accept json
+// @Produce json
+// @Param work body dto.CreateWorkRequest true "Create work"
+// @Success 201 {object} dto.WorkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/works [post]
+func CreateWork(c *gin.Context) {
+	var req dto.CreateWorkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if req.SeriesID != nil {
+		if exists, err := referenceExists(db, &models.Series{}, *req.SeriesID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to create work",
+				Details: err.Error(),
+			})
+			return
+		} else if !exists {
+			respondMissingReference(c, "series", *req.SeriesID)
+			return
+		}
+	}
+
+	work := models.Work{
+		Title:              req.Title,
+		OriginalLanguage:   req.OriginalLanguage,
+		FirstPublishedYear: req.FirstPublishedYear,
+		SeriesID:           req.SeriesID,
+		SeriesPosition:     req.SeriesPosition,
+	}
+	if err := db.Create(&work).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create work",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toWorkResponse(work))
+}
+
+// UpdateWork godoc
+// @Summary Update a work
+// @Description Update a work with the input payload. Set leave_series to take it out of its series.
+// @Tags works
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Param work body dto.UpdateWorkRequest true "Update work"
+// @Success 200 {object} dto.WorkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/works/{id} [put]
+func UpdateWork(c *gin.Context) {
+	var req dto.UpdateWorkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid input data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var work models.Work
+	if err := db.First(&work, c.Param("id")).Error; err != nil {
+		respondWorkNotFound(c)
+		return
+	}
+
+	if req.Title != "" {
+		work.Title = req.Title
+	}
+	if req.OriginalLanguage != "" {
+		work.OriginalLanguage = req.OriginalLanguage
+	}
+	if req.FirstPublishedYear != 0 {
+		work.FirstPublishedYear = req.FirstPublishedYear
+	}
+	if req.SeriesPosition != 0 {
+		work.SeriesPosition = req.SeriesPosition
+	}
+	if req.LeaveSeries {
+		work.SeriesID = nil
+		work.SeriesPosition = 0
+	} else if req.SeriesID != nil {
+		if exists, err := referenceExists(db, &models.Series{}, *req.SeriesID); err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to update work",
+				Details: err.Error(),
+			})
+			return
+		} else if !exists {
+			respondMissingReference(c, "series", *req.SeriesID)
+			return
+		}
+		work.SeriesID = req.SeriesID
+	}
+
+	if err := db.Save(&work).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update work",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := withEditions(db).First(&work, work.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update work",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, toWorkResponse(work))
+}
+
+// DeleteWork godoc
+// @Summary Delete a work
+// @Description Delete a work that has no editions left
+// @Tags works
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/works/{id} [delete]
+func DeleteWork(c *gin.Context) {
+	var work models.Work
+	if err := db.First(&work, c.Param("id")).Error; err != nil {
+		respondWorkNotFound(c)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var editions int64
+		if err := tx.Model(&models.Book{}).Where("work_id = ?", work.ID).Count(&editions).Error; err != nil {
+			return err
+		}
+		if editions > 0 {
+			return &dependentsError{Entity: "edition", Count: editions}
+		}
+		return tx.Delete(&work).Error
+	})
+	var dependents *dependentsError
+	if errors.As(err, &dependents) {
+		respondDependents(c, "work", dependents)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete work",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Work deleted"})
+}
+
+// GetWorkEditions godoc
+// @Summary Get the editions of a work
+// @Description Get every book that is an edition of the given work
+// @Tags works
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Success 200 {array} dto.BookResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/works/{id}/editions [get]
+func GetWorkEditions(c *gin.Context) {
+	var work models.Work
+	if err := db.First(&work, c.Param("id")).Error; err != nil {
+		respondWorkNotFound(c)
+		return
+	}
+
+	respondEditions(c, work.ID, 0)
+}
+
+// GetWorkReviews godoc
+// @Summary Get the reviews of a work
+// @Description Get a page of the published reviews written for any edition of the given work, newest first
+// @Description unless sort says otherwise
+// @Tags works
+// @Accept json
+// @Produce json
+// @Param id path string true "Work ID"
+// @Param sort query string false "helpful, newest, highest or lowest"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Records per page (default 20, max 100)"
+// @Param include query string false "Related resources to embed: book"
+// @Param fields query string false "Comma separated fields to return; id is always returned"
+// @Success 200 {array} dto.ReviewResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/works/{id}/reviews [get]
+func GetWorkReviews(c *gin.Context) {
+	options, ok := parseReadOptions(c, dto.ReviewResponse{}, "book")
+	if !ok {
+		return
+	}
+	order, ok := reviewOrder(c, "newest")
+	if !ok {
+		return
+	}
+	var work models.Work
+	if err := db.First(&work, c.Param("id")).Error; err != nil {
+		respondWorkNotFound(c)
+		return
+	}
+
+	query := publishedReviews(db.Model(&models.Review{})).
+		Where("book_id IN (SELECT id FROM books WHERE work_id = ? AND deleted_at IS NULL)", work.ID)
+	paged, err := paginate(c, query, &models.Review{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve reviews",
+			Details: err.Error(),
+		})
+		return
+	}
+	var reviews []models.Review
+	if err := withReviewer(paged).Order(order).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve reviews",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response, err := shapeReviews(options, reviews)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve reviews",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBookEditions godoc
+// @Summary Get the other editions of a book
+// @Description Get the books that are editions of the same work as the given book
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} dto.BookResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/books/{id}/editions [get]
+func GetBookEditions(c *gin.Context) {
+	var book models.Book
+	if err := db.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "Book not found",
+			Details: "The book with the given ID does not exist",
+		})
+		return
+	}
+	if book.WorkID == nil {
+		c.JSON(http.StatusOK, []dto.BookResponse{})
+		return
+	}
+
+	respondEditions(c, *book.WorkID, book.ID)
+}
+
+// respondEditions lists the editions of a work, leaving out the book with ID except.
+func respondEditions(c *gin.Context, workID, except uint) {
+	var books []models.Book
+	err := withBookDetails(db).
+		Where("work_id = ? AND id <> ?", workID, except).
+		Order("publication_year, id").
+		Find(&books).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retrieve editions",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response := make([]dto.BookResponse, 0, len(books))
+	for _, book := range books {
+		response = append(response, toBookResponse(book))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondWorkNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, ErrorResponse{
+		Code:    http.StatusNotFound,
+		Message: "Work not found",
+		Details: "The work with the given ID does not exist",
+	})
+}
+
+// withEditions preloads the editions of a work, oldest first.
+func withEditions(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Editions", func(db *gorm.DB) *gorm.DB { return db.Order("publication_year, id") }).
+		Preload("Editions.Publisher")
+}
+
+func toWorkResponse(work models.Work) dto.WorkResponse {
+	editions := make([]dto.EditionSummary, 0, len(work.Editions))
+	for _, book := range work.Editions {
+		var publisher string
+		if book.Publisher != nil {
+			publisher = book.Publisher.Name
+		}
+		editions = append(editions, dto.EditionSummary{
+			ID:              book.ID,
+			Title:           book.Title,
+			ISBN:            book.ISBN,
+			Format:          string(book.Format),
+			Publisher:       publisher,
+			Language:        book.Language,
+			PublicationYear: book.PublicationYear,
+		})
+	}
+
+	return dto.WorkResponse{
+		ID:                 work.ID,
+		Title:              work.Title,
+		OriginalLanguage:   work.OriginalLanguage,
+		FirstPublishedYear: work.FirstPublishedYear,
+		SeriesID:           work.SeriesID,
+		SeriesPosition:     work.SeriesPosition,
+		Editions:           editions,
+	}
+}
@@ -0,0 +1,577 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-rest-api-ozgur/internal/dto"
+	"go-rest-api-ozgur/internal/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusCreated    = "created"
+	StatusUpdated    = "updated"
+	StatusUnchanged  = "unchanged"
+	StatusValid      = "valid"
+	StatusInvalid    = "invalid"
+	StatusFailed     = "failed"
+	StatusRolledBack = "rolled_back"
+)
+
+// pendingAuthorID stands in for authors and publishers that a dry run would create,
+// so the row can still be validated against dto.CreateBookRequest.
+const pendingAuthorID = ^uint(0)
+
+var errRollback = errors.New("import rolled back")
+
+// Options controls how rows are written.
+type Options struct {
+	// DryRun validates every row and reports what would happen without writing anything.
+	DryRun bool
+	// ChunkSize commits rows in transactions of this many rows, skipping invalid rows.
+	// Zero imports everything in one transaction that is rolled back if any row fails.
+	ChunkSize int
+	// Upsert updates the book with the same ISBN instead of rejecting the row, so feeds
+	// can be imported again and again.
+	Upsert bool
+	// Record, when set, is called in the transaction of a row for every book and author
+	// the row creates or updates, so the caller can keep their history. The entity is
+	// EntityBook or EntityAuthor, the action models.RevisionCreate or RevisionUpdate.
+	Record func(tx *gorm.DB, entity string, id uint, action string) error
+}
+
+// The entities passed to Options.Record.
+const (
+	EntityBook   = "book"
+	EntityAuthor = "author"
+)
+
+// Importer writes parsed rows to the database.
+type Importer struct {
+	db                *gorm.DB
+	authors           map[string]uint
+	created           []string
+	publishers        map[string]uint
+	imprints          map[string]uint
+	publishersCreated []string
+	imprintsCreated   []string
+	record            func(tx *gorm.DB, entity string, id uint, action string) error
+}
+
+func New(db *gorm.DB) *Importer {
+	return &Importer{db: db}
+}
+
+// Run imports the rows and reports the outcome of each. The returned error is only set
+// when the import could not run at all; row level problems are part of the report.
+func (im *Importer) Run(rows []ParsedRow, opts Options) (*dto.ImportReport, error) {
+	im.authors = make(map[string]uint)
+	im.created = nil
+	im.publishers = make(map[string]uint)
+	im.imprints = make(map[string]uint)
+	im.publishersCreated = nil
+	im.imprintsCreated = nil
+	im.record = opts.Record
+
+	report := &dto.ImportReport{
+		DryRun: opts.DryRun,
+		Mode:   "transaction",
+		Total:  len(rows),
+		Rows:   make([]dto.ImportRowResult, len(rows)),
+	}
+	chunkSize := len(rows)
+	if opts.ChunkSize > 0 {
+		report.Mode = "chunked"
+		chunkSize = opts.ChunkSize
+	}
+
+	seenISBN := make(map[string]int)
+	for start := 0; start < len(rows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := im.runChunk(rows, start, end, opts, report, seenISBN); err != nil {
+			return nil, err
+		}
+	}
+
+	report.AuthorsCreated = append([]string{}, im.created...)
+	report.PublishersCreated = append([]string{}, im.publishersCreated...)
+	for _, row := range rows {
+		for _, field := range row.Unrecognized {
+			if report.UnrecognizedFields == nil {
+				report.UnrecognizedFields = make(map[string]int)
+			}
+			report.UnrecognizedFields[field]++
+		}
+	}
+	for _, row := range report.Rows {
+		switch row.Status {
+		case StatusCreated:
+			report.Created++
+		case StatusUpdated:
+			report.Updated++
+		case StatusUnchanged:
+			report.Unchanged++
+		case StatusValid:
+			report.Valid++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+func (im *Importer) runChunk(rows []ParsedRow, start, end int, opts Options, report *dto.ImportReport, seenISBN map[string]int) error {
+	authorsBefore := len(im.created)
+	publishersBefore := len(im.publishersCreated)
+	imprintsBefore := len(im.imprintsCreated)
+
+	err := im.db.Transaction(func(tx *gorm.DB) error {
+		failed := false
+		for i := start; i < end; i++ {
+			result := &report.Rows[i]
+			*result = dto.ImportRowResult{Row: i + 1, Title: rows[i].Title}
+
+			existingID, errs := im.check(tx, rows[i], i+1, seenISBN, opts)
+			if len(errs) > 0 {
+				result.Status = StatusInvalid
+				result.Errors = errs
+				failed = true
+				continue
+			}
+			if opts.DryRun {
+				result.Status = StatusValid
+				result.BookID = existingID
+				continue
+			}
+
+			status, bookID := StatusCreated, existingID
+			var err error
+			action := models.RevisionCreate
+			if existingID != 0 {
+				status, action = StatusUpdated, models.RevisionUpdate
+				var changed bool
+				if changed, err = im.updateBook(tx, existingID, rows[i].Row); err == nil && !changed {
+					status = StatusUnchanged
+				}
+			} else {
+				bookID, err = im.createBook(tx, rows[i].Row)
+			}
+			if err == nil && status != StatusUnchanged {
+				err = im.recordRevision(tx, EntityBook, bookID, action)
+			}
+			if err != nil {
+				// Postgres aborts the transaction on a failed statement, so the rest of
+				// the chunk cannot be written either.
+				result.Status = StatusFailed
+				result.Errors = []string{err.Error()}
+				return errRollback
+			}
+			result.Status = status
+			result.BookID = bookID
+		}
+
+		if failed && opts.ChunkSize == 0 && !opts.DryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return err
+	}
+
+	if errors.Is(err, errRollback) {
+		for i := start; i < end; i++ {
+			switch report.Rows[i].Status {
+			case StatusCreated, StatusUpdated:
+				report.Rows[i].Status = StatusRolledBack
+				report.Rows[i].BookID = 0
+			case "":
+				// Rows after a failed write in the same transaction were never attempted.
+				report.Rows[i] = dto.ImportRowResult{
+					Row:    i + 1,
+					Title:  rows[i].Title,
+					Status: StatusRolledBack,
+					Errors: []string{"not imported because an earlier row in the same transaction failed"},
+				}
+			}
+		}
+		// Authors created in the rolled back transaction no longer exist.
+		for _, name := range im.created[authorsBefore:] {
+			delete(im.authors, authorKey(name))
+		}
+		im.created = im.created[:authorsBefore]
+		for _, name := range im.publishersCreated[publishersBefore:] {
+			delete(im.publishers, authorKey(name))
+		}
+		im.publishersCreated = im.publishersCreated[:publishersBefore]
+		for _, key := range im.imprintsCreated[imprintsBefore:] {
+			delete(im.imprints, key)
+		}
+		im.imprintsCreated = im.imprintsCreated[:imprintsBefore]
+	}
+	return nil
+}
+
+// check validates the row and then resolves its authors and publisher, so they are
+// only created for rows that will be imported. New books are validated with the rules
+// of dto.CreateBookRequest, books that an upsert updates with dto.UpdateBookRequest.
+// The ID of the book with the same ISBN is returned when the row updates it.
+func (im *Importer) check(tx *gorm.DB, row ParsedRow, line int, seenISBN map[string]int, opts Options) (uint, []string) {
+	if row.Err != nil {
+		return 0, []string{row.Err.Error()}
+	}
+
+	var contributors []dto.ContributorRequest
+	credited := make(map[string]bool, len(row.Authors))
+	for _, author := range row.Authors {
+		name := normalizeName(author.Name)
+		if name == "" {
+			return 0, []string{"author name is empty"}
+		}
+		credit := authorKey(name) + "|" + author.Role
+		if credited[credit] {
+			return 0, []string{fmt.Sprintf("%s is listed twice with the same role", name)}
+		}
+		credited[credit] = true
+		contributors = append(contributors, dto.ContributorRequest{AuthorID: pendingAuthorID, Role: author.Role})
+	}
+
+	var existingID uint
+	if isbn := normalizeISBN(row.ISBN); isbn != "" {
+		if first, ok := seenISBN[isbn]; ok {
+			return 0, []string{fmt.Sprintf("ISBN %s already appears in row %d", row.ISBN, first)}
+		}
+		var existing []uint
+		err := tx.Model(&models.Book{}).
+			Where(models.BookISBNKey+" IN ?", isbnVariants(isbn)).
+			Order("id").Limit(1).Pluck("id", &existing).Error
+		if err != nil {
+			return 0, []string{err.Error()}
+		}
+		if len(existing) > 0 {
+			if !opts.Upsert {
+				return 0, []string{fmt.Sprintf("a book with ISBN %s already exists", row.ISBN)}
+			}
+			existingID = existing[0]
+		}
+	}
+
+	var err error
+	if existingID != 0 {
+		err = binding.Validator.ValidateStruct(&dto.UpdateBookRequest{
+			Title:           row.Title,
+			Contributors:    contributors,
+			PublicationYear: row.PublicationYear,
+			Description:     row.Description,
+			Format:          row.Format,
+			PageCount:       row.PageCount,
+			Language:        row.Language,
+		})
+	} else {
+		err = binding.Validator.ValidateStruct(&dto.CreateBookRequest{
+			Title:           row.Title,
+			Contributors:    contributors,
+			ISBN:            row.ISBN,
+			PublicationYear: row.PublicationYear,
+			Description:     row.Description,
+			Format:          row.Format,
+			PageCount:       row.PageCount,
+			Language:        row.Language,
+		})
+	}
+	if err != nil {
+		return 0, strings.Split(err.Error(), "\n")
+	}
+
+	for _, author := range row.Authors {
+		if err := im.resolveAuthor(tx, normalizeName(author.Name), opts.DryRun); err != nil {
+			return 0, []string{err.Error()}
+		}
+	}
+	if err := im.resolvePublisher(tx, row.Row, opts.DryRun); err != nil {
+		return 0, []string{err.Error()}
+	}
+	seenISBN[normalizeISBN(row.ISBN)] = line
+	return existingID, nil
+}
+
+// resolveAuthor finds an author by case-insensitive name, creating it unless this is a
+// dry run. The ID is remembered for createBook.
+func (im *Importer) resolveAuthor(tx *gorm.DB, name string, dryRun bool) error {
+	key := authorKey(name)
+	if _, ok := im.authors[key]; ok {
+		return nil
+	}
+
+	var author models.Author
+	err := tx.Where("LOWER(name) = ?", key).Order("id").First(&author).Error
+	if err == nil {
+		im.authors[key] = author.ID
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	id := pendingAuthorID
+	if !dryRun {
+		author = models.Author{Name: name}
+		if err := tx.Create(&author).Error; err != nil {
+			return err
+		}
+		if err := im.recordRevision(tx, EntityAuthor, author.ID, models.RevisionCreate); err != nil {
+			return err
+		}
+		id = author.ID
+	}
+	im.authors[key] = id
+	im.created = append(im.created, name)
+	return nil
+}
+
+// resolvePublisher finds the publisher and imprint of the row by case-insensitive name,
+// creating them unless this is a dry run. A row that only names an imprint is filed
+// under a publisher of the same name.
+func (im *Importer) resolvePublisher(tx *gorm.DB, row Row, dryRun bool) error {
+	name := normalizeName(row.Publisher)
+	if name == "" {
+		name = normalizeName(row.Imprint)
+	}
+	if name == "" {
+		return nil
+	}
+
+	key := authorKey(name)
+	publisherID, ok := im.publishers[key]
+	if !ok {
+		var publisher models.Publisher
+		err := tx.Where("LOWER(name) = ?", key).Order("id").First(&publisher).Error
+		switch {
+		case err == nil:
+			publisherID = publisher.ID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		case dryRun:
+			publisherID = pendingAuthorID
+			im.publishersCreated = append(im.publishersCreated, name)
+		default:
+			publisher = models.Publisher{Name: name}
+			if err := tx.Create(&publisher).Error; err != nil {
+				return err
+			}
+			publisherID = publisher.ID
+			im.publishersCreated = append(im.publishersCreated, name)
+		}
+		im.publishers[key] = publisherID
+	}
+
+	imprint := normalizeName(row.Imprint)
+	if imprint == "" || normalizeName(row.Publisher) == "" {
+		return nil
+	}
+	imprintKey := imprintKey(publisherID, imprint)
+	if _, ok := im.imprints[imprintKey]; ok {
+		return nil
+	}
+	var existing models.Imprint
+	err := gorm.ErrRecordNotFound
+	if publisherID != pendingAuthorID {
+		err = tx.Where("publisher_id = ? AND LOWER(name) = ?", publisherID, authorKey(imprint)).Order("id").First(&existing).Error
+	}
+	switch {
+	case err == nil:
+		im.imprints[imprintKey] = existing.ID
+		return nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	case dryRun:
+		im.imprints[imprintKey] = pendingAuthorID
+	default:
+		existing = models.Imprint{PublisherID: publisherID, Name: imprint}
+		if err := tx.Create(&existing).Error; err != nil {
+			return err
+		}
+		im.imprints[imprintKey] = existing.ID
+	}
+	im.imprintsCreated = append(im.imprintsCreated, imprintKey)
+	return nil
+}
+
+// publisherIDs returns the publisher and imprint resolved for the row.
+func (im *Importer) publisherIDs(row Row) (*uint, *uint) {
+	name := normalizeName(row.Publisher)
+	if name == "" {
+		name = normalizeName(row.Imprint)
+	}
+	publisherID, ok := im.publishers[authorKey(name)]
+	if !ok {
+		return nil, nil
+	}
+	imprintID, ok := im.imprints[imprintKey(publisherID, normalizeName(row.Imprint))]
+	if !ok {
+		return &publisherID, nil
+	}
+	return &publisherID, &imprintID
+}
+
+// createBook writes a validated row as the first edition of a new work.
+func (im *Importer) createBook(tx *gorm.DB, row Row) (uint, error) {
+	work := models.Work{
+		Title:              row.Title,
+		OriginalLanguage:   row.Language,
+		FirstPublishedYear: row.PublicationYear,
+	}
+	if err := tx.Create(&work).Error; err != nil {
+		return 0, err
+	}
+
+	publisherID, imprintID := im.publisherIDs(row)
+	book := models.Book{
+		Title:           row.Title,
+		WorkID:          &work.ID,
+		PublisherID:     publisherID,
+		ImprintID:       imprintID,
+		ISBN:            row.ISBN,
+		PublicationYear: row.PublicationYear,
+		Description:     row.Description,
+		Format:          models.BookFormat(row.Format),
+		PageCount:       row.PageCount,
+		Language:        row.Language,
+		Contributors:    im.contributors(row),
+	}
+
+	if err := tx.Omit("Contributors.Author").Create(&book).Error; err != nil {
+		return 0, err
+	}
+	return book.ID, nil
+}
+
+// updateBook applies the non-empty fields of a row to an existing book. Contributors
+// are replaced when the row lists any. Fields that already hold the row's values are
+// left alone, and a row that changes nothing writes nothing, so importing the same feed
+// again leaves updated_at and the history as they were. It reports whether it wrote.
+func (im *Importer) updateBook(tx *gorm.DB, id uint, row Row) (bool, error) {
+	var book models.Book
+	err := tx.Preload("Contributors", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).First(&book, id).Error
+	if err != nil {
+		return false, err
+	}
+
+	updates := map[string]interface{}{}
+	for column, value := range map[string][2]string{
+		"title":       {row.Title, book.Title},
+		"description": {row.Description, book.Description},
+		"format":      {row.Format, string(book.Format)},
+		"language":    {row.Language, book.Language},
+	} {
+		if value[0] != "" && value[0] != value[1] {
+			updates[column] = value[0]
+		}
+	}
+	if row.PublicationYear != 0 && row.PublicationYear != book.PublicationYear {
+		updates["publication_year"] = row.PublicationYear
+	}
+	if row.PageCount != 0 && row.PageCount != book.PageCount {
+		updates["page_count"] = row.PageCount
+	}
+	if publisherID, imprintID := im.publisherIDs(row); publisherID != nil &&
+		(!sameID(publisherID, book.PublisherID) || !sameID(imprintID, book.ImprintID)) {
+		updates["publisher_id"] = *publisherID
+		updates["imprint_id"] = imprintID
+	}
+
+	var contributors []models.BookContributor
+	if len(row.Authors) > 0 {
+		contributors = im.contributors(row)
+		if sameContributors(contributors, book.Contributors) {
+			contributors = nil
+		}
+	}
+	if len(updates) == 0 && contributors == nil {
+		return false, nil
+	}
+
+	if len(updates) > 0 {
+		if err := tx.Model(&models.Book{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return false, err
+		}
+	}
+	if contributors == nil {
+		return true, nil
+	}
+	if err := tx.Where("book_id = ?", id).Delete(&models.BookContributor{}).Error; err != nil {
+		return false, err
+	}
+	for i := range contributors {
+		contributors[i].BookID = id
+	}
+	return true, tx.Omit("Author").Create(&contributors).Error
+}
+
+// sameContributors reports whether two credit lists name the same authors in the same
+// roles and order.
+func sameContributors(a, b []models.BookContributor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].AuthorID != b[i].AuthorID || a[i].Role != b[i].Role || a[i].Position != b[i].Position {
+			return false
+		}
+	}
+	return true
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// recordRevision hands a written book or author to Options.Record, if one was set.
+func (im *Importer) recordRevision(tx *gorm.DB, entity string, id uint, action string) error {
+	if im.record == nil {
+		return nil
+	}
+	return im.record(tx, entity, id, action)
+}
+
+// contributors credits the resolved authors of the row in the order they are listed.
+func (im *Importer) contributors(row Row) []models.BookContributor {
+	var contributors []models.BookContributor
+	for i, author := range row.Authors {
+		role := models.ContributorRole(author.Role)
+		if role == "" {
+			role = models.ContributorAuthor
+		}
+		contributors = append(contributors, models.BookContributor{
+			AuthorID: im.authors[authorKey(normalizeName(author.Name))],
+			Role:     role,
+			Position: i,
+		})
+	}
+	return contributors
+}
+
+// normalizeName collapses the whitespace in an author name.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func authorKey(name string) string {
+	return strings.ToLower(name)
+}
+
+func imprintKey(publisherID uint, name string) string {
+	return fmt.Sprintf("%d|%s", publisherID, strings.ToLower(name))
+}
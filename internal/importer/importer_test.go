@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"testing"
+
+	"go-rest-api-ozgur/internal/models"
+)
+
+func TestSameContributors(t *testing.T) {
+	stored := []models.BookContributor{
+		{BookID: 7, AuthorID: 1, Role: models.ContributorAuthor, Position: 0},
+		{BookID: 7, AuthorID: 2, Role: models.ContributorIllustrator, Position: 1},
+	}
+	for _, tc := range []struct {
+		name string
+		row  []models.BookContributor
+		want bool
+	}{
+		{"same credits", []models.BookContributor{
+			{AuthorID: 1, Role: models.ContributorAuthor, Position: 0},
+			{AuthorID: 2, Role: models.ContributorIllustrator, Position: 1},
+		}, true},
+		{"reordered", []models.BookContributor{
+			{AuthorID: 2, Role: models.ContributorIllustrator, Position: 0},
+			{AuthorID: 1, Role: models.ContributorAuthor, Position: 1},
+		}, false},
+		{"other role", []models.BookContributor{
+			{AuthorID: 1, Role: models.ContributorAuthor, Position: 0},
+			{AuthorID: 2, Role: models.ContributorTranslator, Position: 1},
+		}, false},
+		{"fewer", []models.BookContributor{
+			{AuthorID: 1, Role: models.ContributorAuthor, Position: 0},
+		}, false},
+	} {
+		if got := sameContributors(tc.row, stored); got != tc.want {
+			t.Errorf("%s: sameContributors = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSameID(t *testing.T) {
+	one, otherOne, two := uint(1), uint(1), uint(2)
+	for _, tc := range []struct {
+		a, b *uint
+		want bool
+	}{
+		{nil, nil, true},
+		{&one, nil, false},
+		{nil, &one, false},
+		{&one, &otherOne, true},
+		{&one, &two, false},
+	} {
+		if got := sameID(tc.a, tc.b); got != tc.want {
+			t.Errorf("sameID(%v, %v) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package importer
+
+import "strings"
+
+// normalizeISBN strips hyphens, spaces and qualifiers such as "(pbk.)" and converts
+// ISBN-10 to ISBN-13, so the same book is found however a feed writes its ISBN.
+// Values that are not a valid ISBN are returned without separators.
+func normalizeISBN(value string) string {
+	if i := strings.IndexAny(value, "( "); i > 0 && len(strings.TrimSpace(value[:i])) >= 10 {
+		value = value[:i]
+	}
+	value = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(value)))
+	if len(value) == 10 && validISBN10(value) {
+		return isbn10To13(value)
+	}
+	return value
+}
+
+// isbnVariants returns the spellings under which a normalized ISBN may already be
+// stored: the ISBN-13 and, for the 978 prefix, the equivalent ISBN-10.
+func isbnVariants(isbn string) []string {
+	variants := []string{isbn}
+	if len(isbn) == 13 && strings.HasPrefix(isbn, "978") && strings.Trim(isbn, "0123456789") == "" {
+		variants = append(variants, isbn13To10(isbn))
+	}
+	return variants
+}
+
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var digit int
+		switch {
+		case isbn[i] >= '0' && isbn[i] <= '9':
+			digit = int(isbn[i] - '0')
+		case isbn[i] == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func isbn10To13(isbn string) string {
+	body := "978" + isbn[:9]
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(body[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return body + string(rune('0'+(10-sum%10)%10))
+}
+
+func isbn13To10(isbn string) string {
+	body := isbn[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X"
+	}
+	return body + string(rune('0'+check))
+}
@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	for _, tc := range []struct {
+		value, want string
+	}{
+		{"9780261103573", "9780261103573"},
+		{"978-0-261-10357-3", "9780261103573"},
+		{" 978 0 261 10357 3 ", "9780261103573"},
+		{"9780261103573 (pbk.)", "9780261103573"},
+		{"0261103571", "9780261103573"},
+		{"0-8044-2957-X", "9780804429573"},
+		{"080442957x (hbk)", "9780804429573"},
+		// Not valid ISBN-10s, so they are only stripped of separators.
+		{"1234567890", "1234567890"},
+		{"12-3", "123"},
+		{"", ""},
+	} {
+		if got := normalizeISBN(tc.value); got != tc.want {
+			t.Errorf("normalizeISBN(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestISBNVariants(t *testing.T) {
+	for _, tc := range []struct {
+		isbn string
+		want []string
+	}{
+		{"9780261103573", []string{"9780261103573", "0261103571"}},
+		{"9780804429573", []string{"9780804429573", "080442957X"}},
+		// 979 ISBNs have no ISBN-10.
+		{"9791032305690", []string{"9791032305690"}},
+		{"978026110357X", []string{"978026110357X"}},
+		{"123", []string{"123"}},
+	} {
+		if got := isbnVariants(tc.isbn); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("isbnVariants(%q) = %q, want %q", tc.isbn, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	marcRecordTerminator = 0x1D
+	marcFieldTerminator  = 0x1E
+	marcSubfieldDelim    = 0x1F
+)
+
+// marcRecord is a MARC21 bibliographic record, read from ISO 2709 or MARCXML. Like
+// xmlNode it remembers which fields were read for the mapping report.
+type marcRecord struct {
+	Leader string
+	Fields []*marcField
+}
+
+type marcField struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Value     string // control fields only
+	Subfields []*marcSubfield
+	used      bool
+}
+
+type marcSubfield struct {
+	Code  byte
+	Value string
+	used  bool
+}
+
+// marcRelators maps relator terms ($e) and codes ($4) to contributor roles.
+var marcRelators = map[string]string{
+	"author":      "author",
+	"aut":         "author",
+	"editor":      "editor",
+	"edt":         "editor",
+	"translator":  "translator",
+	"trl":         "translator",
+	"illustrator": "illustrator",
+	"ill":         "illustrator",
+}
+
+var (
+	marcPages = regexp.MustCompile(`(\d+)\s*(?:p\b|p\.|pages)`)
+	marcYear  = regexp.MustCompile(`\d{4}`)
+)
+
+// parseMARC reads MARC21 records in ISO 2709 transmission format. Records must be
+// UTF-8 encoded (leader position 9 "a"); MARC-8 is not supported.
+func parseMARC(r io.Reader) ([]ParsedRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []ParsedRow
+	for _, raw := range bytes.Split(data, []byte{marcRecordTerminator}) {
+		raw = bytes.TrimLeft(raw, "\r\n\t ")
+		if len(raw) == 0 {
+			continue
+		}
+		record, err := decodeMARC(raw)
+		if err != nil {
+			rows = append(rows, ParsedRow{Err: err})
+			continue
+		}
+		rows = append(rows, mapMARC(record))
+	}
+	return rows, nil
+}
+
+func decodeMARC(raw []byte) (*marcRecord, error) {
+	if len(raw) < 24 {
+		return nil, errors.New("MARC record is shorter than its leader")
+	}
+	leader := string(raw[:24])
+	if leader[9] != 'a' && !utf8.Valid(raw) {
+		return nil, errors.New("MARC-8 encoded records are not supported, convert the file to UTF-8")
+	}
+	base, err := strconv.Atoi(leader[12:17])
+	if err != nil || base < 25 || base > len(raw) {
+		return nil, fmt.Errorf("MARC leader has an invalid base address %q", leader[12:17])
+	}
+
+	record := &marcRecord{Leader: leader}
+	directory := raw[24 : base-1]
+	for len(directory) >= 12 {
+		entry := string(directory[:12])
+		directory = directory[12:]
+		length, err1 := strconv.Atoi(entry[3:7])
+		start, err2 := strconv.Atoi(entry[7:12])
+		// Atoi accepts signs, so negative lengths and offsets have to be refused here.
+		if err1 != nil || err2 != nil || start < 0 || length < 0 || base+start < 24 || base+start+length > len(raw) {
+			return nil, fmt.Errorf("MARC directory entry %q is invalid", entry)
+		}
+		value := string(bytes.TrimRight(raw[base+start:base+start+length], string(rune(marcFieldTerminator))))
+		record.Fields = append(record.Fields, newMARCField(entry[:3], value))
+	}
+	return record, nil
+}
+
+// newMARCField splits the value of a data field into indicators and subfields.
+func newMARCField(tag, value string) *marcField {
+	field := &marcField{Tag: tag}
+	if strings.HasPrefix(tag, "00") {
+		field.Value = value
+		return field
+	}
+	if len(value) >= 2 {
+		field.Ind1, field.Ind2 = value[0], value[1]
+		value = value[2:]
+	}
+	for _, part := range strings.Split(value, string(rune(marcSubfieldDelim))) {
+		if part == "" {
+			continue
+		}
+		field.Subfields = append(field.Subfields, &marcSubfield{Code: part[0], Value: part[1:]})
+	}
+	return field
+}
+
+type marcXMLRecord struct {
+	Leader        string `xml:"leader"`
+	ControlFields []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	DataFields []struct {
+		Tag       string `xml:"tag,attr"`
+		Ind1      string `xml:"ind1,attr"`
+		Ind2      string `xml:"ind2,attr"`
+		Subfields []struct {
+			Code  string `xml:"code,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+// parseMARCXML reads the record elements of a MARCXML document, with or without a
+// surrounding collection.
+func parseMARCXML(r io.Reader) ([]ParsedRow, error) {
+	decoder := xml.NewDecoder(r)
+	var rows []ParsedRow
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var raw marcXMLRecord
+		if err := decoder.DecodeElement(&raw, &start); err != nil {
+			return nil, err
+		}
+		record := &marcRecord{Leader: raw.Leader}
+		for _, control := range raw.ControlFields {
+			record.Fields = append(record.Fields, &marcField{Tag: control.Tag, Value: control.Value})
+		}
+		for _, data := range raw.DataFields {
+			field := &marcField{Tag: data.Tag, Ind1: firstByte(data.Ind1), Ind2: firstByte(data.Ind2)}
+			for _, subfield := range data.Subfields {
+				field.Subfields = append(field.Subfields, &marcSubfield{Code: firstByte(subfield.Code), Value: subfield.Value})
+			}
+			record.Fields = append(record.Fields, field)
+		}
+		rows = append(rows, mapMARC(record))
+	}
+}
+
+func firstByte(value string) byte {
+	if value == "" {
+		return ' '
+	}
+	return value[0]
+}
+
+func (r *marcRecord) fields(tag string) []*marcField {
+	var matches []*marcField
+	for _, field := range r.Fields {
+		if field.Tag == tag {
+			matches = append(matches, field)
+		}
+	}
+	return matches
+}
+
+// control returns the value of a control field and marks it used.
+func (r *marcRecord) control(tag string) string {
+	for _, field := range r.Fields {
+		if field.Tag == tag {
+			field.used = true
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// subfield returns the first subfield with the given code and marks it used.
+func (f *marcField) subfield(code byte) string {
+	for _, subfield := range f.Subfields {
+		if subfield.Code == code {
+			subfield.used = true
+			return strings.TrimSpace(subfield.Value)
+		}
+	}
+	return ""
+}
+
+// unused lists the control fields and subfields nobody read, as "008" or "245$c".
+func (r *marcRecord) unused() []string {
+	var paths []string
+	for _, field := range r.Fields {
+		if field.Subfields == nil {
+			if !field.used {
+				paths = append(paths, field.Tag)
+			}
+			continue
+		}
+		for _, subfield := range field.Subfields {
+			if !subfield.used {
+				paths = append(paths, field.Tag+"$"+string(subfield.Code))
+			}
+		}
+	}
+	return paths
+}
+
+func mapMARC(record *marcRecord) ParsedRow {
+	var row ParsedRow
+	record.control("001")
+	record.control("003")
+	record.control("005")
+
+	if fixed := record.control("008"); len(fixed) >= 38 {
+		row.PublicationYear, _ = strconv.Atoi(fixed[7:11])
+		if language := strings.TrimSpace(fixed[35:38]); language != "" && language != "|||" {
+			row.Language = language
+		}
+	}
+
+	for _, field := range record.fields("020") {
+		if row.ISBN != "" {
+			break
+		}
+		isbn := field.subfield('a')
+		if isbn == "" {
+			continue
+		}
+		row.ISBN = normalizeISBN(isbn)
+		qualifier := field.subfield('q')
+		if open := strings.Index(isbn, "("); open >= 0 && qualifier == "" {
+			qualifier = isbn[open:]
+		}
+		row.Format = marcFormat(qualifier)
+	}
+	for _, field := range record.fields("041") {
+		if language := field.subfield('a'); language != "" {
+			row.Language = language
+		}
+	}
+
+	for _, tag := range []string{"100", "700"} {
+		for _, field := range record.fields(tag) {
+			name := trimISBD(field.subfield('a'))
+			if field.Ind1 == '1' {
+				name = uninvertName(name)
+			}
+			relator := strings.ToLower(trimISBD(field.subfield('e')))
+			if relator == "" {
+				relator = field.subfield('4')
+			}
+			role := "author"
+			if relator != "" {
+				var ok bool
+				if role, ok = marcRelators[relator]; !ok {
+					row.Unrecognized = append(row.Unrecognized, tag+"$e="+relator)
+					continue
+				}
+			}
+			if name != "" {
+				row.Authors = append(row.Authors, AuthorRef{Name: name, Role: role})
+			}
+		}
+	}
+
+	for _, field := range record.fields("245") {
+		row.Title = trimISBD(field.subfield('a'))
+		if subtitle := trimISBD(field.subfield('b')); subtitle != "" {
+			row.Title += ": " + subtitle
+		}
+		break
+	}
+
+	// RDA records use 264 with second indicator 1 for publication, older ones 260.
+	for _, field := range append(record.fields("264"), record.fields("260")...) {
+		if field.Tag == "264" && field.Ind2 != '1' {
+			continue
+		}
+		if row.Publisher == "" {
+			row.Publisher = strings.Trim(trimISBD(field.subfield('b')), "[]")
+		}
+		if year := marcYear.FindString(field.subfield('c')); year != "" {
+			row.PublicationYear, _ = strconv.Atoi(year)
+		}
+		break
+	}
+
+	for _, field := range record.fields("300") {
+		for _, match := range marcPages.FindAllStringSubmatch(field.subfield('a'), -1) {
+			if pages, _ := strconv.Atoi(match[1]); pages > row.PageCount {
+				row.PageCount = pages
+			}
+		}
+	}
+
+	for _, field := range record.fields("520") {
+		if row.Description == "" {
+			row.Description = field.subfield('a')
+		}
+	}
+
+	row.Unrecognized = append(row.Unrecognized, record.unused()...)
+	return row
+}
+
+// marcFormat guesses the format from an ISBN qualifier such as "(pbk.)".
+func marcFormat(qualifier string) string {
+	qualifier = strings.ToLower(qualifier)
+	switch {
+	case qualifier == "":
+		return ""
+	case strings.Contains(qualifier, "audio"):
+		return "audiobook"
+	case strings.Contains(qualifier, "ebook"), strings.Contains(qualifier, "e-book"),
+		strings.Contains(qualifier, "electronic"), strings.Contains(qualifier, "epub"):
+		return "ebook"
+	case strings.Contains(qualifier, "pbk"), strings.Contains(qualifier, "paperback"), strings.Contains(qualifier, "softcover"):
+		return "paperback"
+	case strings.Contains(qualifier, "hbk"), strings.Contains(qualifier, "hardcover"),
+		strings.Contains(qualifier, "hardback"), strings.Contains(qualifier, "cloth"):
+		return "hardcover"
+	}
+	return ""
+}
+
+// trimISBD strips the trailing ISBD punctuation that MARC keeps in field values, as in
+// "The title /" or "Doe, Jane,".
+func trimISBD(value string) string {
+	value = strings.TrimSpace(value)
+	for {
+		trimmed := strings.TrimSpace(strings.TrimRight(value, "/:;,="))
+		if strings.HasSuffix(trimmed, ".") && !strings.HasSuffix(trimmed, "..") && len(trimmed) > 2 &&
+			trimmed[len(trimmed)-3] != ' ' {
+			// Keep the period of initials like "J. R. R." but drop a closing one.
+			trimmed = strings.TrimSuffix(trimmed, ".")
+		}
+		if trimmed == value {
+			return value
+		}
+		value = trimmed
+	}
+}
@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMARCField is written as tag and value; data field values start with their two
+// indicators and separate subfields with $, which is replaced by the delimiter.
+type testMARCField struct {
+	tag, value string
+}
+
+// encodeMARC builds an ISO 2709 record the way a library system exports it.
+func encodeMARC(fields ...testMARCField) []byte {
+	var directory, data bytes.Buffer
+	for _, field := range fields {
+		value := strings.ReplaceAll(field.value, "$", string(rune(marcSubfieldDelim))) + string(rune(marcFieldTerminator))
+		fmt.Fprintf(&directory, "%s%04d%05d", field.tag, len(value), data.Len())
+		data.WriteString(value)
+	}
+	directory.WriteByte(marcFieldTerminator)
+	base := 24 + directory.Len()
+	length := base + data.Len() + 1
+	leader := fmt.Sprintf("%05dnam a22%05d   4500", length, base)
+	return append([]byte(leader+directory.String()+data.String()), marcRecordTerminator)
+}
+
+var fellowshipMARC = []testMARCField{
+	{"001", "rec-1"},
+	{"008", "540729s1954" + strings.Repeat(" ", 24) + "eng d"},
+	{"020", "  $a0261103571 (pbk.)"},
+	{"100", "1 $aTolkien, J. R. R.,$eauthor."},
+	{"245", "14$aThe fellowship of the ring :$bbeing the first part of The lord of the rings /$cJ.R.R. Tolkien."},
+	{"264", " 1$aLondon :$bAllen & Unwin,$c1991."},
+	{"300", "  $a423 p. ;$c24 cm"},
+	{"520", "  $aFrodo sets out from the Shire."},
+	{"700", "1 $aLee, Alan,$eillustrator."},
+	{"700", "1 $aInglis, Rob,$enarrator."},
+}
+
+func TestParseMARC(t *testing.T) {
+	rows, err := parseMARC(bytes.NewReader(encodeMARC(fellowshipMARC...)))
+	if err != nil {
+		t.Fatalf("parseMARC: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Err != nil {
+		t.Fatalf("parseMARC returned %+v, want one row without error", rows)
+	}
+	want := ParsedRow{
+		Row: Row{
+			Title: "The fellowship of the ring: being the first part of The lord of the rings",
+			Authors: []AuthorRef{
+				{Name: "J. R. R. Tolkien", Role: "author"},
+				{Name: "Alan Lee", Role: "illustrator"},
+			},
+			ISBN:            "9780261103573",
+			PublicationYear: 1991,
+			Description:     "Frodo sets out from the Shire.",
+			Format:          "paperback",
+			Language:        "eng",
+			PageCount:       423,
+			Publisher:       "Allen & Unwin",
+		},
+		Unrecognized: []string{"700$e=narrator", "245$c", "264$a", "300$c"},
+	}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("parseMARC mapped\n%+v\nwant\n%+v", rows[0], want)
+	}
+}
+
+func TestParseMARCXML(t *testing.T) {
+	document := `<collection xmlns="http://www.loc.gov/MARC21/slim"><record>
+		<leader>00000nam a2200000   4500</leader>
+		<controlfield tag="008">540729s1954` + strings.Repeat(" ", 24) + `ger d</controlfield>
+		<datafield tag="020" ind1=" " ind2=" "><subfield code="a">978-3-608-93981-1</subfield><subfield code="q">hardcover</subfield></datafield>
+		<datafield tag="041" ind1="1" ind2=" "><subfield code="a">ger</subfield></datafield>
+		<datafield tag="100" ind1="0" ind2=" "><subfield code="a">Tolkien</subfield></datafield>
+		<datafield tag="245" ind1="1" ind2="0"><subfield code="a">Die Gefährten.</subfield></datafield>
+		<datafield tag="260" ind1=" " ind2=" "><subfield code="b">[Klett-Cotta],</subfield><subfield code="c">c2000.</subfield></datafield>
+	</record></collection>`
+
+	rows, err := parseMARCXML(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("parseMARCXML: %v", err)
+	}
+	want := []ParsedRow{{Row: Row{
+		Title:           "Die Gefährten",
+		Authors:         []AuthorRef{{Name: "Tolkien", Role: "author"}},
+		ISBN:            "9783608939811",
+		PublicationYear: 2000,
+		Format:          "hardcover",
+		Language:        "ger",
+		Publisher:       "Klett-Cotta",
+	}}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("parseMARCXML mapped\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestDecodeMARCRejectsMalformedRecords(t *testing.T) {
+	valid := encodeMARC(testMARCField{"245", "10$aTitle"})
+	// The only directory entry follows the leader: tag, 4 digit length, 5 digit start.
+	withEntry := func(length, start string) []byte {
+		raw := append([]byte(nil), valid...)
+		copy(raw[27:31], length)
+		copy(raw[31:36], start)
+		return raw
+	}
+	withBase := func(base string) []byte {
+		raw := append([]byte(nil), valid...)
+		copy(raw[12:17], base)
+		return raw
+	}
+	marc8 := append([]byte(nil), valid...)
+	marc8[9] = ' '
+	marc8 = append(marc8[:len(marc8)-3], 0xE1, 'e', marcFieldTerminator, marcRecordTerminator)
+
+	for _, tc := range []struct {
+		name string
+		raw  []byte
+		want string
+	}{
+		{"shorter than the leader", valid[:20], "shorter than its leader"},
+		{"base address not a number", withBase("00a37"), "invalid base address"},
+		{"base address inside the leader", withBase("00010"), "invalid base address"},
+		{"base address past the end", withBase("99999"), "invalid base address"},
+		{"negative length", withEntry("-001", "00000"), "directory entry"},
+		{"negative start", withEntry("0005", "-9999"), "directory entry"},
+		{"field past the end", withEntry("9999", "00000"), "directory entry"},
+		{"start past the end", withEntry("0005", "99999"), "directory entry"},
+		{"MARC-8", marc8, "MARC-8"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := decodeMARC(bytes.TrimSuffix(tc.raw, []byte{marcRecordTerminator}))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("decodeMARC returned %+v, %v; want an error containing %q", record, err, tc.want)
+			}
+		})
+	}
+
+	if _, err := decodeMARC(bytes.TrimSuffix(valid, []byte{marcRecordTerminator})); err != nil {
+		t.Errorf("decodeMARC rejected the unaltered record: %v", err)
+	}
+}
+
+func TestParseMARCKeepsGoingAfterBadRecords(t *testing.T) {
+	bad := encodeMARC(testMARCField{"245", "10$aBroken"})
+	copy(bad[27:31], "-001")
+	good := encodeMARC(testMARCField{"245", "10$aWhole."})
+
+	rows, err := parseMARC(bytes.NewReader(append(append(bad, '\n'), good...)))
+	if err != nil {
+		t.Fatalf("parseMARC: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Err == nil || rows[1].Err != nil || rows[1].Title != "Whole" {
+		t.Errorf("parseMARC returned %+v, want the broken record's error and then the whole record", rows)
+	}
+}
+
+func TestTrimISBD(t *testing.T) {
+	for _, tc := range []struct {
+		value, want string
+	}{
+		{"The title /", "The title"},
+		{"Doe, Jane,", "Doe, Jane"},
+		{"Tolkien, J. R. R.", "Tolkien, J. R. R."},
+		{"London :", "London"},
+		{"author.", "author"},
+		{"Wait...", "Wait..."},
+	} {
+		if got := trimISBD(tc.value); got != tc.want {
+			t.Errorf("trimISBD(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
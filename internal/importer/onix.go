@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// onixShortTags maps the ONIX 3.0 short tags of the elements we read to their
+// reference names, so both flavours of a feed map the same way.
+var onixShortTags = map[string]string{
+	"product":           "Product",
+	"a001":              "RecordReference",
+	"a002":              "NotificationType",
+	"productidentifier": "ProductIdentifier",
+	"b221":              "ProductIDType",
+	"b244":              "IDValue",
+	"descriptivedetail": "DescriptiveDetail",
+	"b012":              "ProductForm",
+	"titledetail":       "TitleDetail",
+	"b202":              "TitleType",
+	"titleelement":      "TitleElement",
+	"x409":              "TitleElementLevel",
+	"b203":              "TitleText",
+	"b030":              "TitlePrefix",
+	"b031":              "TitleWithoutPrefix",
+	"b029":              "Subtitle",
+	"contributor":       "Contributor",
+	"b034":              "SequenceNumber",
+	"b035":              "ContributorRole",
+	"b036":              "PersonName",
+	"b037":              "PersonNameInverted",
+	"b039":              "NamesBeforeKey",
+	"b040":              "KeyNames",
+	"b047":              "CorporateName",
+	"language":          "Language",
+	"b253":              "LanguageRole",
+	"b252":              "LanguageCode",
+	"extent":            "Extent",
+	"b218":              "ExtentType",
+	"b219":              "ExtentValue",
+	"b220":              "ExtentUnit",
+	"collateraldetail":  "CollateralDetail",
+	"textcontent":       "TextContent",
+	"x426":              "TextType",
+	"d104":              "Text",
+	"publishingdetail":  "PublishingDetail",
+	"imprint":           "Imprint",
+	"b079":              "ImprintName",
+	"publisher":         "Publisher",
+	"b291":              "PublishingRole",
+	"b081":              "PublisherName",
+	"publishingdate":    "PublishingDate",
+	"x448":              "PublishingDateRole",
+	"b306":              "Date",
+}
+
+// onixRoles maps ONIX contributor role codes (list 17) to contributor roles.
+var onixRoles = map[string]string{
+	"A01": "author",
+	"B01": "editor",
+	"B06": "translator",
+	"A12": "illustrator",
+}
+
+// onixForms maps ONIX product form codes (list 150) to book formats.
+var onixForms = map[string]string{
+	"BB": "hardcover",
+	"BC": "paperback",
+	"EA": "ebook",
+	"EB": "ebook",
+	"EC": "ebook",
+	"ED": "ebook",
+	"AA": "audiobook",
+	"AB": "audiobook",
+	"AC": "audiobook",
+	"AE": "audiobook",
+	"AJ": "audiobook",
+	"AN": "audiobook",
+}
+
+// parseONIX reads the Product records of an ONIX 3.0 message, in reference or short
+// tags. The message header is skipped.
+func parseONIX(r io.Reader) ([]ParsedRow, error) {
+	decoder := xml.NewDecoder(r)
+	var rows []ParsedRow
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || onixName(start.Name.Local) != "Product" {
+			continue
+		}
+
+		var product xmlNode
+		if err := decoder.DecodeElement(&product, &start); err != nil {
+			return nil, err
+		}
+		product.canonicalize(onixName)
+		rows = append(rows, mapONIXProduct(&product))
+	}
+}
+
+func onixName(name string) string {
+	if reference, ok := onixShortTags[name]; ok {
+		return reference
+	}
+	return name
+}
+
+func mapONIXProduct(product *xmlNode) ParsedRow {
+	var row ParsedRow
+	product.text("RecordReference")
+	if product.text("NotificationType") == "05" {
+		row.Err = errors.New("delete notifications are not supported")
+	}
+
+	for _, code := range []string{"15", "02", "03"} {
+		for _, id := range product.children("ProductIdentifier") {
+			if id.text("ProductIDType") == code && row.ISBN == "" {
+				row.ISBN = normalizeISBN(id.text("IDValue"))
+			}
+		}
+	}
+
+	if detail := product.child("DescriptiveDetail"); detail != nil {
+		if form := detail.text("ProductForm"); form != "" {
+			row.Format = onixForms[form]
+			if row.Format == "" {
+				row.Unrecognized = append(row.Unrecognized, "DescriptiveDetail/ProductForm="+form)
+			}
+		}
+		row.Title = onixTitle(detail)
+
+		for _, contributor := range detail.children("Contributor") {
+			contributor.text("SequenceNumber")
+			code := contributor.text("ContributorRole")
+			role, ok := onixRoles[code]
+			if !ok {
+				row.Unrecognized = append(row.Unrecognized, "DescriptiveDetail/Contributor/ContributorRole="+code)
+				continue
+			}
+			if name := onixPersonName(contributor); name != "" {
+				row.Authors = append(row.Authors, AuthorRef{Name: name, Role: role})
+			}
+		}
+
+		for _, language := range detail.children("Language") {
+			if role := language.text("LanguageRole"); role == "01" && row.Language == "" {
+				row.Language = language.text("LanguageCode")
+			}
+		}
+
+		for _, extentType := range []string{"00", "11", "07"} {
+			for _, extent := range detail.children("Extent") {
+				if extent.text("ExtentType") == extentType && extent.text("ExtentUnit") == "03" && row.PageCount == 0 {
+					row.PageCount, _ = strconv.Atoi(extent.text("ExtentValue"))
+				}
+			}
+		}
+	}
+
+	if collateral := product.child("CollateralDetail"); collateral != nil {
+		for _, textType := range []string{"03", "02"} {
+			for _, content := range collateral.children("TextContent") {
+				if content.text("TextType") == textType && row.Description == "" {
+					if text := content.child("Text"); text != nil {
+						row.Description = text.innerText()
+					}
+				}
+			}
+		}
+	}
+
+	if publishing := product.child("PublishingDetail"); publishing != nil {
+		if imprint := publishing.child("Imprint"); imprint != nil {
+			row.Imprint = imprint.text("ImprintName")
+		}
+		for _, publisher := range publishing.children("Publisher") {
+			if role := publisher.text("PublishingRole"); (role == "" || role == "01") && row.Publisher == "" {
+				row.Publisher = publisher.text("PublisherName")
+			}
+		}
+		for _, date := range publishing.children("PublishingDate") {
+			if role := date.text("PublishingDateRole"); role == "01" && row.PublicationYear == 0 {
+				row.PublicationYear = leadingYear(date.text("Date"))
+			}
+		}
+	}
+
+	row.Unrecognized = append(row.Unrecognized, product.unusedLeaves("")...)
+	return row
+}
+
+// onixTitle builds the distinctive title of the product, including its subtitle.
+func onixTitle(detail *xmlNode) string {
+	for _, title := range detail.children("TitleDetail") {
+		if title.text("TitleType") != "01" {
+			continue
+		}
+		for _, element := range title.children("TitleElement") {
+			if level := element.text("TitleElementLevel"); level != "01" {
+				continue
+			}
+			text := element.text("TitleText")
+			if text == "" {
+				text = strings.TrimSpace(element.text("TitlePrefix") + " " + element.text("TitleWithoutPrefix"))
+			}
+			if subtitle := element.text("Subtitle"); subtitle != "" {
+				text += ": " + subtitle
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+func onixPersonName(contributor *xmlNode) string {
+	if name := contributor.text("PersonName"); name != "" {
+		return name
+	}
+	if key := contributor.text("KeyNames"); key != "" {
+		return strings.TrimSpace(contributor.text("NamesBeforeKey") + " " + key)
+	}
+	if inverted := contributor.text("PersonNameInverted"); inverted != "" {
+		return uninvertName(inverted)
+	}
+	return contributor.text("CorporateName")
+}
+
+// uninvertName turns "Doe, Jane" into "Jane Doe".
+func uninvertName(name string) string {
+	last, first, ok := strings.Cut(name, ",")
+	if !ok {
+		return strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+}
+
+// leadingYear reads the year at the start of a date such as 20240131 or 2024-01.
+func leadingYear(date string) int {
+	date = strings.TrimSpace(date)
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
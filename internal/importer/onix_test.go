@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseONIX(t *testing.T) {
+	reference := `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+	<Header><Sender><SenderName>Allen &amp; Unwin</SenderName></Sender></Header>
+	<Product>
+		<RecordReference>au-0261103571</RecordReference>
+		<NotificationType>03</NotificationType>
+		<ProductIdentifier><ProductIDType>02</ProductIDType><IDValue>0261103571</IDValue></ProductIdentifier>
+		<ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>978-0-261-10357-3</IDValue></ProductIdentifier>
+		<DescriptiveDetail>
+			<ProductForm>BC</ProductForm>
+			<TitleDetail>
+				<TitleType>01</TitleType>
+				<TitleElement>
+					<TitleElementLevel>01</TitleElementLevel>
+					<TitlePrefix>The</TitlePrefix>
+					<TitleWithoutPrefix>Fellowship of the Ring</TitleWithoutPrefix>
+					<Subtitle>Being the First Part of The Lord of the Rings</Subtitle>
+				</TitleElement>
+			</TitleDetail>
+			<Contributor><SequenceNumber>1</SequenceNumber><ContributorRole>A01</ContributorRole><PersonNameInverted>Tolkien, J. R. R.</PersonNameInverted></Contributor>
+			<Contributor><SequenceNumber>2</SequenceNumber><ContributorRole>A12</ContributorRole><NamesBeforeKey>Alan</NamesBeforeKey><KeyNames>Lee</KeyNames></Contributor>
+			<Contributor><SequenceNumber>3</SequenceNumber><ContributorRole>E07</ContributorRole><PersonName>Rob Inglis</PersonName></Contributor>
+			<Language><LanguageRole>01</LanguageRole><LanguageCode>eng</LanguageCode></Language>
+			<Extent><ExtentType>00</ExtentType><ExtentValue>423</ExtentValue><ExtentUnit>03</ExtentUnit></Extent>
+		</DescriptiveDetail>
+		<CollateralDetail>
+			<TextContent><TextType>02</TextType><Text>Short copy.</Text></TextContent>
+			<TextContent><TextType>03</TextType><Text>Frodo sets out <i>from the Shire</i>.</Text></TextContent>
+		</CollateralDetail>
+		<PublishingDetail>
+			<Imprint><ImprintName>HarperCollins</ImprintName></Imprint>
+			<Publisher><PublishingRole>01</PublishingRole><PublisherName>Allen &amp; Unwin</PublisherName></Publisher>
+			<PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>19910704</Date></PublishingDate>
+		</PublishingDetail>
+		<ProductSupply><Market>GB</Market></ProductSupply>
+	</Product>
+	<Product>
+		<RecordReference>au-withdrawn</RecordReference>
+		<NotificationType>05</NotificationType>
+	</Product>
+</ONIXMessage>`
+
+	rows, err := parseONIX(strings.NewReader(reference))
+	if err != nil {
+		t.Fatalf("parseONIX: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("parseONIX returned %d rows, want 2", len(rows))
+	}
+	want := ParsedRow{
+		Row: Row{
+			Title: "The Fellowship of the Ring: Being the First Part of The Lord of the Rings",
+			Authors: []AuthorRef{
+				{Name: "J. R. R. Tolkien", Role: "author"},
+				{Name: "Alan Lee", Role: "illustrator"},
+			},
+			ISBN:            "9780261103573",
+			PublicationYear: 1991,
+			Description:     "Frodo sets out from the Shire.",
+			Format:          "paperback",
+			Language:        "eng",
+			PageCount:       423,
+			Publisher:       "Allen & Unwin",
+			Imprint:         "HarperCollins",
+		},
+		// The ISBN-10, the short copy and the unknown contributor are not used either.
+		Unrecognized: []string{
+			"DescriptiveDetail/Contributor/ContributorRole=E07",
+			"ProductIdentifier/IDValue",
+			"DescriptiveDetail/Contributor/PersonName",
+			"CollateralDetail/TextContent/Text",
+			"ProductSupply/Market",
+		},
+	}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("parseONIX mapped\n%+v\nwant\n%+v", rows[0], want)
+	}
+	if rows[1].Err == nil {
+		t.Error("a delete notification was accepted")
+	}
+}
+
+func TestParseONIXShortTags(t *testing.T) {
+	short := `<ONIXmessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/short">
+	<product>
+		<a001>au-1</a001>
+		<productidentifier><b221>15</b221><b244>9780261103573</b244></productidentifier>
+		<descriptivedetail>
+			<b012>EA</b012>
+			<titledetail><b202>01</b202><titleelement><x409>01</x409><b203>The Fellowship of the Ring</b203></titleelement></titledetail>
+			<contributor><b035>A01</b035><b036>J. R. R. Tolkien</b036></contributor>
+		</descriptivedetail>
+		<publishingdetail>
+			<publisher><b081>Allen &amp; Unwin</b081></publisher>
+			<publishingdate><x448>01</x448><b306>2012-02</b306></publishingdate>
+		</publishingdetail>
+	</product>
+</ONIXmessage>`
+
+	rows, err := parseONIX(strings.NewReader(short))
+	if err != nil {
+		t.Fatalf("parseONIX: %v", err)
+	}
+	want := []ParsedRow{{Row: Row{
+		Title:           "The Fellowship of the Ring",
+		Authors:         []AuthorRef{{Name: "J. R. R. Tolkien", Role: "author"}},
+		ISBN:            "9780261103573",
+		PublicationYear: 2012,
+		Format:          "ebook",
+		Publisher:       "Allen & Unwin",
+	}}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("parseONIX mapped short tags to\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestParseONIXMalformed(t *testing.T) {
+	if _, err := parseONIX(strings.NewReader(`<ONIXMessage><Product><RecordReference>1</Product>`)); err == nil {
+		t.Error("parseONIX accepted a malformed document")
+	}
+}
@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatONIX    Format = "onix"
+	FormatMARC    Format = "marc"
+	FormatMARCXML Format = "marcxml"
+)
+
+// AuthorRef names a contributor of an imported book. Authors are matched by name and
+// created when no author with that name exists yet.
+type AuthorRef struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Row is one book of an import file.
+type Row struct {
+	Title           string      `json:"title"`
+	Authors         []AuthorRef `json:"authors"`
+	ISBN            string      `json:"isbn"`
+	PublicationYear int         `json:"publication_year"`
+	Description     string      `json:"description"`
+	Format          string      `json:"format"`
+	Language        string      `json:"language"`
+	PageCount       int         `json:"page_count"`
+	Publisher       string      `json:"publisher"`
+	Imprint         string      `json:"imprint"`
+}
+
+// ParsedRow is a row together with the error that kept it from being parsed, if any.
+// Unrecognized lists the source fields that could not be mapped onto a book, such as
+// ONIX element paths or MARC tags and subfields.
+type ParsedRow struct {
+	Row
+	Err          error
+	Unrecognized []string
+}
+
+// Parse reads every row of an import file. Rows that cannot be parsed are returned with
+// their error so they show up in the report; only unreadable input fails the whole parse.
+func Parse(r io.Reader, format Format) ([]ParsedRow, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatNDJSON:
+		return parseNDJSON(r)
+	case FormatONIX:
+		return parseONIX(r)
+	case FormatMARC:
+		return parseMARC(r)
+	case FormatMARCXML:
+		return parseMARCXML(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// parseCSV expects a header row. Recognised columns are title, authors, isbn,
+// publication_year, description, format, language, page_count, publisher and imprint. Authors are
+// separated by ";" and may carry a role after "|", e.g. "Jane Doe;John Roe|translator".
+func parseCSV(r io.Reader) ([]ParsedRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("CSV header has no title column")
+	}
+
+	var rows []ParsedRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, ParsedRow{Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var row ParsedRow
+		row.Title = field("title")
+		row.Authors = parseAuthorList(field("authors"))
+		row.ISBN = field("isbn")
+		row.Description = field("description")
+		row.Format = field("format")
+		row.Language = field("language")
+		row.Publisher = field("publisher")
+		row.Imprint = field("imprint")
+		row.PublicationYear, row.Err = atoiField("publication_year", field("publication_year"))
+		if row.Err == nil {
+			row.PageCount, row.Err = atoiField("page_count", field("page_count"))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseNDJSON expects one JSON object per line. Authors may be given as objects or as
+// plain name strings. Blank lines are skipped.
+func parseNDJSON(r io.Reader) ([]ParsedRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var rows []ParsedRow
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var raw struct {
+			Row
+			Authors []json.RawMessage `json:"authors"`
+		}
+		var row ParsedRow
+		if err := json.Unmarshal(line, &raw); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %w", err)
+			rows = append(rows, row)
+			continue
+		}
+		row.Row = raw.Row
+		row.Authors = nil
+		for _, author := range raw.Authors {
+			var ref AuthorRef
+			var name string
+			if err := json.Unmarshal(author, &name); err == nil {
+				ref.Name = name
+			} else if err := json.Unmarshal(author, &ref); err != nil {
+				row.Err = fmt.Errorf("invalid author %s", author)
+				break
+			}
+			row.Authors = append(row.Authors, ref)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func parseAuthorList(value string) []AuthorRef {
+	var authors []AuthorRef
+	for _, entry := range strings.Split(value, ";") {
+		name, role, _ := strings.Cut(entry, "|")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		authors = append(authors, AuthorRef{Name: name, Role: strings.TrimSpace(role)})
+	}
+	return authors
+}
+
+func atoiField(name, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", name, value)
+	}
+	return n, nil
+}
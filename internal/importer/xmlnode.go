@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// xmlNode is a generic XML element. Mappers read it through text, child and children,
+// which remember what was used, so every leaf nobody read can be reported as an
+// unrecognized field.
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []*xmlNode `xml:",any"`
+	Text     string     `xml:",chardata"`
+	Inner    string     `xml:",innerxml"`
+
+	used bool
+}
+
+// canonicalize renames the element and its descendants with rename.
+func (n *xmlNode) canonicalize(rename func(string) string) {
+	n.XMLName.Local = rename(n.XMLName.Local)
+	for _, child := range n.Children {
+		child.canonicalize(rename)
+	}
+}
+
+func (n *xmlNode) children(name string) []*xmlNode {
+	var matches []*xmlNode
+	for _, child := range n.Children {
+		if child.XMLName.Local == name {
+			matches = append(matches, child)
+		}
+	}
+	return matches
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	for _, child := range n.Children {
+		if child.XMLName.Local == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// text returns the trimmed text of the first child with the given name and marks it used.
+func (n *xmlNode) text(name string) string {
+	child := n.child(name)
+	if child == nil {
+		return ""
+	}
+	child.used = true
+	return strings.TrimSpace(child.Text)
+}
+
+// innerText returns all text below the element, e.g. of XHTML markup, and marks the
+// whole subtree used.
+func (n *xmlNode) innerText() string {
+	var markUsed func(*xmlNode)
+	markUsed = func(node *xmlNode) {
+		node.used = true
+		for _, child := range node.Children {
+			markUsed(child)
+		}
+	}
+	markUsed(n)
+
+	var b strings.Builder
+	decoder := xml.NewDecoder(strings.NewReader(n.Inner))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			if t.Name.Local == "br" {
+				b.WriteByte(' ')
+			}
+		case xml.EndElement:
+			// Separate paragraphs and list items, but not inline markup like <em>.
+			switch t.Name.Local {
+			case "p", "div", "li", "h1", "h2", "h3", "h4", "h5", "h6":
+				b.WriteByte(' ')
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// unusedLeaves lists the paths of leaf elements below n that were never read.
+func (n *xmlNode) unusedLeaves(prefix string) []string {
+	var paths []string
+	for _, child := range n.Children {
+		path := prefix + child.XMLName.Local
+		if len(child.Children) == 0 {
+			if !child.used {
+				paths = append(paths, path)
+			}
+			continue
+		}
+		paths = append(paths, child.unusedLeaves(path+"/")...)
+	}
+	return paths
+}
@@ -0,0 +1,77 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixtureProvider(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"9780261103573.json": `{"title": "The Fellowship of the Ring", "authors": ["J.R.R. Tolkien"], "cover_url": "https://covers.example.com/fellowship.jpg"}`,
+		"fellowship.jpg":     "jpeg bytes",
+		"notes.txt":          "not a record",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := NewFixtureProvider(dir)
+	if err != nil {
+		t.Fatalf("NewFixtureProvider: %v", err)
+	}
+	ctx := context.Background()
+
+	record, err := p.Lookup(ctx, "9780261103573")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if record.ISBN != "9780261103573" || record.Title != "The Fellowship of the Ring" {
+		t.Errorf("Lookup returned %+v, want the record keyed by its file name", record)
+	}
+	// Callers may edit the record without changing the fixture.
+	record.Authors[0] = "Someone Else"
+	if again, _ := p.Lookup(ctx, "9780261103573"); again.Authors[0] != "J.R.R. Tolkien" {
+		t.Errorf("editing a looked up record changed the fixture to %v", again.Authors)
+	}
+
+	cover, err := p.FetchCover(ctx, record)
+	if err != nil || string(cover) != "jpeg bytes" {
+		t.Errorf("FetchCover returned %q (%v), want the file named by the cover URL", cover, err)
+	}
+
+	if _, err := p.Lookup(ctx, "9780000000002"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup of an unknown ISBN returned %v, want ErrNotFound", err)
+	}
+	if _, err := p.FetchCover(ctx, &Record{CoverURL: "https://covers.example.com/missing.jpg"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchCover of a missing file returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestFixtureProviderErrors(t *testing.T) {
+	if _, err := NewFixtureProvider(""); err == nil {
+		t.Error("NewFixtureProvider without a directory succeeded")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "9780261103573.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFixtureProvider(dir); err == nil {
+		t.Error("NewFixtureProvider accepted a malformed record")
+	}
+
+	static := NewStaticProvider(Record{ISBN: "9780261103573", CoverURL: "https://covers.example.com/fellowship.jpg"})
+	record, err := static.Lookup(context.Background(), "9780261103573")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if _, err := static.FetchCover(context.Background(), record); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a static provider fetched a cover with %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,207 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCoverDownload bounds the size of downloaded cover images.
+const maxCoverDownload = 10 << 20
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// OpenLibrary looks books up with the Open Library JSON API, or any service that
+// serves the same /isbn/{isbn}.json, /works/{id}.json and /authors/{id}.json documents.
+type OpenLibrary struct {
+	baseURL   string
+	coversURL string
+	client    *http.Client
+}
+
+// NewOpenLibrary creates a provider for the API at baseURL, defaulting to
+// https://openlibrary.org, with cover images from coversURL.
+func NewOpenLibrary(baseURL, coversURL string, client *http.Client) *OpenLibrary {
+	if baseURL == "" {
+		baseURL = "https://openlibrary.org"
+	}
+	if coversURL == "" {
+		coversURL = "https://covers.openlibrary.org"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &OpenLibrary{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		coversURL: strings.TrimSuffix(coversURL, "/"),
+		client:    client,
+	}
+}
+
+func (o *OpenLibrary) Name() string {
+	return "openlibrary"
+}
+
+type olKey struct {
+	Key string `json:"key"`
+}
+
+// olText is a description, which Open Library stores either as a plain string or as
+// {"type": "/type/text", "value": "..."}.
+type olText string
+
+func (t *olText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = olText(s)
+		return nil
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	*t = olText(typed.Value)
+	return nil
+}
+
+type olEdition struct {
+	Title         string   `json:"title"`
+	Subtitle      string   `json:"subtitle"`
+	Authors       []olKey  `json:"authors"`
+	Works         []olKey  `json:"works"`
+	Description   olText   `json:"description"`
+	NumberOfPages int      `json:"number_of_pages"`
+	PublishDate   string   `json:"publish_date"`
+	Publishers    []string `json:"publishers"`
+	Covers        []int    `json:"covers"`
+}
+
+type olWork struct {
+	Description olText `json:"description"`
+	Authors     []struct {
+		Author olKey `json:"author"`
+	} `json:"authors"`
+}
+
+type olAuthor struct {
+	Name string `json:"name"`
+}
+
+func (o *OpenLibrary) Lookup(ctx context.Context, isbn string) (*Record, error) {
+	var edition olEdition
+	if err := o.getJSON(ctx, "/isbn/"+url.PathEscape(isbn)+".json", &edition); err != nil {
+		return nil, err
+	}
+
+	record := &Record{
+		ISBN:        isbn,
+		Title:       edition.Title,
+		Description: strings.TrimSpace(string(edition.Description)),
+		PageCount:   edition.NumberOfPages,
+	}
+	if edition.Subtitle != "" {
+		record.Title += ": " + edition.Subtitle
+	}
+	if year := yearPattern.FindString(edition.PublishDate); year != "" {
+		record.PublicationYear, _ = strconv.Atoi(year)
+	}
+	if len(edition.Publishers) > 0 {
+		record.Publisher = edition.Publishers[0]
+	}
+	if len(edition.Covers) > 0 && edition.Covers[0] > 0 {
+		record.CoverURL = fmt.Sprintf("%s/b/id/%d-L.jpg", o.coversURL, edition.Covers[0])
+	}
+
+	// Editions often leave the description and authors to their work.
+	authorKeys := edition.Authors
+	if len(edition.Works) > 0 && (record.Description == "" || len(authorKeys) == 0) {
+		var work olWork
+		if err := o.getJSON(ctx, edition.Works[0].Key+".json", &work); err == nil {
+			if record.Description == "" {
+				record.Description = strings.TrimSpace(string(work.Description))
+			}
+			if len(authorKeys) == 0 {
+				for _, author := range work.Authors {
+					authorKeys = append(authorKeys, author.Author)
+				}
+			}
+		}
+	}
+	for _, key := range authorKeys {
+		var author olAuthor
+		if err := o.getJSON(ctx, key.Key+".json", &author); err != nil {
+			return nil, err
+		}
+		if author.Name != "" {
+			record.Authors = append(record.Authors, author.Name)
+		}
+	}
+	return record, nil
+}
+
+func (o *OpenLibrary) FetchCover(ctx context.Context, record *Record) ([]byte, error) {
+	if record.CoverURL == "" {
+		return nil, ErrNotFound
+	}
+	// Without default=false Open Library answers missing covers with a blank image.
+	coverURL := record.CoverURL
+	if !strings.Contains(coverURL, "?") {
+		coverURL += "?default=false"
+	}
+	resp, err := o.get(ctx, coverURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverDownload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCoverDownload {
+		return nil, fmt.Errorf("cover at %s is larger than %d bytes", record.CoverURL, maxCoverDownload)
+	}
+	return data, nil
+}
+
+func (o *OpenLibrary) getJSON(ctx context.Context, path string, v interface{}) error {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	resp, err := o.get(ctx, o.baseURL+path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// get performs a GET request and turns 404 into ErrNotFound and other failures into errors.
+func (o *OpenLibrary) get(ctx context.Context, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "BookLAB catalog enrichment")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s failed with %s", target, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,50 @@
+// Package metadata looks up bibliographic data about a book by ISBN in external
+// databases, so curators do not have to type it in by hand.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-rest-api-ozgur/internal/config"
+)
+
+// ErrNotFound is returned when the provider knows no book with the ISBN.
+var ErrNotFound = errors.New("no metadata found for this ISBN")
+
+// Record is what a provider knows about one edition. Zero values mean unknown.
+type Record struct {
+	ISBN            string   `json:"isbn"`
+	Title           string   `json:"title"`
+	Authors         []string `json:"authors"`
+	Description     string   `json:"description"`
+	PageCount       int      `json:"page_count"`
+	PublicationYear int      `json:"publication_year"`
+	Publisher       string   `json:"publisher"`
+	CoverURL        string   `json:"cover_url"`
+}
+
+// MetadataProvider fetches book metadata from an external source.
+type MetadataProvider interface {
+	// Name identifies the provider in enrichment proposals.
+	Name() string
+	Lookup(ctx context.Context, isbn string) (*Record, error)
+	// FetchCover downloads the cover image of a record with a CoverURL.
+	FetchCover(ctx context.Context, record *Record) ([]byte, error)
+}
+
+// New builds the provider selected by METADATA_PROVIDER. It returns nil when
+// enrichment is turned off.
+func New(cfg *config.Config) (MetadataProvider, error) {
+	switch cfg.MetadataProvider {
+	case "", "openlibrary":
+		return NewOpenLibrary(cfg.MetadataBaseURL, cfg.MetadataCoversURL, nil), nil
+	case "fixture":
+		return NewFixtureProvider(cfg.MetadataFixtureDir)
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown metadata provider %q", cfg.MetadataProvider)
+	}
+}
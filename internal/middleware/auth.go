@@ -23,6 +23,32 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
+
+// OptionalAuth identifies the user when a valid token is sent but lets anonymous
+// requests through, so public routes can still tell who made a change. An expired or
+// malformed token is ignored rather than refused: public reads must keep working for
+// clients holding a stale token, and only AuthRequired answers 401.
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.GetHeader("Authorization")
+		if tokenString == "" {
+			c.Next()
+			return
+		}
+
+		claims, err := utils.ValidateToken(tokenString)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
 		c.Next()
@@ -40,3 +66,16 @@ func AdminOnly() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ModeratorOnly lets admins and moderators through.
+func ModeratorOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role != "admin" && role != "moderator" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Moderator access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,11 +1,91 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
 
 type Author struct {
 	gorm.Model
-	Name      string
-	Biography string
-	BirthDate string
-	Books     []Book
+	Name          string
+	NameKey       string `gorm:"index"`
+	SortKey       string `gorm:"index"`
+	Biography     string
+	Born          PartialDate `gorm:"embedded;embeddedPrefix:born_"`
+	Died          PartialDate `gorm:"embedded;embeddedPrefix:died_"`
+	Nationality   string
+	Aliases       []AuthorAlias     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Contributions []BookContributor `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	// CascadeID tags the rows a cascading delete soft deleted together, see Review.CascadeID.
+	CascadeID *string `gorm:"type:varchar(16);index"`
+}
+
+// BeforeSave keeps the search and sort keys in step with the name.
+func (a *Author) BeforeSave(tx *gorm.DB) error {
+	a.NameKey = SearchKey(a.Name)
+	a.SortKey = SortKey(a.Name)
+	return nil
+}
+
+// AuthorAlias is another name an author is known by, such as a pen name.
+type AuthorAlias struct {
+	ID       uint   `gorm:"primarykey"`
+	AuthorID uint   `gorm:"uniqueIndex:idx_author_alias;not null"`
+	Name     string `gorm:"uniqueIndex:idx_author_alias;not null"`
+	NameKey  string `gorm:"index"`
+}
+
+// BeforeSave keeps the search key in step with the name.
+func (a *AuthorAlias) BeforeSave(tx *gorm.DB) error {
+	a.NameKey = SearchKey(a.Name)
+	return nil
+}
+
+// foldedLetters spells out letters that do not decompose into a base letter and accents.
+var foldedLetters = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "ł", "l", "đ", "d", "ð", "d", "þ", "th", "ı", "i")
+
+// SearchKey reduces a name or title to lowercase letters and digits without accents,
+// so "J.R.R. Tolkien" and "J. R. R. Tolkién" compare equal.
+func SearchKey(value string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(foldedLetters.Replace(strings.ToLower(value))) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// nameSuffixes are left out when looking for the surname at the end of a name.
+var nameSuffixes = map[string]bool{"jr": true, "sr": true, "ii": true, "iii": true, "iv": true}
+
+// SortKey orders authors by surname: "Stephen King" sorts as "king stephen". A name
+// written as "King, Stephen" is taken to give the surname first; otherwise the last
+// word is the surname.
+func SortKey(name string) string {
+	surname, rest := name, ""
+	if i := strings.Index(name, ","); i >= 0 {
+		surname, rest = name[:i], name[i+1:]
+	} else if words := strings.Fields(name); len(words) > 1 {
+		last := len(words) - 1
+		for last > 0 && nameSuffixes[SearchKey(words[last])] {
+			last--
+		}
+		if last > 0 {
+			surname = words[last]
+			rest = strings.Join(append(append([]string{}, words[:last]...), words[last+1:]...), " ")
+		}
+	}
+	var parts []string
+	for _, part := range []string{surname, rest} {
+		if key := SearchKey(part); key != "" {
+			parts = append(parts, key)
+		}
+	}
+	return strings.Join(parts, " ")
 }
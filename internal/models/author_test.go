@@ -0,0 +1,50 @@
+package models
+
+import "testing"
+
+func TestSearchKey(t *testing.T) {
+	for _, tc := range []struct {
+		value, want string
+	}{
+		{"J.R.R. Tolkien", "jrrtolkien"},
+		{"J. R. R. Tolkién", "jrrtolkien"},
+		{"  Gabriel García Márquez ", "gabrielgarciamarquez"},
+		{"Søren Kierkegaard", "sorenkierkegaard"},
+		{"Straße", "strasse"},
+		{"Ærø", "aero"},
+		{"Łódź", "lodz"},
+		{"Þórbergur Þórðarson", "thorbergurthordarson"},
+		{"İstanbul", "istanbul"},
+		{"Catch-22", "catch22"},
+		{"Фёдор Достоевский", "федордостоевскии"},
+		{"村上春樹", "村上春樹"},
+		{"...", ""},
+		{"", ""},
+	} {
+		if got := SearchKey(tc.value); got != tc.want {
+			t.Errorf("SearchKey(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"Stephen King", "king stephen"},
+		{"King, Stephen", "king stephen"},
+		{"Tolkien, J. R. R.", "tolkien jrr"},
+		{"J. R. R. Tolkien", "tolkien jrr"},
+		{"Gabriel García Márquez", "marquez gabrielgarcia"},
+		// Suffixes are not taken for the surname.
+		{"Martin Luther King Jr.", "king martinlutherjr"},
+		{"Plato", "plato"},
+		{"Jr.", "jr"},
+		{"Madonna,", "madonna"},
+		{"", ""},
+	} {
+		if got := SortKey(tc.name); got != tc.want {
+			t.Errorf("SortKey(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -2,13 +2,47 @@ package models
 
 import "gorm.io/gorm"
 
+// Book is a single edition of a Work.
 type Book struct {
 	gorm.Model
 	Title           string
-	AuthorID        uint
-	Author          Author
+	WorkID          *uint `gorm:"index"`
+	Work            *Work
+	Contributors    []BookContributor `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	ISBN            string
 	PublicationYear int
 	Description     string
-	Reviews         []Review
+	Format          BookFormat `gorm:"type:varchar(32)"`
+	PublisherID     *uint      `gorm:"index"`
+	Publisher       *Publisher
+	ImprintID       *uint `gorm:"index"`
+	Imprint         *Imprint
+	PageCount       int
+	Language        string
+	CoverKey        string
+	Reviews         []Review   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Genres          []Genre    `gorm:"many2many:book_genres;constraint:OnDelete:CASCADE;"`
+	Tags            []Tag      `gorm:"many2many:book_tags;constraint:OnDelete:CASCADE;"`
+	Rating          BookRating `gorm:"embedded;embeddedPrefix:rating_"`
+	// CascadeID tags the rows a cascading delete soft deleted together, see Review.CascadeID.
+	CascadeID *string `gorm:"type:varchar(16);index"`
+}
+
+// BookISBNKey is the SQL expression books are looked up by ISBN with: the stored ISBN
+// without hyphens or spaces, upper cased. MigrateBookISBNIndex indexes it, so queries
+// have to use it verbatim for Postgres to pick the index.
+const BookISBNKey = "REPLACE(REPLACE(UPPER(isbn), '-', ''), ' ', '')"
+
+// BookRating sums up the published reviews of a book. It is stored on the book so
+// lists can sort by it, and kept up to date by the ratings package.
+type BookRating struct {
+	Count   int     `gorm:"not null;default:0"`
+	Average float64 `gorm:"not null;default:0"`
+	Stars1  int     `gorm:"not null;default:0"`
+	Stars2  int     `gorm:"not null;default:0"`
+	Stars3  int     `gorm:"not null;default:0"`
+	Stars4  int     `gorm:"not null;default:0"`
+	Stars5  int     `gorm:"not null;default:0"`
+	// Score is the Bayesian average, see ratings.Prior.
+	Score float64 `gorm:"not null;default:0;index"`
 }
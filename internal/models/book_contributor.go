@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type ContributorRole string
+
+const (
+	ContributorAuthor      ContributorRole = "author"
+	ContributorEditor      ContributorRole = "editor"
+	ContributorTranslator  ContributorRole = "translator"
+	ContributorIllustrator ContributorRole = "illustrator"
+)
+
+// BookContributor links an author to a book in a given role. Position keeps the
+// order in which contributors are credited on the book.
+type BookContributor struct {
+	BookID    uint            `gorm:"primaryKey"`
+	AuthorID  uint            `gorm:"primaryKey;index"`
+	Role      ContributorRole `gorm:"primaryKey;type:varchar(32);default:'author'"`
+	Position  int             `gorm:"not null;default:0"`
+	Author    Author          `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	CreatedAt time.Time
+}
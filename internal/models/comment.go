@@ -0,0 +1,19 @@
+package models
+
+import "gorm.io/gorm"
+
+// Comment is a remark on a review or, with a ParentID, a reply to another comment.
+// RootID points at the top-level comment of the thread, so a whole thread loads in one
+// query. Deleted comments keep their row to hold the thread together and are shown
+// without their text.
+type Comment struct {
+	gorm.Model
+	ReviewID uint     `gorm:"not null;index"`
+	Review   Review   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ParentID *uint    `gorm:"index"`
+	Parent   *Comment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	RootID   *uint    `gorm:"index"`
+	UserID   *uint    `gorm:"index"`
+	User     *User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Body     string   `gorm:"not null"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Genre is a node in the classification tree. Root genres have no parent.
+type Genre struct {
+	gorm.Model
+	Name     string  `gorm:"not null"`
+	Slug     string  `gorm:"uniqueIndex:idx_genre_slug,where:deleted_at IS NULL;not null"`
+	ParentID *uint   `gorm:"index"`
+	Children []Genre `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Books    []Book  `gorm:"many2many:book_genres;constraint:OnDelete:CASCADE;"`
+}
+
+// Tag is a free-form label attached to books. Names are stored lower-cased.
+type Tag struct {
+	ID        uint   `gorm:"primarykey"`
+	Name      string `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time
+	Books     []Book `gorm:"many2many:book_tags;constraint:OnDelete:CASCADE;"`
+}
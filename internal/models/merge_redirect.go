@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MergeRedirect remembers that a book or author was merged into another record, so
+// requests for the merged ID can be redirected to the survivor.
+type MergeRedirect struct {
+	ID        uint   `gorm:"primarykey"`
+	Entity    string `gorm:"type:varchar(16);not null;uniqueIndex:idx_merge_redirects_from"`
+	FromID    uint   `gorm:"not null;uniqueIndex:idx_merge_redirects_from"`
+	ToID      uint   `gorm:"not null;index"`
+	MergedBy  string
+	CreatedAt time.Time
+}
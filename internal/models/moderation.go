@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Reasons a review can be flagged for.
+const (
+	FlagSpam     = "spam"
+	FlagAbuse    = "abuse"
+	FlagSpoiler  = "spoiler"
+	FlagOffTopic = "off_topic"
+	FlagOther    = "other"
+)
+
+// Moderator actions on a review. Dismiss resolves the open flags and leaves the status
+// as it is; the others also set the status. Hold is recorded when the content filters
+// hold a review for a moderator.
+const (
+	ModerationPublish = "publish"
+	ModerationHide    = "hide"
+	ModerationReject  = "reject"
+	ModerationDismiss = "dismiss"
+	ModerationHold    = "hold"
+)
+
+// ReviewFlag is a user's report of a review. A flag stays open until a moderator acts
+// on the review; a user has at most one open flag per review.
+type ReviewFlag struct {
+	ID         uint   `gorm:"primarykey"`
+	ReviewID   uint   `gorm:"not null;index;uniqueIndex:idx_review_flag_open,where:resolved_at IS NULL"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_review_flag_open,where:resolved_at IS NULL"`
+	User       User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Review     Review `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Reason     string `gorm:"type:varchar(16);not null"`
+	Note       string
+	CreatedAt  time.Time
+	ResolvedAt *time.Time
+}
+
+// ModerationAction records what a moderator did to a review and why.
+type ModerationAction struct {
+	ID            uint   `gorm:"primarykey"`
+	ReviewID      uint   `gorm:"not null;index"`
+	Review        Review `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Action        string `gorm:"type:varchar(16);not null"`
+	FromStatus    string `gorm:"type:varchar(16);not null"`
+	ToStatus      string `gorm:"type:varchar(16);not null"`
+	Note          string
+	FlagsResolved int
+	Moderator     string
+	CreatedAt     time.Time
+}
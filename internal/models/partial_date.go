@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DatePrecision tells how much of a PartialDate is known.
+type DatePrecision string
+
+const (
+	PrecisionYear  DatePrecision = "year"
+	PrecisionMonth DatePrecision = "month"
+	PrecisionDay   DatePrecision = "day"
+)
+
+var precisionLayouts = map[DatePrecision]string{
+	PrecisionYear:  "2006",
+	PrecisionMonth: "2006-01",
+	PrecisionDay:   "2006-01-02",
+}
+
+// PartialDate is a date of which only the year, or the year and month, may be known.
+// Date holds the first day of the known period and is nil when nothing is known.
+type PartialDate struct {
+	Date      *time.Time    `gorm:"type:date"`
+	Precision DatePrecision `gorm:"type:varchar(5)"`
+}
+
+// ParsePartialDate reads YYYY, YYYY-MM or YYYY-MM-DD. An empty string is an unknown date.
+func ParsePartialDate(value string) (PartialDate, error) {
+	if value == "" {
+		return PartialDate{}, nil
+	}
+	for _, precision := range []DatePrecision{PrecisionDay, PrecisionMonth, PrecisionYear} {
+		if len(value) != len(precisionLayouts[precision]) {
+			continue
+		}
+		if date, err := time.Parse(precisionLayouts[precision], value); err == nil {
+			return PartialDate{Date: &date, Precision: precision}, nil
+		}
+	}
+	return PartialDate{}, fmt.Errorf("%q is not a date in the form YYYY, YYYY-MM or YYYY-MM-DD", value)
+}
+
+// String formats the date as far as it is known, or returns "" when it is unknown.
+func (d PartialDate) String() string {
+	if d.Date == nil {
+		return ""
+	}
+	layout, ok := precisionLayouts[d.Precision]
+	if !ok {
+		layout = precisionLayouts[PrecisionDay]
+	}
+	return d.Date.Format(layout)
+}
+
+// Before reports whether d lies before other, compared at the coarser precision of the
+// two, so 1920 is not before 1920-05-17. Unknown dates are never before anything.
+func (d PartialDate) Before(other PartialDate) bool {
+	if d.Date == nil || other.Date == nil {
+		return false
+	}
+	precision := PrecisionDay
+	for _, coarser := range []DatePrecision{PrecisionYear, PrecisionMonth} {
+		if d.Precision == coarser || other.Precision == coarser {
+			precision = coarser
+			break
+		}
+	}
+	layout := precisionLayouts[precision]
+	return d.Date.Format(layout) < other.Date.Format(layout)
+}
@@ -0,0 +1,71 @@
+package models
+
+import "testing"
+
+func TestParsePartialDate(t *testing.T) {
+	for _, tc := range []struct {
+		value     string
+		precision DatePrecision
+	}{
+		{"", ""},
+		{"1954", PrecisionYear},
+		{"0800", PrecisionYear},
+		{"1954-07", PrecisionMonth},
+		{"1954-07-29", PrecisionDay},
+		{"2000-02-29", PrecisionDay},
+	} {
+		date, err := ParsePartialDate(tc.value)
+		if err != nil {
+			t.Errorf("ParsePartialDate(%q): %v", tc.value, err)
+			continue
+		}
+		if date.Precision != tc.precision || (date.Date == nil) != (tc.value == "") {
+			t.Errorf("ParsePartialDate(%q) = %+v, want precision %q", tc.value, date, tc.precision)
+		}
+		// Dates are kept as the first day of the known period and format back as given.
+		if date.Date != nil && ((tc.precision == PrecisionYear && date.Date.YearDay() != 1) || (tc.precision == PrecisionMonth && date.Date.Day() != 1)) {
+			t.Errorf("ParsePartialDate(%q) holds %v, want the first day of the period", tc.value, date.Date)
+		}
+		if got := date.String(); got != tc.value {
+			t.Errorf("ParsePartialDate(%q).String() = %q", tc.value, got)
+		}
+	}
+
+	for _, value := range []string{"54", "19540", "1954-7", "1954-13", "1954-07-5", "1954-02-30", "1900-02-29", "1954/07/29", "July 1954", "1954-07-29T00:00:00Z"} {
+		if date, err := ParsePartialDate(value); err == nil {
+			t.Errorf("ParsePartialDate(%q) = %+v, want an error", value, date)
+		}
+	}
+}
+
+func TestPartialDateBefore(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"1919", "1920", true},
+		{"1920", "1919", false},
+		{"1920", "1920", false},
+		// Compared at the coarser precision, a year neither precedes nor follows its days.
+		{"1920", "1920-05-17", false},
+		{"1920-05-17", "1920", false},
+		{"1919-12-31", "1920", true},
+		{"1920-05", "1920-05-17", false},
+		{"1920-04-30", "1920-05", true},
+		{"1920-05-16", "1920-05-17", true},
+		{"1920-05-17", "1920-05-16", false},
+		// Unknown dates are never before anything, nor is anything before them.
+		{"", "1920", false},
+		{"1920", "", false},
+		{"", "", false},
+	} {
+		a, errA := ParsePartialDate(tc.a)
+		b, errB := ParsePartialDate(tc.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("parsing %q and %q: %v, %v", tc.a, tc.b, errA, errB)
+		}
+		if got := a.Before(b); got != tc.want {
+			t.Errorf("%q.Before(%q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "gorm.io/gorm"
+
+type Publisher struct {
+	gorm.Model
+	Name     string `gorm:"not null"`
+	Website  string
+	Country  string
+	Imprints []Imprint `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Books    []Book    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// Imprint is a brand name a publisher releases books under.
+type Imprint struct {
+	gorm.Model
+	PublisherID uint   `gorm:"not null;index"`
+	Name        string `gorm:"not null"`
+	Books       []Book `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
@@ -1,11 +1,50 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Review statuses. Only published reviews are shown to readers; the others wait for or
+// were set aside by a moderator.
+const (
+	ReviewPending   = "pending"
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+	ReviewRejected  = "rejected"
+)
+
+// Review is a user's rating of a book. A user reviews a book once; reviews written
+// before reviews had owners have no UserID. The vote counts and HelpfulScore are kept
+// in step with the ReviewVote rows so reviews can be sorted by helpfulness, and
+// CommentCount with the live comments on the review.
 type Review struct {
 	gorm.Model
-	Rating     int
-	Comment    string
-	DatePosted string
-	BookID     uint
+	Rating         int
+	Comment        string
+	DatePosted     string
+	Status         string  `gorm:"type:varchar(16);not null;default:published;index"`
+	BookID         uint    `gorm:"not null;index;uniqueIndex:idx_review_book_user,where:deleted_at IS NULL"`
+	UserID         *uint   `gorm:"uniqueIndex:idx_review_book_user,where:deleted_at IS NULL"`
+	User           *User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	HelpfulVotes   int     `gorm:"not null;default:0"`
+	UnhelpfulVotes int     `gorm:"not null;default:0"`
+	HelpfulScore   float64 `gorm:"not null;default:0;index"`
+	CommentCount   int     `gorm:"not null;default:0"`
+	// CascadeID is shared by the rows one cascading delete soft deleted, the deleted
+	// author or book included, so restoring it brings back exactly those rows.
+	CascadeID *string `gorm:"type:varchar(16);index"`
+}
+
+// ReviewVote is a user's verdict on whether a review was helpful. Each user has one
+// vote per review, which they can change or take back.
+type ReviewVote struct {
+	ReviewID  uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"primaryKey"`
+	Review    Review `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User      User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Helpful   bool   `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
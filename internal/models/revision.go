@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRevert  = "revert"
+	RevisionMerge   = "merge"
+	RevisionRestore = "restore"
+)
+
+// Revision is one version of a book, author or review. Snapshot holds the complete
+// state after the change as JSON, so two versions can be compared and an older one
+// restored without replaying the history.
+type Revision struct {
+	ID           uint   `gorm:"primarykey"`
+	Entity       string `gorm:"type:varchar(16);not null;uniqueIndex:idx_revisions_version"`
+	EntityID     uint   `gorm:"not null;uniqueIndex:idx_revisions_version"`
+	Version      int    `gorm:"not null;uniqueIndex:idx_revisions_version"`
+	Action       string `gorm:"type:varchar(16);not null"`
+	RevertedFrom *int
+	ChangedBy    string
+	Snapshot     string `gorm:"type:jsonb;not null"`
+	CreatedAt    time.Time
+}
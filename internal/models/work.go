@@ -0,0 +1,33 @@
+package models
+
+import "gorm.io/gorm"
+
+type BookFormat string
+
+const (
+	FormatHardcover BookFormat = "hardcover"
+	FormatPaperback BookFormat = "paperback"
+	FormatEbook     BookFormat = "ebook"
+	FormatAudiobook BookFormat = "audiobook"
+)
+
+// Work is the abstract creation shared by all of its editions. Each Book row is
+// one edition of a work; reviews are written against editions and roll up here.
+type Work struct {
+	gorm.Model
+	Title              string `gorm:"not null"`
+	OriginalLanguage   string
+	FirstPublishedYear int
+	SeriesID           *uint `gorm:"index"`
+	Series             *Series
+	SeriesPosition     int
+	Editions           []Book `gorm:"foreignKey:WorkID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+}
+
+// Series groups works that are meant to be read in order.
+type Series struct {
+	gorm.Model
+	Name        string `gorm:"not null"`
+	Description string
+	Works       []Work `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
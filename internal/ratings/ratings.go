@@ -0,0 +1,105 @@
+// Package ratings keeps the rating aggregates stored on books in step with their
+// published reviews.
+package ratings
+
+import (
+	"fmt"
+
+	"go-rest-api-ozgur/internal/config"
+	"go-rest-api-ozgur/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Prior is the belief a Bayesian score starts from: Weight imaginary reviews rating
+// the book Mean, giving a score of (Weight*Mean + sum of ratings) / (Weight + count).
+// It pulls books with few reviews towards Mean, so one five star review does not
+// outrank a hundred four star ones. Books without reviews score 0, so they sort after
+// every rated book.
+type Prior struct {
+	Mean   float64
+	Weight float64
+}
+
+// PriorFromConfig reads the prior from RATING_PRIOR_MEAN and RATING_PRIOR_WEIGHT.
+func PriorFromConfig(cfg *config.Config) Prior {
+	return Prior{Mean: cfg.RatingPriorMean, Weight: cfg.RatingPriorWeight}
+}
+
+// refreshSQL recomputes the aggregates of the books matched by the %s condition from
+// their live, published reviews. Books whose aggregates are already right are left
+// alone, so their updated_at and ETag only change when the numbers do.
+const refreshSQL = `
+	UPDATE books SET
+		rating_count = s.count,
+		rating_average = s.average,
+		rating_stars1 = s.stars1,
+		rating_stars2 = s.stars2,
+		rating_stars3 = s.stars3,
+		rating_stars4 = s.stars4,
+		rating_stars5 = s.stars5,
+		rating_score = s.score,
+		updated_at = NOW()
+	FROM (
+		SELECT b.id,
+			COUNT(r.id) AS count,
+			COALESCE(ROUND(AVG(r.rating)::numeric, 4), 0) AS average,
+			COUNT(r.id) FILTER (WHERE r.rating = 1) AS stars1,
+			COUNT(r.id) FILTER (WHERE r.rating = 2) AS stars2,
+			COUNT(r.id) FILTER (WHERE r.rating = 3) AS stars3,
+			COUNT(r.id) FILTER (WHERE r.rating = 4) AS stars4,
+			COUNT(r.id) FILTER (WHERE r.rating = 5) AS stars5,
+			CASE WHEN COUNT(r.id) = 0 THEN 0
+				ELSE ROUND(((CAST(@weight AS float8) * CAST(@mean AS float8) + COALESCE(SUM(r.rating), 0))
+					/ (CAST(@weight AS float8) + COUNT(r.id)))::numeric, 4)
+			END AS score
+		FROM books b
+		LEFT JOIN reviews r ON r.book_id = b.id AND r.deleted_at IS NULL AND r.status = @published
+		WHERE %s
+		GROUP BY b.id
+	) s
+	WHERE books.id = s.id AND (
+		books.rating_count, books.rating_average, books.rating_score,
+		books.rating_stars1, books.rating_stars2, books.rating_stars3, books.rating_stars4, books.rating_stars5
+	) IS DISTINCT FROM (
+		s.count, s.average, s.score, s.stars1, s.stars2, s.stars3, s.stars4, s.stars5
+	)`
+
+// Refresh recomputes the aggregates of the given books. Call it in the transaction that
+// changes their reviews, so the aggregates commit or roll back with them. Deleted
+// books are refreshed too, so they come back from the trash with the right numbers.
+func Refresh(tx *gorm.DB, prior Prior, bookIDs ...uint) error {
+	ids := make([]uint, 0, len(bookIDs))
+	for _, id := range bookIDs {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Exec(statement("b.id IN @ids"), args(prior, map[string]interface{}{"ids": ids})).Error
+}
+
+// RecomputeAll recomputes the aggregates of every book, repairing any that drifted and
+// rescoring all of them after the prior changed. It returns how many books changed.
+func RecomputeAll(db *gorm.DB, prior Prior) (int64, error) {
+	result := db.Exec(statement("TRUE"), args(prior, nil))
+	return result.RowsAffected, result.Error
+}
+
+func statement(condition string) string {
+	return fmt.Sprintf(refreshSQL, condition)
+}
+
+func args(prior Prior, extra map[string]interface{}) map[string]interface{} {
+	named := map[string]interface{}{
+		"mean":      prior.Mean,
+		"weight":    prior.Weight,
+		"published": models.ReviewPublished,
+	}
+	for name, value := range extra {
+		named[name] = value
+	}
+	return named
+}
@@ -9,28 +9,119 @@ import (
 
 func SetupRoutes(router *gin.Engine) {
 
-	// Public routes (accessible without authentication)
-	api := router.Group("/api/v1")
+	// Public routes (accessible without authentication; a token, if sent, identifies the user)
+	api := router.Group("/api/v1", middleware.OptionalAuth())
 	{
 		// Books
 		api.GET("/books", handlers.GetBooks)
 		api.GET("/books/:id", handlers.GetBook)
 		api.POST("/books", handlers.CreateBook)
 		api.PUT("/books/:id", handlers.UpdateBook)
+		api.PATCH("/books/:id", handlers.PatchBook)
 		api.DELETE("/books/:id", middleware.AdminOnly(), handlers.DeleteBook)
+		api.PUT("/books/:id/cover", handlers.UploadBookCover)
+		api.POST("/books/:id/cover", handlers.UploadBookCover)
+		api.DELETE("/books/:id/cover", middleware.AdminOnly(), handlers.DeleteBookCover)
 
 		// Authors
 		api.GET("/authors", handlers.GetAuthors)
+		api.GET("/authors/index", handlers.GetAuthorIndex)
 		api.GET("/authors/:id", handlers.GetAuthor)
 		api.POST("/authors", handlers.CreateAuthor)
 		api.PUT("/authors/:id", handlers.UpdateAuthor)
+		api.PATCH("/authors/:id", handlers.PatchAuthor)
 		api.DELETE("/authors/:id", middleware.AdminOnly(), handlers.DeleteAuthor)
 
+		// Publishers
+		api.GET("/publishers", handlers.GetPublishers)
+		api.GET("/publishers/:id", handlers.GetPublisher)
+		api.GET("/publishers/:id/books", handlers.GetPublisherBooks)
+		api.POST("/publishers", handlers.CreatePublisher)
+		api.PUT("/publishers/:id", handlers.UpdatePublisher)
+		api.POST("/publishers/:id/imprints", handlers.CreateImprint)
+		api.PUT("/publishers/:id/imprints/:imprint_id", handlers.UpdateImprint)
+
 		// Reviews
 		api.GET("/books/:id/reviews", handlers.GetReviewsForBook)
-		api.POST("/books/:id/reviews", handlers.CreateReview)
-		api.PUT("/reviews/:id", handlers.UpdateReview)
-		api.DELETE("/reviews/:id", middleware.AdminOnly(), handlers.DeleteReview)
+		api.GET("/reviews/:id/comments", handlers.GetReviewComments)
+
+		// Genres and tags
+		api.GET("/genres", handlers.GetGenres)
+		api.GET("/genres/:id", handlers.GetGenre)
+		api.GET("/tags", handlers.GetTags)
+
+		// Works, editions and series
+		api.GET("/books/:id/editions", handlers.GetBookEditions)
+		api.GET("/works", handlers.GetWorks)
+		api.GET("/works/:id", handlers.GetWork)
+		api.GET("/works/:id/editions", handlers.GetWorkEditions)
+		api.GET("/works/:id/reviews", handlers.GetWorkReviews)
+		api.GET("/series", handlers.GetAllSeries)
+		api.GET("/series/:id", handlers.GetSeries)
+	}
+
+	// Member routes (any signed in user; handlers check who may change what)
+	member := router.Group("/api/v1", middleware.AuthRequired())
+	{
+		member.POST("/books/:id/reviews", handlers.CreateReview)
+		member.PUT("/reviews/:id", handlers.UpdateReview)
+		member.PATCH("/reviews/:id", handlers.PatchReview)
+		member.DELETE("/reviews/:id", middleware.ModeratorOnly(), handlers.DeleteReview)
+		member.POST("/reviews/:id/flags", handlers.FlagReview)
+		member.PUT("/reviews/:id/vote", handlers.VoteReview)
+		member.DELETE("/reviews/:id/vote", handlers.UnvoteReview)
+		member.POST("/reviews/:id/comments", handlers.CreateComment)
+		member.PUT("/comments/:id", handlers.UpdateComment)
+		member.DELETE("/comments/:id", handlers.DeleteComment)
+	}
+
+	// Moderator routes (admin or moderator token required)
+	moderator := router.Group("/api/v1/admin/moderation", middleware.AuthRequired(), middleware.ModeratorOnly())
+	{
+		moderator.GET("/queue", handlers.GetModerationQueue)
+		moderator.POST("/reviews/:id", handlers.ModerateReview)
+		moderator.GET("/reviews/:id/history", handlers.GetModerationHistory)
+	}
+
+	// Curator routes (admin token required)
+	curator := router.Group("/api/v1", middleware.AuthRequired(), middleware.AdminOnly())
+	{
+		curator.POST("/genres", handlers.CreateGenre)
+		curator.PUT("/genres/:id", handlers.UpdateGenre)
+		curator.DELETE("/genres/:id", handlers.DeleteGenre)
+
+		curator.POST("/works", handlers.CreateWork)
+		curator.PUT("/works/:id", handlers.UpdateWork)
+		curator.DELETE("/works/:id", handlers.DeleteWork)
+		curator.POST("/series", handlers.CreateSeries)
+		curator.PUT("/series/:id", handlers.UpdateSeries)
+		curator.DELETE("/series/:id", handlers.DeleteSeries)
+
+		curator.DELETE("/publishers/:id", handlers.DeletePublisher)
+		curator.DELETE("/publishers/:id/imprints/:imprint_id", handlers.DeleteImprint)
+
+		curator.POST("/admin/import/books", handlers.ImportBooks)
+		curator.GET("/admin/export/:dataset", handlers.ExportDataset)
+
+		curator.GET("/admin/books/:id/enrichment", handlers.GetBookEnrichment)
+		curator.POST("/admin/books/:id/enrichment", handlers.ApplyBookEnrichment)
+		curator.POST("/admin/enrichment/jobs", handlers.StartEnrichmentJob)
+		curator.GET("/admin/enrichment/jobs/:id", handlers.GetEnrichmentJob)
+
+		curator.GET("/admin/duplicates/authors", handlers.GetDuplicateAuthors)
+		curator.GET("/admin/duplicates/books", handlers.GetDuplicateBooks)
+		curator.POST("/admin/authors/:id/merge", handlers.MergeAuthors)
+		curator.POST("/admin/books/:id/merge", handlers.MergeBooks)
+
+		curator.GET("/admin/history/:entity/:id", handlers.GetRevisions)
+		curator.GET("/admin/history/:entity/:id/at", handlers.GetRevisionAt)
+		curator.GET("/admin/history/:entity/:id/diff", handlers.DiffRevisions)
+		curator.GET("/admin/history/:entity/:id/:version", handlers.GetRevision)
+		curator.POST("/admin/history/:entity/:id/:version/revert", handlers.RevertRevision)
+
+		curator.GET("/admin/trash/:entity", handlers.GetTrash)
+		curator.POST("/admin/trash/:entity/:id/restore", handlers.RestoreTrash)
+		curator.DELETE("/admin/trash/:entity/:id", handlers.PurgeTrash)
 	}
 
 	// Auth routes (for registration, login, and token refresh)
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-rest-api-ozgur/internal/config"
+)
+
+// ErrNotFound is returned by Get when no blob is stored under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary objects such as cover images under slash separated keys.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients can fetch the blob from.
+	URL(key string) string
+}
+
+// New builds the blob store selected by STORAGE_BACKEND.
+func New(cfg *config.Config) (BlobStore, error) {
+	switch cfg.StorageBackend {
+	case "", "local":
+		publicURL := cfg.StoragePublicURL
+		if publicURL == "" {
+			publicURL = "/media"
+		}
+		return NewLocalStore(cfg.StorageLocalDir, publicURL), nil
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.StoragePublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// validKey rejects keys that could escape the store's root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
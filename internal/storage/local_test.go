@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorePutGetDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(dir, "/media/")
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "books/7/cover.jpg", "image/jpeg", []byte("jpeg bytes")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "books", "7", "cover.jpg"))
+	if err != nil || string(data) != "jpeg bytes" {
+		t.Fatalf("the file holds %q (%v), want %q", data, err, "jpeg bytes")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "books", "7"))
+	if len(entries) != 1 {
+		t.Errorf("the directory holds %d entries, want only the blob without temporary files", len(entries))
+	}
+
+	if err := store.Put(ctx, "books/7/cover.jpg", "image/jpeg", []byte("replaced")); err != nil {
+		t.Fatalf("Put over an existing blob: %v", err)
+	}
+	body, err := store.Get(ctx, "books/7/cover.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ = io.ReadAll(body)
+	body.Close()
+	if string(data) != "replaced" {
+		t.Errorf("Get returned %q, want %q", data, "replaced")
+	}
+
+	if err := store.Delete(ctx, "books/7/cover.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "books/7/cover.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "books/7/cover.jpg"); err != nil {
+		t.Errorf("deleting a missing blob returned %v, want nil", err)
+	}
+}
+
+func TestLocalStoreRejectsInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(filepath.Join(dir, "blobs"), "/media")
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "books/../../outside", "books//7", "books/./7"} {
+		if err := store.Put(ctx, key, "", []byte("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) returned %v, want an invalid key error", key, err)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want an invalid key error", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Error("a key escaped the store directory")
+	}
+}
+
+func TestLocalStoreURL(t *testing.T) {
+	store := NewLocalStore("uploads", "/media/")
+	if got, want := store.URL("books/7/cover.jpg"), "/media/books/7/cover.jpg"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+	if store.BaseURL() != "/media" || store.Dir() != "uploads" {
+		t.Errorf("BaseURL, Dir = %q, %q, want /media, uploads", store.BaseURL(), store.Dir())
+	}
+}
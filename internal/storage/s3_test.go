@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-west-1"
+	testBucket    = "covers"
+)
+
+// s3Stub is a MinIO-style object store that keeps objects in memory and refuses
+// requests whose SigV4 signature does not check out, recomputed from what arrived.
+type s3Stub struct {
+	secretKey string
+
+	mu       sync.Mutex
+	objects  map[string]stubObject
+	requests []*http.Request
+}
+
+type stubObject struct {
+	contentType string
+	data        []byte
+}
+
+func newS3Stub(t *testing.T) (*s3Stub, *httptest.Server) {
+	stub := &s3Stub{secretKey: testSecretKey, objects: map[string]stubObject{}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+
+	if err := s.verify(r, body); err != "" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+err+"</Message></Error>")
+		return
+	}
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "<Error><Code>NoSuchBucket</Code></Error>")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = stubObject{contentType: r.Header.Get("Content-Type"), data: body}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		object, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Write(object.data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify checks the request the way S3 does and describes the first problem found.
+func (s *s3Stub) verify(r *http.Request, body []byte) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return "missing AWS4-HMAC-SHA256 authorization"
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		fields[name] = value
+	}
+	credential := strings.SplitN(fields["Credential"], "/", 2)
+	if len(credential) != 2 || credential[0] != testAccessKey {
+		return "unknown access key"
+	}
+	scope := credential[1]
+	scopeParts := strings.Split(scope, "/")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(scopeParts) != 4 || !strings.HasPrefix(amzDate, scopeParts[0]) || scopeParts[2] != "s3" || scopeParts[3] != "aws4_request" {
+		return "malformed credential scope"
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		return "payload hash does not match the body"
+	}
+
+	names := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(names) {
+		return "signed headers are not sorted"
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		uriEncodePath(r.URL.Path),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		sha256Hex(body),
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), scopeParts[0])
+	key = hmacSHA256(key, scopeParts[1])
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); fields["Signature"] != want {
+		return "signature does not match"
+	}
+	return ""
+}
+
+func newTestS3Store(t *testing.T, endpoint string, secretKey string) *S3Store {
+	t.Helper()
+	store, err := NewS3Store(S3Options{
+		Endpoint:  endpoint,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "books/7/cover.jpg", "image/jpeg", []byte("jpeg bytes")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	object, ok := stub.objects["books/7/cover.jpg"]
+	if !ok {
+		t.Fatalf("the stub holds %v, want books/7/cover.jpg", stub.objects)
+	}
+	if object.contentType != "image/jpeg" || string(object.data) != "jpeg bytes" {
+		t.Errorf("stored %q as %q, want %q as image/jpeg", object.data, object.contentType, "jpeg bytes")
+	}
+
+	body, err := store.Get(ctx, "books/7/cover.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "jpeg bytes" {
+		t.Errorf("Get returned %q, want %q", data, "jpeg bytes")
+	}
+
+	if err := store.Delete(ctx, "books/7/cover.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := stub.objects["books/7/cover.jpg"]; ok {
+		t.Error("the object is still stored after Delete")
+	}
+	if _, err := store.Get(ctx, "books/7/cover.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "books/7/cover.jpg"); err != nil {
+		t.Errorf("deleting a missing object returned %v, want nil", err)
+	}
+}
+
+func TestS3StoreSignsRequests(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+	store.now = func() time.Time { return time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC) }
+
+	// Spaces and other reserved characters have to be escaped the same way on both ends.
+	if err := store.Put(context.Background(), "books/7/my cover+1.png", "image/png", []byte("png")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := stub.objects["books/7/my cover+1.png"]; !ok {
+		t.Errorf("the stub holds %v, want the key unescaped", stub.objects)
+	}
+
+	req := stub.requests[len(stub.requests)-1]
+	if got := req.Header.Get("X-Amz-Date"); got != "20240501T123000Z" {
+		t.Errorf("X-Amz-Date = %q, want 20240501T123000Z", got)
+	}
+	auth := req.Header.Get("Authorization")
+	for _, want := range []string{
+		"Credential=" + testAccessKey + "/20240501/" + testRegion + "/s3/aws4_request",
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date",
+	} {
+		if !strings.Contains(auth, want) {
+			t.Errorf("Authorization %q does not contain %q", auth, want)
+		}
+	}
+	if got, want := req.URL.EscapedPath(), "/covers/books/7/my%20cover%2B1.png"; got != want {
+		t.Errorf("request path = %q, want %q", got, want)
+	}
+}
+
+func TestS3StoreRejectedSignature(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, "not the secret")
+
+	err := store.Put(context.Background(), "books/7/cover.jpg", "image/jpeg", []byte("jpeg"))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put with a wrong secret returned %v, want a 403 naming SignatureDoesNotMatch", err)
+	}
+	if len(stub.objects) != 0 {
+		t.Errorf("the stub stored %v despite the bad signature", stub.objects)
+	}
+	if err := store.Delete(context.Background(), "books/7/cover.jpg"); err == nil {
+		t.Error("Delete with a wrong secret succeeded")
+	}
+}
+
+func TestS3StoreRejectsInvalidKeys(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+
+	for _, key := range []string{"", "/books/7", "books/../secret", "books//7"} {
+		if err := store.Put(context.Background(), key, "image/jpeg", []byte("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+	if len(stub.requests) != 0 {
+		t.Errorf("invalid keys reached the server %d times", len(stub.requests))
+	}
+}
+
+func TestS3StoreURL(t *testing.T) {
+	store := newTestS3Store(t, "http://minio.local:9000/", testSecretKey)
+	if got, want := store.URL("books/7/cover.jpg"), "http://minio.local:9000/covers/books/7/cover.jpg"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	public, err := NewS3Store(S3Options{Endpoint: "http://minio.local:9000", Bucket: testBucket, PublicURL: "https://cdn.example.com/"})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	if got, want := public.URL("books/7/cover.jpg"), "https://cdn.example.com/books/7/cover.jpg"; got != want {
+		t.Errorf("URL with a public URL = %q, want %q", got, want)
+	}
+
+	if _, err := NewS3Store(S3Options{Endpoint: "http://minio.local:9000"}); err == nil {
+		t.Error("NewS3Store without a bucket succeeded")
+	}
+}
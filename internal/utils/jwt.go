@@ -9,15 +9,17 @@ import (
 var jwtKey = []byte("TESTB4ENV")
 
 type Claims struct {
+	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
 	jwt.StandardClaims
 }
 
-func GenerateTokens(username string, role string) (string, string, error) {
+func GenerateTokens(userID uint, username string, role string) (string, string, error) {
 	// Access token
 	accessTokenExp := time.Now().Add(15 * time.Minute)
 	accessClaims := &Claims{
+		UserID:   userID,
 		Username: username,
 		Role:     role,
 		StandardClaims: jwt.StandardClaims{
@@ -33,6 +35,7 @@ func GenerateTokens(username string, role string) (string, string, error) {
 	// Refresh token
 	refreshTokenExp := time.Now().Add(7 * 24 * time.Hour)
 	refreshClaims := &Claims{
+		UserID:   userID,
 		Username: username,
 		Role:     role,
 		StandardClaims: jwt.StandardClaims{
@@ -5,16 +5,22 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"go-rest-api-ozgur/internal/cache"
 	"go-rest-api-ozgur/internal/config"
-	"go-rest-api-ozgur/internal/db"
+	"go-rest-api-ozgur/internal/contentfilter"
+	database "go-rest-api-ozgur/internal/db"
+	"go-rest-api-ozgur/internal/dto"
 	"go-rest-api-ozgur/internal/handlers"
+	"go-rest-api-ozgur/internal/metadata"
 	"go-rest-api-ozgur/internal/middleware"
 	"go-rest-api-ozgur/internal/models"
+	"go-rest-api-ozgur/internal/ratings"
 	"go-rest-api-ozgur/internal/routes"
+	"go-rest-api-ozgur/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -58,24 +64,125 @@ func main() {
 	log.Info("Redis initialized")
 
 	// Initialize database
-	db, err := db.InitDB(cfg)
+	db, err := database.InitDB(cfg)
 	if err != nil {
 		log.Fatal("Failed to connect to database")
 	}
 	log.Info("Database connected")
 
 	// Auto migrate models
-	if err := db.AutoMigrate(&models.User{}); err != nil {
+	if err := database.MigrateRoles(db); err != nil {
+		log.Fatal("Failed to migrate user roles")
+	}
+	// Ratings are backfilled once, when the columns holding them are created
+	backfillRatings := !db.Migrator().HasColumn(&models.Book{}, "rating_count")
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Author{},
+		&models.AuthorAlias{},
+		&models.Series{},
+		&models.Work{},
+		&models.Publisher{},
+		&models.Imprint{},
+		&models.Book{},
+		&models.BookContributor{},
+		&models.Review{},
+		&models.ReviewFlag{},
+		&models.ReviewVote{},
+		&models.Comment{},
+		&models.ModerationAction{},
+		&models.Genre{},
+		&models.Tag{},
+		&models.MergeRedirect{},
+		&models.Revision{},
+	); err != nil {
 		log.Fatal("Failed to migrate database")
 	}
+	if err := database.MigrateBookContributors(db); err != nil {
+		log.Fatal("Failed to migrate book authors to contributors")
+	}
+	if err := database.MigrateBookWorks(db); err != nil {
+		log.Fatal("Failed to migrate books to works")
+	}
+	if err := database.MigratePublishers(db); err != nil {
+		log.Fatal("Failed to migrate book publishers")
+	}
+	if err := database.MigrateAuthorBirthDates(db); err != nil {
+		log.Fatal("Failed to migrate author birth dates")
+	}
+	if err := database.MigrateAuthorSearchKeys(db); err != nil {
+		log.Fatal("Failed to index author names")
+	}
+	if err := database.MigrateGenreSlugs(db); err != nil {
+		log.Fatal("Failed to migrate genre slugs")
+	}
+	if err := database.MigrateBookISBNIndex(db); err != nil {
+		log.Fatal("Failed to index book ISBNs")
+	}
+	if backfillRatings {
+		if changed, err := ratings.RecomputeAll(db, ratings.PriorFromConfig(cfg)); err != nil {
+			log.Fatal("Failed to compute book ratings")
+		} else if changed > 0 {
+			log.WithField("books", changed).Info("Computed book ratings")
+		}
+	}
+	if err := database.EnableTrigramSearch(db); err != nil {
+		log.Warn("pg_trgm is not available, duplicate detection only finds exact matches: ", err)
+	}
 	log.Info("Database migrated")
 
 	handlers.InitDB(db)
+	handlers.InitConfig(cfg)
+
+	// Initialize blob storage for uploaded covers
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage: ", err)
+	}
+	handlers.InitStorage(store)
+
+	// Initialize the metadata provider used to enrich books by ISBN
+	provider, err := metadata.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize metadata provider: ", err)
+	}
+	handlers.InitMetadata(provider)
+
+	// Initialize the filters that screen reviews before they are published
+	filters, err := contentfilter.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize content filters: ", err)
+	}
+	handlers.InitContentFilter(filters)
+
+	// Purge the trash bin in the background
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	if cfg.TrashRetentionDays > 0 {
+		retention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+		go handlers.RunTrashRetention(purgeCtx, retention, time.Hour, func(report dto.TrashPurgeReport, err error) {
+			if err != nil {
+				log.Warn("Failed to purge part of the trash: ", err)
+			}
+			if report.Books+report.Authors+report.Reviews > 0 {
+				log.WithFields(logrus.Fields{
+					"books":   report.Books,
+					"authors": report.Authors,
+					"reviews": report.Reviews,
+				}).Info("Purged expired trash")
+			}
+		})
+	}
 
 	// Set up Gin router
 	router := gin.Default()
 	router.Use(middleware.RateLimiter()) // Apply rate limiting
 
+	// Serve locally stored uploads
+	if local, ok := store.(*storage.LocalStore); ok && strings.HasPrefix(local.BaseURL(), "/") {
+		router.Static(local.BaseURL(), local.Dir())
+	}
+
 	// Add Swagger route
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -109,5 +216,6 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
+	handlers.StopEnrichmentJobs(ctx)
 	log.Info("Server exited gracefully")
 }
\ No newline at end of file